@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/yourusername/optionator/pkg/optionator"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
 )
 
 // Server represents a configurable HTTP server with defaults.
@@ -0,0 +1,388 @@
+// Command optionator inspects configuration structs from source.
+//
+// Usage:
+//
+//	optionator doc <pkg>.<Type>
+//	optionator diff [-naming snake|kebab|camel] <config.json> <pkg>.<Type>
+//
+// doc prints the option table of a struct: every field path with its type,
+// default, whether it is required and its doc comment or desc tag, leaving
+// out fields tagged hidden:"true". diff compares a JSON config file against
+// a struct and reports unknown keys, missing required fields, type
+// mismatches, keys whose from tag excludes files, values that merely restate
+// a default, and keys that use a field's old name from its alias tag. diff
+// exits with status 1 when it finds anything other than restated defaults
+// and old keys. -naming matches keys the way Config.NamingStrategy does.
+//
+// <pkg> is an import path or a directory such as ./internal/config.
+//
+// Both commands read structs from source, sharing the required and list tag
+// rules with the library, and walk nested and embedded structs declared in
+// the same package. They read the default, required and from tags under
+// those names, so custom Config tag names, tag compatibility modes and tags
+// added by Define or RegisterOverlay are not seen, and structs declared in
+// other packages are treated as opaque values.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/internal/structscan"
+	"github.com/chetan-giradkar/Optionator/internal/tagrules"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "doc":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		err = doc(os.Stdout, os.Args[2])
+	case "diff":
+		fs := flag.NewFlagSet("diff", flag.ExitOnError)
+		naming := fs.String("naming", "", "naming strategy of the keys: snake, kebab or camel")
+		fs.Parse(os.Args[2:])
+		if fs.NArg() != 2 {
+			usage()
+		}
+		strategy, ok := namingStrategies[*naming]
+		if !ok {
+			usage()
+		}
+		var problems bool
+		problems, err = diff(os.Stdout, fs.Arg(0), fs.Arg(1), strategy)
+		if err == nil && problems {
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "optionator:", err)
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: optionator doc <pkg>.<Type>\n       optionator diff [-naming snake|kebab|camel] <config.json> <pkg>.<Type>")
+	os.Exit(2)
+}
+
+var namingStrategies = map[string]optionator.NamingStrategy{
+	"":      nil,
+	"snake": optionator.SnakeCase,
+	"kebab": optionator.KebabCase,
+	"camel": optionator.CamelCase,
+}
+
+// load resolves "<pkg>.<Type>" to its package and struct.
+func load(ref string) (*structscan.Package, *structscan.Struct, error) {
+	dot := strings.LastIndex(ref, ".")
+	if dot <= 0 || dot == len(ref)-1 {
+		return nil, nil, fmt.Errorf("expected <pkg>.<Type>, got %q", ref)
+	}
+	pkgPath, typeName := ref[:dot], ref[dot+1:]
+	dir := pkgPath
+	if fi, err := os.Stat(pkgPath); err != nil || !fi.IsDir() {
+		out, err := exec.Command("go", "list", "-f", "{{.Dir}}", pkgPath).Output()
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolving package %s: %w", pkgPath, err)
+		}
+		dir = strings.TrimSpace(string(out))
+	}
+	pkg, err := structscan.Load(dir, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, ok := pkg.Lookup(typeName)
+	if !ok {
+		return nil, nil, fmt.Errorf("struct %s not found in %s", typeName, pkgPath)
+	}
+	return pkg, s, nil
+}
+
+func doc(out io.Writer, ref string) error {
+	pkg, s, err := load(ref)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tTYPE\tDEFAULT\tREQUIRED\tDESCRIPTION")
+	var walk func(s *structscan.Struct, prefix string, seen map[string]bool)
+	walk = func(s *structscan.Struct, prefix string, seen map[string]bool) {
+		seen[s.Name] = true
+		defer delete(seen, s.Name)
+		for _, f := range s.Fields {
+			if f.Tag.Get("hidden") == "true" {
+				continue
+			}
+			if name, ok := f.LocalStruct(pkg); ok {
+				if !seen[name] {
+					nested, _ := pkg.Lookup(name)
+					walk(nested, prefix+f.Name+".", seen)
+				}
+				continue
+			}
+			required := ""
+			if tagrules.Required(f.Tag.Get("required")) {
+				required = "yes"
+			}
+			desc := f.Doc
+			if desc == "" {
+				desc = f.Tag.Get("desc")
+			}
+			desc = strings.ReplaceAll(desc, "\n", " ")
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", prefix+f.Name, f.Type, f.Tag.Get("default"), required, desc)
+		}
+	}
+	walk(s, "", map[string]bool{})
+	return w.Flush()
+}
+
+func diff(out io.Writer, path, ref string, naming optionator.NamingStrategy) (bool, error) {
+	pkg, s, err := load(ref)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	d := &differ{pkg: pkg, naming: naming}
+	d.compare(s, values, "")
+	sort.Strings(d.problems)
+	sort.Strings(d.defaults)
+	for _, p := range d.problems {
+		fmt.Fprintln(out, p)
+	}
+	for _, p := range d.defaults {
+		fmt.Fprintln(out, p)
+	}
+	return len(d.problems) > 0, nil
+}
+
+type differ struct {
+	pkg      *structscan.Package
+	naming   optionator.NamingStrategy
+	problems []string
+	defaults []string
+}
+
+// compare checks values, which may be nil when the section is absent,
+// against the fields of s.
+func (d *differ) compare(s *structscan.Struct, values map[string]any, prefix string) {
+	matched := map[string]bool{}
+	for _, f := range s.Fields {
+		key, value, present := d.lookup(values, f.Name)
+		path := prefix + f.Name
+		for _, alias := range tagrules.List(f.Tag.Get("alias")) {
+			if present {
+				continue
+			}
+			if key, value, present = lookup(values, alias); present {
+				d.defaults = append(d.defaults, fmt.Sprintf("old key: %s%s; rename it to %s", prefix, key, f.Name))
+			}
+		}
+		if present {
+			matched[key] = true
+			if from := f.Tag.Get("from"); from != "" && !allowsFile(from) {
+				d.problems = append(d.problems, fmt.Sprintf("restricted key: %s%s may only be set from %s", prefix, key, from))
+				continue
+			}
+		}
+		if name, ok := f.LocalStruct(d.pkg); ok {
+			nested, _ := d.pkg.Lookup(name)
+			obj, isObj := value.(map[string]any)
+			if present && !isObj {
+				d.problems = append(d.problems, fmt.Sprintf("type mismatch: %s: expected object, got %s", path, jsonKind(value)))
+				continue
+			}
+			d.compare(nested, obj, path+".")
+			continue
+		}
+		def := f.Tag.Get("default")
+		required := f.Tag.Get("required")
+		if !present {
+			if tagrules.Required(required) && def == "" {
+				d.problems = append(d.problems, "missing required field: "+path)
+			}
+			continue
+		}
+		if required == "nonempty" && isEmpty(value) {
+			d.problems = append(d.problems, "empty required field: "+path)
+			continue
+		}
+		if want := expectedKind(f); !accepts(want, value) {
+			d.problems = append(d.problems, fmt.Sprintf("type mismatch: %s: expected %s, got %s", path, want, jsonKind(value)))
+			continue
+		}
+		if def != "" && equalsDefault(f, value, def) {
+			d.defaults = append(d.defaults, fmt.Sprintf("equals default: %s = %s", path, def))
+		}
+	}
+	for key := range values {
+		// Keys such as $include and $version are directives, not fields.
+		if !matched[key] && !strings.HasPrefix(key, "$") {
+			d.problems = append(d.problems, "unknown key: "+prefix+key)
+		}
+	}
+}
+
+// allowsFile reports whether a from tag lists file sources.
+func allowsFile(from string) bool {
+	for _, kind := range tagrules.List(from) {
+		if optionator.SourceKind(kind) == optionator.FromFile {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup finds the key of a field by its name, or by its name under the
+// naming strategy.
+func (d *differ) lookup(values map[string]any, name string) (string, any, bool) {
+	if key, value, ok := lookup(values, name); ok || d.naming == nil {
+		return key, value, ok
+	}
+	return lookup(values, d.naming(name))
+}
+
+// isEmpty reports whether a decoded JSON value is an empty array or object.
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// lookup finds a field's key the way optionator binds sources: exact match
+// first, then case-insensitively.
+func lookup(values map[string]any, name string) (string, any, bool) {
+	if v, ok := values[name]; ok {
+		return name, v, true
+	}
+	for k, v := range values {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// expectedKind classifies a field type as the JSON kind it accepts.
+func expectedKind(f structscan.Field) string {
+	switch t := f.Expr.(type) {
+	case *ast.ArrayType:
+		return "array"
+	case *ast.MapType:
+		return "object"
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "bool"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+			return "number"
+		}
+	}
+	if f.Type == "time.Duration" {
+		return "duration"
+	}
+	return "any"
+}
+
+// accepts reports whether a decoded JSON value can be bound to a field of
+// the given kind. Strings are accepted for scalars that they parse as, since
+// optionator parses them like default tags.
+func accepts(kind string, value any) bool {
+	s, isString := value.(string)
+	switch kind {
+	case "string":
+		return isString
+	case "number":
+		if isString {
+			_, err := strconv.ParseFloat(s, 64)
+			return err == nil
+		}
+		_, ok := value.(json.Number)
+		return ok
+	case "duration":
+		if isString {
+			_, err := time.ParseDuration(s)
+			return err == nil
+		}
+		_, ok := value.(json.Number)
+		return ok
+	case "bool":
+		if isString {
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		}
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+func equalsDefault(f structscan.Field, value any, def string) bool {
+	if f.Type == "time.Duration" {
+		want, err := time.ParseDuration(def)
+		if s, ok := value.(string); ok && err == nil {
+			got, err := time.ParseDuration(s)
+			return err == nil && got == want
+		}
+		return false
+	}
+	return fmt.Sprint(value) == def
+}
+
+func jsonKind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
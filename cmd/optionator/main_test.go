@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+const ref = "testdata/app.Config"
+
+func TestDoc(t *testing.T) {
+	var out bytes.Buffer
+	if err := doc(&out, ref); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{"Base.Name", "DB.Host", "DB.Hosts", "Timeout", "Host is the database server."} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "Debug") {
+		t.Errorf("Expected hidden fields to be left out:\n%s", text)
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "DB.Hosts") && !strings.Contains(line, "yes") {
+			t.Errorf("Expected nonempty to mark DB.Hosts required: %q", line)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		config   string
+		naming   optionator.NamingStrategy
+		want     []string
+		problems bool
+	}{
+		{
+			name:   "clean",
+			config: `{"Base": {"Name": "api"}, "MaxConns": 50, "DB": {"Host": "db", "Hosts": ["a"]}}`,
+			want:   nil,
+		},
+		{
+			name:   "problems",
+			config: `{"max": 100, "Timeout": "5s", "Token": "t", "Extra": 1, "DB": {"Host": 1, "Hosts": []}}`,
+			want: []string{
+				"empty required field: DB.Hosts",
+				"missing required field: Base.Name",
+				"restricted key: Token may only be set from env",
+				"type mismatch: DB.Host: expected string, got number",
+				"unknown key: Extra",
+				"equals default: MaxConns = 100",
+				"equals default: Timeout = 5s",
+				"old key: max; rename it to MaxConns",
+			},
+			problems: true,
+		},
+		{
+			name:   "naming",
+			config: `{"base": {"name": "api"}, "max_conns": 50, "db": {"host": "db", "hosts": ["a"]}}`,
+			naming: optionator.SnakeCase,
+			want:   nil,
+		},
+	} {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		problems, err := diff(&out, path, ref, tt.naming)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		if text := strings.TrimSpace(out.String()); text != "" {
+			got = strings.Split(text, "\n")
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") || problems != tt.problems {
+			t.Errorf("%s: got problems=%v\n%s\nwant\n%s", tt.name, problems, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
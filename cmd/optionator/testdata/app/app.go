@@ -0,0 +1,24 @@
+package app
+
+import "time"
+
+// Base is embedded in Config.
+type Base struct {
+	Name string `required:"true"`
+}
+
+type DB struct {
+	// Host is the database server.
+	Host  string   `required:"true"`
+	Hosts []string `required:"nonempty"`
+	Pool  int      `default:"4"`
+}
+
+type Config struct {
+	Base
+	MaxConns int           `default:"100" alias:"max"`
+	Timeout  time.Duration `default:"5s"`
+	Token    string        `from:"env"`
+	Debug    bool          `hidden:"true"`
+	DB       DB
+}
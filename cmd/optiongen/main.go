@@ -0,0 +1,176 @@
+// Command optiongen generates an idiomatic functional-options constructor for
+// each struct in a package that is annotated with an //optionator:generate
+// comment or named with -type. The generated New<Type> delegates defaults and
+// required validation to optionator, while each With<Type><Field> option
+// assigns the field directly, so the generated API needs no reflection.
+//
+// Typical use is a go:generate directive next to the struct:
+//
+//	//go:generate go run github.com/chetan-giradkar/Optionator/cmd/optiongen
+//
+//	//optionator:generate
+//	type Server struct { ... }
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chetan-giradkar/Optionator/internal/structscan"
+)
+
+const directive = "optionator:generate"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("optiongen: ")
+	types := flag.String("type", "", "comma-separated struct names to generate for, in addition to annotated ones")
+	output := flag.String("output", "options_gen.go", "output file name, relative to the package directory")
+	dir := flag.String("dir", ".", "package directory")
+	flag.Parse()
+
+	var names []string
+	if *types != "" {
+		names = strings.Split(*types, ",")
+	}
+	src, err := generate(*dir, names, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// target is a struct selected for generation.
+type target struct {
+	name   string
+	fields []field
+}
+
+type field struct {
+	name string
+	typ  string
+	doc  string
+	def  string
+}
+
+// generate parses the package in dir and returns the formatted source of the
+// generated file. The previously generated output file is ignored.
+func generate(dir string, names []string, output string) ([]byte, error) {
+	pkg, err := structscan.Load(dir, func(name string) bool { return name == output })
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, n := range names {
+		wanted[strings.TrimSpace(n)] = true
+	}
+
+	var targets []target
+	imports := map[string]string{} // local name -> import spec
+	for _, s := range pkg.Structs {
+		if !wanted[s.Name] && !s.HasDirective(directive) {
+			continue
+		}
+		if s.Generic {
+			return nil, fmt.Errorf("%s: generic structs are not supported", s.Name)
+		}
+		delete(wanted, s.Name)
+		t := target{name: s.Name}
+		for _, f := range s.Fields {
+			for local, spec := range f.Imports {
+				imports[local] = spec
+			}
+			t.fields = append(t.fields, field{name: f.Name, typ: f.Type, doc: f.Doc, def: f.Tag.Get("default")})
+		}
+		targets = append(targets, t)
+	}
+	for n := range wanted {
+		return nil, fmt.Errorf("struct %s not found", n)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no structs annotated with //%s in %s", directive, dir)
+	}
+	return render(pkg.Name, imports, targets)
+}
+
+func render(pkgName string, imports map[string]string, targets []target) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by optiongen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkgName)
+	specs := make([]string, 0, len(imports))
+	for _, spec := range imports {
+		specs = append(specs, spec)
+	}
+	sort.Strings(specs)
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "\t%s\n", spec)
+	}
+	b.WriteString("\n\t\"github.com/chetan-giradkar/Optionator/pkg/optionator\"\n)\n")
+
+	// The parameters of the generated options must not shadow the struct
+	// types their closures name.
+	taken := map[string]bool{}
+	for _, t := range targets {
+		taken[t.name] = true
+	}
+	value, recv := unusedName("value", taken), unusedName("target", taken)
+
+	seen := map[string]string{}
+	for _, t := range targets {
+		fmt.Fprintf(&b, `
+// %[1]sOption configures a %[1]s built by New%[1]s.
+type %[1]sOption func(*%[1]s) error
+
+// New%[1]s returns a %[1]s with the defaults from its struct tags, the given
+// options applied in order, and its required fields validated.
+func New%[1]s(opts ...%[1]sOption) (*%[1]s, error) {
+	converted := make([]optionator.Option[*%[1]s], len(opts))
+	for i, opt := range opts {
+		converted[i] = optionator.Option[*%[1]s](opt)
+	}
+	return optionator.New(&%[1]s{}, converted...)
+}
+`, t.name)
+		for _, f := range t.fields {
+			fn := "With" + t.name + f.name
+			if other, ok := seen[fn]; ok {
+				return nil, fmt.Errorf("%s: option %s already generated for %s", t.name, fn, other)
+			}
+			seen[fn] = t.name
+			fmt.Fprintf(&b, "\n// %s sets %s", fn, f.name)
+			if f.def != "" {
+				fmt.Fprintf(&b, ", which defaults to %s", f.def)
+			}
+			b.WriteString(".")
+			if f.doc != "" {
+				b.WriteString("\n//\n// " + strings.ReplaceAll(f.doc, "\n", "\n// "))
+			}
+			fmt.Fprintf(&b, `
+func %s(%s %s) %sOption {
+	return func(%s *%s) error {
+		%s.%s = %s
+		return nil
+	}
+}
+`, fn, value, f.typ, t.name, recv, t.name, recv, f.name, value)
+		}
+	}
+	return format.Source(b.Bytes())
+}
+
+// unusedName returns name, with underscores appended until it is not taken.
+func unusedName(name string, taken map[string]bool) string {
+	for taken[name] {
+		name += "_"
+	}
+	taken[name] = true
+	return name
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestGenerate(t *testing.T) {
+	for _, name := range []string{"server", "shared"} {
+		dir := filepath.Join("testdata", name)
+		got, err := generate(dir, nil, "options_gen.go")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		golden := filepath.Join(dir, "options_gen.golden")
+		if *update {
+			if err := os.WriteFile(golden, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: generated code differs from %s:\n%s", name, golden, got)
+		}
+	}
+	if _, err := generate(filepath.Join("testdata", "server"), []string{"Missing"}, "options_gen.go"); err == nil {
+		t.Error("Expected an error for a missing -type struct")
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import "time"
+
+// Server is annotated for generation.
+//
+//optionator:generate
+type Server struct {
+	// Host is the interface to listen on.
+	Host    string `default:"localhost"`
+	Port    int    `default:"8080"`
+	Timeout time.Duration
+}
+
+// Ignored has no annotation.
+type Ignored struct {
+	Name string
+}
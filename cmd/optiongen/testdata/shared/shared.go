@@ -0,0 +1,17 @@
+package shared
+
+//optionator:generate
+type Volume struct {
+	Size int `default:"10"`
+	Path string
+}
+
+//optionator:generate
+type Disk struct {
+	Size int
+}
+
+//optionator:generate
+type value struct {
+	Size int
+}
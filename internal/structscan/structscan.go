@@ -0,0 +1,255 @@
+// Package structscan reads struct declarations and their tags from Go
+// source, for tools that work on packages they cannot import.
+package structscan
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package holds the struct types declared in one package directory.
+type Package struct {
+	Name    string
+	Dir     string
+	Structs []*Struct
+}
+
+// Struct is a struct type declaration.
+type Struct struct {
+	Name string
+	Doc  string
+	// Directives are comment lines such as "optionator:generate" found in
+	// the declaration's doc comment, without the leading slashes.
+	Directives []string
+	Generic    bool
+	Fields     []Field
+}
+
+// Field is an exported field of a struct. An embedded field is named after
+// its type, as reflect names it, and holds its fields nested under that
+// name, as optionator binds them.
+type Field struct {
+	Name     string
+	Embedded bool
+	// Type is the field type as written in source, e.g. "*tls.Config".
+	Type string
+	Expr ast.Expr
+	Tag  reflect.StructTag
+	Doc  string
+	// Imports maps each package name used in Type to its import spec,
+	// e.g. "tls" to `"crypto/tls"`.
+	Imports map[string]string
+}
+
+// Load parses the non-test Go files in dir, skipping files for which skip
+// returns true, and collects their struct types in file order.
+func Load(dir string, skip func(name string) bool) (*Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && (skip == nil || !skip(fi.Name()))
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	result := &Package{Name: pkg.Name, Dir: dir}
+	fileNames := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+	for _, name := range fileNames {
+		file := pkg.Files[name]
+		fileImports := importsByName(file)
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				s := &Struct{
+					Name:       ts.Name.Name,
+					Doc:        docText(doc),
+					Directives: directives(doc),
+					Generic:    ts.TypeParams != nil,
+				}
+				for _, f := range st.Fields.List {
+					typ, err := exprString(fset, f.Type)
+					if err != nil {
+						return nil, err
+					}
+					imports := map[string]string{}
+					for _, local := range selectorPackages(f.Type) {
+						spec, ok := fileImports[local]
+						if !ok {
+							return nil, fmt.Errorf("%s: unresolved package %s", ts.Name.Name, local)
+						}
+						imports[local] = spec
+					}
+					var tag reflect.StructTag
+					if f.Tag != nil {
+						if raw, err := strconv.Unquote(f.Tag.Value); err == nil {
+							tag = reflect.StructTag(raw)
+						}
+					}
+					names := f.Names
+					if len(names) == 0 {
+						names = []*ast.Ident{embeddedName(f.Type)}
+					}
+					for _, n := range names {
+						if n == nil || !n.IsExported() {
+							continue
+						}
+						s.Fields = append(s.Fields, Field{
+							Name:     n.Name,
+							Embedded: len(f.Names) == 0,
+							Type:     typ,
+							Expr:     f.Type,
+							Tag:      tag,
+							Doc:      docText(f.Doc),
+							Imports:  imports,
+						})
+					}
+				}
+				result.Structs = append(result.Structs, s)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Lookup returns the struct with the given name.
+func (p *Package) Lookup(name string) (*Struct, bool) {
+	for _, s := range p.Structs {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// HasDirective reports whether the struct's doc comment contains d.
+func (s *Struct) HasDirective(d string) bool {
+	for _, have := range s.Directives {
+		if have == d {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalStruct returns the name of the struct type a field refers to when it
+// is a struct, or pointer to a struct, declared in the same package.
+func (f Field) LocalStruct(p *Package) (string, bool) {
+	expr := f.Expr
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	_, ok = p.Lookup(id.Name)
+	return id.Name, ok
+}
+
+// embeddedName returns the field name of an embedded type expression: the
+// type name, without pointer, package or type arguments.
+func embeddedName(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedName(e.X)
+	}
+	return nil
+}
+
+func directives(doc *ast.CommentGroup) []string {
+	if doc == nil {
+		return nil
+	}
+	var ds []string
+	for _, c := range doc.List {
+		text := strings.TrimPrefix(c.Text, "//")
+		if text != c.Text && !strings.HasPrefix(text, " ") && strings.Contains(text, ":") {
+			ds = append(ds, strings.TrimSpace(text))
+		}
+	}
+	return ds
+}
+
+// importsByName maps the local name of each import in file to its spec.
+func importsByName(file *ast.File) map[string]string {
+	m := map[string]string{}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		local := path[strings.LastIndex(path, "/")+1:]
+		spec := imp.Path.Value
+		if imp.Name != nil {
+			local = imp.Name.Name
+			spec = imp.Name.Name + " " + imp.Path.Value
+		}
+		m[local] = spec
+	}
+	return m
+}
+
+// selectorPackages returns the package names referenced by a type expression.
+func selectorPackages(expr ast.Expr) []string {
+	var pkgs []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				pkgs = append(pkgs, id.Name)
+			}
+		}
+		return true
+	})
+	return pkgs
+}
+
+func exprString(fset *token.FileSet, expr ast.Expr) (string, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, expr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
@@ -0,0 +1,79 @@
+package structscan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package app
+
+import (
+	"time"
+
+	tls "crypto/tls"
+)
+
+// Base is embedded.
+type Base struct {
+	Name string
+}
+
+type hidden struct {
+	Secret string
+}
+
+// App is annotated.
+//
+//optionator:generate
+type App struct {
+	Base
+	*hidden
+	// Timeout bounds requests.
+	Timeout time.Duration ` + "`default:\"5s\"`" + `
+	TLS     *tls.Config
+	A, b    int
+}
+`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "skipped.go"), []byte("package app\n\ntype Skipped struct{}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := Load(dir, func(name string) bool { return name == "skipped.go" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pkg.Lookup("Skipped"); ok {
+		t.Error("Expected skipped.go to be left out")
+	}
+	app, ok := pkg.Lookup("App")
+	if !ok || !app.HasDirective("optionator:generate") || app.Doc != "App is annotated." {
+		t.Fatalf("App = %+v", app)
+	}
+	var names []string
+	for _, f := range app.Fields {
+		names = append(names, f.Name)
+	}
+	if want := "Base Timeout TLS A"; strings.Join(names, " ") != want {
+		t.Fatalf("fields = %q, want %q", strings.Join(names, " "), want)
+	}
+	base, timeout, tlsField := app.Fields[0], app.Fields[1], app.Fields[2]
+	if name, ok := base.LocalStruct(pkg); !base.Embedded || !ok || name != "Base" {
+		t.Errorf("Base = %+v, local %q %v", base, name, ok)
+	}
+	if timeout.Type != "time.Duration" || timeout.Tag.Get("default") != "5s" || timeout.Doc != "Timeout bounds requests." || timeout.Imports["time"] != `"time"` {
+		t.Errorf("Timeout = %+v", timeout)
+	}
+	if tlsField.Type != "*tls.Config" || tlsField.Imports["tls"] != `tls "crypto/tls"` {
+		t.Errorf("TLS = %+v", tlsField)
+	}
+	if _, ok := tlsField.LocalStruct(pkg); ok {
+		t.Error("Expected *tls.Config not to be a local struct")
+	}
+}
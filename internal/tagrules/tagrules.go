@@ -0,0 +1,25 @@
+// Package tagrules holds the rules for reading optionator struct tags that
+// the library and the tools reading structs from source share, so the two
+// cannot drift apart.
+package tagrules
+
+import "strings"
+
+// Required reports whether a required tag value demands a value: "true",
+// or for slices and maps "nonnil", which accepts an empty one, and
+// "nonempty", which does not.
+func Required(value string) bool {
+	return value == "true" || value == "nonnil" || value == "nonempty"
+}
+
+// List splits a comma-separated tag value such as a from or alias tag,
+// dropping blanks.
+func List(value string) []string {
+	var list []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -0,0 +1,176 @@
+// Package admission validates configurations embedded in Kubernetes
+// objects, such as the spec of a custom resource, from a validating
+// admission webhook. It speaks the admission.k8s.io/v1 AdmissionReview JSON
+// directly rather than depending on the Kubernetes client libraries.
+//
+// The config section of each admitted object is loaded over the defaults of
+// a fresh target with optionator.NewLenient, so every problem is reported
+// at once: errors deny the object with one status cause per field, and
+// warnings such as unknown keys are returned as admission warnings, which
+// kubectl prints.
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Review is an admission.k8s.io/v1 AdmissionReview.
+type Review struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Request    *Request  `json:"request,omitempty"`
+	Response   *Response `json:"response,omitempty"`
+}
+
+// Request is the part of an AdmissionRequest the webhook reads.
+type Request struct {
+	UID       string          `json:"uid"`
+	Operation string          `json:"operation,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Object    json.RawMessage `json:"object,omitempty"`
+}
+
+// Response is an AdmissionResponse.
+type Response struct {
+	UID      string   `json:"uid"`
+	Allowed  bool     `json:"allowed"`
+	Result   *Status  `json:"result,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Status is the metav1.Status explaining a denial.
+type Status struct {
+	Code    int32          `json:"code,omitempty"`
+	Reason  string         `json:"reason,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Details *StatusDetails `json:"details,omitempty"`
+}
+
+// StatusDetails lists the causes of a denial.
+type StatusDetails struct {
+	Causes []StatusCause `json:"causes,omitempty"`
+}
+
+// StatusCause is the failure of one field, named by its path in the
+// admitted object.
+type StatusCause struct {
+	Type    string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// Validator validates the config section of admitted objects as T.
+type Validator[T any] struct {
+	// New returns the target to load each section into, such as
+	// func() *Config { return &Config{} }.
+	New func() T
+	// Path is the dotted path of the config section in the object, such
+	// as "spec.config"; empty means the whole object.
+	Path string
+	// Config is passed to NewLenient, so it names the tags as usual, e.g.
+	// optionator.Config{DefaultTag: "default", RequiredTag: "required"}.
+	// Its NamingStrategy also names the fields of status causes.
+	Config optionator.Config
+}
+
+// Review validates the object of req and returns the response for it.
+func (v Validator[T]) Review(req *Request) *Response {
+	resp := &Response{UID: req.UID}
+	section, err := v.section(req.Object)
+	if err != nil {
+		resp.Result = &Status{Code: http.StatusBadRequest, Reason: "BadRequest", Message: err.Error()}
+		return resp
+	}
+	config := v.Config
+	config.Sources = append(append([]optionator.Source{}, config.Sources...), optionator.MapSource{Values: section})
+	_, findings := optionator.NewLenient(v.New(), config)
+	var causes []StatusCause
+	for _, f := range findings {
+		field := v.fieldPath(f.Path)
+		if f.Severity == optionator.SeverityWarning {
+			if field != "" {
+				resp.Warnings = append(resp.Warnings, field+": "+f.Message)
+			} else {
+				resp.Warnings = append(resp.Warnings, f.Message)
+			}
+			continue
+		}
+		causes = append(causes, StatusCause{Type: "FieldValueInvalid", Message: f.Message, Field: field})
+	}
+	if len(causes) == 0 {
+		resp.Allowed = true
+		return resp
+	}
+	msgs := make([]string, len(causes))
+	for i, c := range causes {
+		msgs[i] = c.Message
+	}
+	resp.Result = &Status{
+		Code:    http.StatusUnprocessableEntity,
+		Reason:  "Invalid",
+		Message: fmt.Sprintf("invalid %s: %s", v.sectionName(), strings.Join(msgs, "; ")),
+		Details: &StatusDetails{Causes: causes},
+	}
+	return resp
+}
+
+// ServeHTTP answers an AdmissionReview posted by the API server.
+func (v Validator[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var review Review
+	if err := json.NewDecoder(io.LimitReader(r.Body, 3<<20)).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "expected an AdmissionReview with a request", http.StatusBadRequest)
+		return
+	}
+	review.Response = v.Review(review.Request)
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+// section decodes object and returns the map at v.Path.
+func (v Validator[T]) section(object json.RawMessage) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("decoding object: %w", err)
+	}
+	if v.Path == "" {
+		return values, nil
+	}
+	for _, key := range strings.Split(v.Path, ".") {
+		next, ok := values[key].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("object has no %s object", v.Path)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// fieldPath names the field at a dotted Go path in the admitted object.
+func (v Validator[T]) fieldPath(path string) string {
+	if path == "" {
+		return v.Path
+	}
+	path = v.Config.NamingStrategy.Key(path)
+	if v.Path == "" {
+		return path
+	}
+	return v.Path + "." + path
+}
+
+func (v Validator[T]) sectionName() string {
+	if v.Path == "" {
+		return "object"
+	}
+	return v.Path
+}
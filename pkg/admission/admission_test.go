@@ -0,0 +1,68 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type cacheConfig struct {
+	Size     int    `default:"128" min:"1"`
+	Eviction string `default:"lru" oneof:"lru,lfu"`
+	Backend  string `required:"true"`
+}
+
+func TestValidator(t *testing.T) {
+	config := optionator.Config{DefaultTag: "default", RequiredTag: "required", NamingStrategy: optionator.CamelCase}
+	v := Validator[*cacheConfig]{New: func() *cacheConfig { return &cacheConfig{} }, Path: "spec.cache", Config: config}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	review := func(object string) *Response {
+		body, _ := json.Marshal(Review{
+			APIVersion: "admission.k8s.io/v1",
+			Kind:       "AdmissionReview",
+			Request:    &Request{UID: "42", Object: json.RawMessage(object)},
+		})
+		res, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var out Review
+		if err := json.NewDecoder(res.Body).Decode(&out); err != nil || out.Response == nil {
+			t.Fatalf("Decoding review: %v", err)
+		}
+		if out.Response.UID != "42" || out.Kind != "AdmissionReview" {
+			t.Errorf("Unexpected review %+v", out)
+		}
+		return out.Response
+	}
+
+	resp := review(`{"spec": {"cache": {"backend": "redis", "size": 64, "ttl": "5m"}}}`)
+	if !resp.Allowed || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "unknown key ttl") {
+		t.Errorf("Expected an allowed object with an unknown key warning, got %+v", resp)
+	}
+
+	resp = review(`{"spec": {"cache": {"size": 0, "eviction": "fifo"}}}`)
+	if resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected a denial, got %+v", resp)
+	}
+	var fields []string
+	for _, c := range resp.Result.Details.Causes {
+		fields = append(fields, c.Field)
+	}
+	if got := strings.Join(fields, ","); got != "spec.cache.size,spec.cache.eviction,spec.cache.backend" {
+		t.Errorf("Unexpected cause fields %s", got)
+	}
+
+	resp = review(`{"spec": {}}`)
+	if resp.Allowed || resp.Result.Code != http.StatusBadRequest {
+		t.Errorf("Expected a missing section to be a bad request, got %+v", resp)
+	}
+}
@@ -0,0 +1,192 @@
+// Package bench is a benchmark suite for optionator construction: small,
+// medium and huge structs, deep nesting, many options, sources and
+// concurrent construction. Run it on the hardware you plan capacity for,
+// or before and after a change, and compare the runs with benchstat:
+//
+//	go test -run '^$' -bench . -count 10 github.com/chetan-giradkar/Optionator/pkg/bench > old.txt
+//	# change optionator, then
+//	go test -run '^$' -bench . -count 10 github.com/chetan-giradkar/Optionator/pkg/bench > new.txt
+//	benchstat old.txt new.txt
+//
+// Each case reports allocations and the time per field constructed, in
+// ns/field, which is what grows with the size of a config. Other modules
+// can run the suite from a benchmark of their own with Run.
+package bench
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Small is a config of three flat fields.
+type Small struct {
+	Host    string `default:"localhost"`
+	Port    int    `default:"8080" min:"1"`
+	Verbose bool
+}
+
+// Listener is the nested part of Medium.
+type Listener struct {
+	Address string        `default:"0.0.0.0"`
+	Port    int           `default:"8080" min:"1" max:"65535"`
+	Timeout time.Duration `default:"30s"`
+}
+
+// Medium is a typical service config: a dozen fields of assorted kinds,
+// nested structs, a pointer and a list.
+type Medium struct {
+	Name      string        `default:"svc" required:"true"`
+	Mode      string        `default:"fast" oneof:"fast,safe"`
+	Workers   int           `default:"8" min:"1"`
+	Ratio     float64       `default:"0.5"`
+	Enabled   bool          `default:"true"`
+	Interval  time.Duration `default:"1m"`
+	Tags      []string      `default:"a,b,c"`
+	Token     string        `secret:"true"`
+	Public    Listener
+	Admin     *Listener
+	RetryMax  int  `default:"3"`
+	RetryBack bool `default:"true"`
+}
+
+// mediumFields is the number of leaf fields of Medium.
+const mediumFields = 16
+
+// Case is one benchmark of the suite.
+type Case struct {
+	// Name is the sub-benchmark name, stable across releases so results
+	// stay comparable.
+	Name string
+	// Fields is the number of leaf fields constructed per operation.
+	Fields int
+	// Op constructs one config.
+	Op func() error
+	// Parallel runs Op from GOMAXPROCS goroutines at once.
+	Parallel bool
+}
+
+// Cases returns the cases of the suite.
+func Cases() []Case {
+	huge := HugeType(500)
+	deep := DeepType(16)
+	opts := make([]optionator.Option[*Medium], 32)
+	for i := range opts {
+		opts[i] = optionator.With[*Medium]("Workers", i+1)
+	}
+	source := optionator.Config{
+		DefaultTag:  "default",
+		RequiredTag: "required",
+		Sources: []optionator.Source{optionator.MapSource{Values: map[string]any{
+			"Name":    "api",
+			"Workers": 16,
+			"Tags":    []any{"x", "y"},
+			"Public":  map[string]any{"Port": 9090, "Timeout": "5s"},
+		}}},
+	}
+	medium := func() error {
+		_, err := optionator.New(&Medium{})
+		return err
+	}
+	return []Case{
+		{Name: "small", Fields: 3, Op: func() error {
+			_, err := optionator.New(&Small{})
+			return err
+		}},
+		{Name: "medium", Fields: mediumFields, Op: medium},
+		{Name: "huge", Fields: 500, Op: func() error {
+			_, err := optionator.New(reflect.New(huge).Interface())
+			return err
+		}},
+		{Name: "deep", Fields: 16, Op: func() error {
+			_, err := optionator.New(reflect.New(deep).Interface())
+			return err
+		}},
+		{Name: "options", Fields: mediumFields, Op: func() error {
+			_, err := optionator.New(&Medium{}, opts...)
+			return err
+		}},
+		{Name: "sources", Fields: mediumFields, Op: func() error {
+			_, err := optionator.NewWithConfig(&Medium{}, source)
+			return err
+		}},
+		{Name: "concurrent", Fields: mediumFields, Op: medium, Parallel: true},
+	}
+}
+
+// Run runs every case as a sub-benchmark of b.
+func Run(b *testing.B) {
+	for _, c := range Cases() {
+		c := c
+		b.Run(c.Name, c.Benchmark)
+	}
+}
+
+// Benchmark runs the case as the benchmark b.
+func (c Case) Benchmark(b *testing.B) {
+	if err := c.Op(); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	if c.Parallel {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := c.Op(); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	} else {
+		for i := 0; i < b.N; i++ {
+			if err := c.Op(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Nanoseconds())/float64(b.N)/float64(c.Fields), "ns/field")
+}
+
+// HugeType returns a flat struct type with n fields of assorted kinds, each
+// with a default.
+func HugeType(n int) reflect.Type {
+	kinds := []struct {
+		typ reflect.Type
+		def string
+	}{
+		{reflect.TypeOf(""), "value"},
+		{reflect.TypeOf(0), "42"},
+		{reflect.TypeOf(false), "true"},
+		{reflect.TypeOf(time.Duration(0)), "1s"},
+		{reflect.TypeOf(0.0), "0.25"},
+	}
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		k := kinds[i%len(kinds)]
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: k.typ,
+			Tag:  reflect.StructTag(fmt.Sprintf(`default:%q`, k.def)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// DeepType returns a struct type nested depth levels deep, with a field
+// with a default at each level.
+func DeepType(depth int) reflect.Type {
+	t := reflect.StructOf([]reflect.StructField{{Name: "Value", Type: reflect.TypeOf(0), Tag: `default:"1"`}})
+	for i := 1; i < depth; i++ {
+		t = reflect.StructOf([]reflect.StructField{
+			{Name: "Value", Type: reflect.TypeOf(0), Tag: `default:"1"`},
+			{Name: "Next", Type: t},
+		})
+	}
+	return t
+}
@@ -0,0 +1,34 @@
+//go:build !race
+
+package bench
+
+import "testing"
+
+func BenchmarkConstruct(b *testing.B) { Run(b) }
+
+// allocBudgets bounds the allocations of each case, so a change that makes
+// the reflection paths allocate more fails here before it ships.
+var allocBudgets = map[string]float64{
+	"small":   4,
+	"medium":  24,
+	"huge":    4,
+	"deep":    56,
+	"options": 100,
+	"sources": 36,
+}
+
+func TestAllocBudgets(t *testing.T) {
+	for _, c := range Cases() {
+		budget, ok := allocBudgets[c.Name]
+		if !ok {
+			continue
+		}
+		if err := c.Op(); err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		allocs := testing.AllocsPerRun(50, func() { c.Op() })
+		if allocs > budget {
+			t.Errorf("%s: %v allocations per run, over the budget of %v", c.Name, allocs, budget)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package configservice is the client side of the ConfigService defined in
+// proto/optionator/v1/config_service.proto. Source loads a configuration
+// document from the service as an optionator source, and Watch follows the
+// service's stream of revisions, reloading a Live value on each one.
+//
+// The package does not depend on gRPC. It talks to the service through a
+// Transport, which a generated gRPC client adapts to in a few lines; the
+// package provides an HTTP/JSON transport and handler, using the proto3 JSON
+// mapping of the messages, and an in-memory transport for control planes
+// embedded in the same process and for tests.
+package configservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// GetConfigRequest mirrors optionator.v1.GetConfigRequest.
+type GetConfigRequest struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// WatchConfigRequest mirrors optionator.v1.WatchConfigRequest.
+type WatchConfigRequest struct {
+	Name     string `json:"name"`
+	Profile  string `json:"profile,omitempty"`
+	Revision uint64 `json:"revision,string,omitempty"`
+}
+
+// ConfigDocument mirrors optionator.v1.ConfigDocument. Values has the shape
+// optionator sources produce: an object keyed by field name, with nested
+// objects for nested structs.
+type ConfigDocument struct {
+	Name     string         `json:"name"`
+	Revision uint64         `json:"revision,string"`
+	Values   map[string]any `json:"values"`
+}
+
+// ErrNotFound is returned for a configuration the service does not have.
+var ErrNotFound = errors.New("configuration not found")
+
+// Transport carries the two ConfigService calls.
+type Transport interface {
+	GetConfig(ctx context.Context, req GetConfigRequest) (*ConfigDocument, error)
+	// WatchConfig sends the current document, then every new revision, to
+	// send until ctx is done, send fails or the stream breaks. Documents at
+	// or below req.Revision are not sent.
+	WatchConfig(ctx context.Context, req WatchConfigRequest, send func(*ConfigDocument) error) error
+}
+
+// Source is an optionator.Source serving the document of one configuration.
+// It fetches the current document on each load, except while Watch follows
+// it, when it serves the last document the stream delivered.
+type Source struct {
+	Transport Transport
+	// Config names the configuration, e.g. "gateway".
+	Config string
+	// Profile selects a profile such as "prod".
+	Profile string
+
+	mu       sync.Mutex
+	doc      *ConfigDocument
+	watching bool
+}
+
+// FromConfigService is the kind of Source in from tags.
+const FromConfigService optionator.SourceKind = "configservice"
+
+// Name identifies the source in errors.
+func (s *Source) Name() string { return "configservice:" + s.Config }
+
+// Kind returns FromConfigService.
+func (s *Source) Kind() optionator.SourceKind { return FromConfigService }
+
+// Load returns the values of the current document.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	if s.watching && s.doc != nil {
+		doc := s.doc
+		s.mu.Unlock()
+		return doc.Values, nil
+	}
+	s.mu.Unlock()
+	doc, err := s.Transport.GetConfig(ctx, GetConfigRequest{Name: s.Config, Profile: s.Profile})
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.doc == nil || doc.Revision >= s.doc.Revision {
+		s.doc = doc
+	}
+	s.mu.Unlock()
+	return doc.Values, nil
+}
+
+// Revision returns the revision of the last document loaded or received, or
+// zero before the first.
+func (s *Source) Revision() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.doc == nil {
+		return 0
+	}
+	return s.doc.Revision
+}
+
+// Watch follows the revisions of src's configuration until ctx is done,
+// reloading live, which must be built with src among its sources, on each
+// new one. A broken stream is reopened after retry, from the last revision
+// received. Stream and reload errors go to onError if it is not nil.
+func Watch[T any](ctx context.Context, live *optionator.Live[T], src *Source, retry time.Duration, onError func(error)) {
+	src.mu.Lock()
+	src.watching = true
+	src.mu.Unlock()
+	defer func() {
+		src.mu.Lock()
+		src.watching = false
+		src.mu.Unlock()
+	}()
+	report := func(err error) {
+		if onError != nil {
+			onError(err)
+		}
+	}
+	for {
+		req := WatchConfigRequest{Name: src.Config, Profile: src.Profile, Revision: src.Revision()}
+		err := src.Transport.WatchConfig(ctx, req, func(doc *ConfigDocument) error {
+			if doc.Revision <= src.Revision() {
+				return nil
+			}
+			src.mu.Lock()
+			src.doc = doc
+			src.mu.Unlock()
+			if err := live.Reload(ctx); err != nil {
+				report(fmt.Errorf("revision %d: %w", doc.Revision, err))
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			report(fmt.Errorf("watching %s: %w", src.Config, err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package configservice
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type gateway struct {
+	Port   int `default:"8080"`
+	Limits struct {
+		Rate int `default:"10"`
+	}
+}
+
+func TestSourceAndWatch(t *testing.T) {
+	service := NewMemory()
+	service.Publish("gateway", "prod", map[string]any{"Port": 9000.0, "Limits": map[string]any{"Rate": 50.0}})
+	server := httptest.NewServer(Handler(service))
+	defer server.Close()
+
+	src := &Source{Transport: HTTPTransport{URL: server.URL}, Config: "gateway", Profile: "prod"}
+	config := optionator.Config{DefaultTag: "default", Sources: []optionator.Source{src}}
+	live, err := optionator.NewLive(func() *gateway { return &gateway{} }, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg := live.Load(); cfg.Port != 9000 || cfg.Limits.Rate != 50 || src.Revision() != 1 {
+		t.Fatalf("got %+v at revision %d", cfg, src.Revision())
+	}
+
+	updated := make(chan *gateway, 1)
+	live.Subscribe(func(_, cfg *gateway) { updated <- cfg })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	go Watch(ctx, live, src, 10*time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	service.Publish("gateway", "prod", map[string]any{"Port": 9001.0})
+	select {
+	case cfg := <-updated:
+		if cfg.Port != 9001 || cfg.Limits.Rate != 10 {
+			t.Errorf("pushed config = %+v", cfg)
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after publish")
+	}
+	if src.Revision() != 2 {
+		t.Errorf("revision = %d", src.Revision())
+	}
+
+	missing := &Source{Transport: HTTPTransport{URL: server.URL}, Config: "absent"}
+	if _, err := missing.Load(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package configservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The HTTP mapping of the service: GET /v1/configs/{name} returns the
+// current ConfigDocument as JSON, and GET /v1/configs/{name}:watch streams
+// one JSON document per line. Both take profile as a query parameter, and
+// the watch takes revision.
+const configsPath = "/v1/configs/"
+
+// HTTPTransport calls a ConfigService served by Handler.
+type HTTPTransport struct {
+	// URL is the base URL of the service, e.g. "http://control-plane:8080".
+	URL string
+	// Client makes the requests; http.DefaultClient when nil.
+	Client *http.Client
+}
+
+func (t HTTPTransport) client() *http.Client {
+	if t.Client == nil {
+		return http.DefaultClient
+	}
+	return t.Client
+}
+
+func (t HTTPTransport) get(ctx context.Context, name, suffix string, query url.Values) (*http.Response, error) {
+	u := strings.TrimSuffix(t.URL, "/") + configsPath + url.PathEscape(name) + suffix
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("config service: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// GetConfig fetches the current document.
+func (t HTTPTransport) GetConfig(ctx context.Context, req GetConfigRequest) (*ConfigDocument, error) {
+	query := url.Values{}
+	if req.Profile != "" {
+		query.Set("profile", req.Profile)
+	}
+	resp, err := t.get(ctx, req.Name, "", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var doc ConfigDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("config service: decoding document: %w", err)
+	}
+	return &doc, nil
+}
+
+// WatchConfig streams documents until ctx is done, send fails or the
+// connection breaks.
+func (t HTTPTransport) WatchConfig(ctx context.Context, req WatchConfigRequest, send func(*ConfigDocument) error) error {
+	query := url.Values{"revision": {strconv.FormatUint(req.Revision, 10)}}
+	if req.Profile != "" {
+		query.Set("profile", req.Profile)
+	}
+	resp, err := t.get(ctx, req.Name, ":watch", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var doc ConfigDocument
+		if err := dec.Decode(&doc); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("config service: decoding document: %w", err)
+		}
+		if err := send(&doc); err != nil {
+			return err
+		}
+	}
+}
+
+// Handler serves t over HTTP, for HTTPTransport clients.
+func Handler(t Transport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, configsPath)
+		if r.Method != http.MethodGet || len(name) == len(r.URL.Path) || name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		profile := r.URL.Query().Get("profile")
+		if watch := strings.TrimSuffix(name, ":watch"); len(watch) < len(name) {
+			serveWatch(w, r, t, WatchConfigRequest{Name: watch, Profile: profile})
+			return
+		}
+		doc, err := t.GetConfig(r.Context(), GetConfigRequest{Name: name, Profile: profile})
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
+
+func serveWatch(w http.ResponseWriter, r *http.Request, t Transport, req WatchConfigRequest) {
+	if rev := r.URL.Query().Get("revision"); rev != "" {
+		n, err := strconv.ParseUint(rev, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid revision", http.StatusBadRequest)
+			return
+		}
+		req.Revision = n
+	}
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	// Send the headers at once, so the client is not left waiting for the
+	// first revision to learn that the stream is open.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flush()
+	enc := json.NewEncoder(w)
+	t.WatchConfig(r.Context(), req, func(doc *ConfigDocument) error {
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
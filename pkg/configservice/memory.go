@@ -0,0 +1,72 @@
+package configservice
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-process ConfigService holding the documents a control
+// plane publishes. It is a Transport, and Handler serves it over HTTP.
+type Memory struct {
+	mu      sync.Mutex
+	docs    map[docKey]*ConfigDocument
+	changed chan struct{} // closed and replaced on each Publish
+}
+
+type docKey struct{ name, profile string }
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{docs: map[docKey]*ConfigDocument{}, changed: make(chan struct{})}
+}
+
+// Publish makes values the next revision of the configuration name under
+// profile and returns that revision. Values must not be modified afterwards.
+func (m *Memory) Publish(name, profile string, values map[string]any) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := docKey{name, profile}
+	var revision uint64 = 1
+	if doc := m.docs[key]; doc != nil {
+		revision = doc.Revision + 1
+	}
+	m.docs[key] = &ConfigDocument{Name: name, Revision: revision, Values: values}
+	close(m.changed)
+	m.changed = make(chan struct{})
+	return revision
+}
+
+func (m *Memory) current(name, profile string) (*ConfigDocument, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.docs[docKey{name, profile}], m.changed
+}
+
+// GetConfig returns the current document, or ErrNotFound.
+func (m *Memory) GetConfig(ctx context.Context, req GetConfigRequest) (*ConfigDocument, error) {
+	doc, _ := m.current(req.Name, req.Profile)
+	if doc == nil {
+		return nil, ErrNotFound
+	}
+	return doc, nil
+}
+
+// WatchConfig sends each revision after req.Revision until ctx is done or
+// send fails.
+func (m *Memory) WatchConfig(ctx context.Context, req WatchConfigRequest, send func(*ConfigDocument) error) error {
+	revision := req.Revision
+	for {
+		doc, changed := m.current(req.Name, req.Profile)
+		if doc != nil && doc.Revision > revision {
+			if err := send(doc); err != nil {
+				return err
+			}
+			revision = doc.Revision
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package cueconfig checks optionator configurations against CUE schemas
+// and loads defaults from them, by running the cue command rather than
+// depending on the CUE module.
+//
+// Schemas describe the configuration in the shape WriteJSON produces: one
+// field per struct field name, nested structs as nested objects and
+// durations as strings such as "5s".
+package cueconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Command is the cue executable that is run.
+var Command = "cue"
+
+// Vet checks the effective configuration of target, a pointer to a struct,
+// against the CUE files with cue vet. Secret fields are checked with their
+// real values, which only reach cue through a temporary file.
+func Vet(ctx context.Context, target any, files ...string) error {
+	values, err := optionator.Values(target)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = optionator.WriteJSON(&buf, target, optionator.ExportOptions{
+		SecretRef: func(path string) string { return fmt.Sprint(values[path]) },
+	})
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "optionator-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	args := append(append([]string{"vet"}, files...), f.Name())
+	if _, err := run(ctx, args); err != nil {
+		return fmt.Errorf("cue vet: %w", err)
+	}
+	return nil
+}
+
+// Source returns a Source loading the concrete values of the CUE files, as
+// cue export gives them, so schema defaults such as `port: int | *8080`
+// fill the configuration. Expr, if not empty, selects the value to export,
+// such as "defaults"; the exported value must be concrete.
+func Source(expr string, files ...string) optionator.Source {
+	return source{expr: expr, files: files}
+}
+
+type source struct {
+	expr  string
+	files []string
+}
+
+func (s source) Name() string { return "cue:" + strings.Join(s.files, ",") }
+
+func (s source) Load(ctx context.Context) (map[string]any, error) {
+	args := []string{"export", "--out", "json"}
+	if s.expr != "" {
+		args = append(args, "-e", s.expr)
+	}
+	out, err := run(ctx, append(args, s.files...))
+	if err != nil {
+		return nil, fmt.Errorf("cue export: %w", err)
+	}
+	var values map[string]any
+	if err := json.Unmarshal(out, &values); err != nil {
+		return nil, fmt.Errorf("cue export: %w", err)
+	}
+	return values, nil
+}
+
+// run runs Command with args and returns its output, or an error carrying
+// what it printed to stderr.
+func run(ctx context.Context, args []string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, Command, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
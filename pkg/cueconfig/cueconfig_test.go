@@ -0,0 +1,74 @@
+package cueconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type server struct {
+	Host     string `default:"localhost"`
+	Port     int
+	Password string `secret:"true" default:"hunter2"`
+}
+
+// fakeCue points Command at a script standing in for cue: it prints the
+// arguments it got and, for vet, the data file, then runs body.
+func fakeCue(t *testing.T, body string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := "#!/bin/sh\necho \"$@\" > " + log + "\n" +
+		"if [ \"$1\" = vet ]; then for f; do :; done; cat \"$f\" >> " + log + "; fi\n" + body + "\n"
+	path := filepath.Join(dir, "cue")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := Command
+	Command = path
+	t.Cleanup(func() { Command = old })
+	return log
+}
+
+func TestSource(t *testing.T) {
+	log := fakeCue(t, `echo '{"Port": 8080}'`)
+	config := optionator.Config{DefaultTag: "default", RequiredTag: "required", Sources: []optionator.Source{Source("defaults", "schema.cue")}}
+	s, err := optionator.NewWithConfig(&server{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Port != 8080 || s.Host != "localhost" {
+		t.Errorf("got %+v", s)
+	}
+	args, _ := os.ReadFile(log)
+	if got := strings.TrimSpace(string(args)); got != "export --out json -e defaults schema.cue" {
+		t.Errorf("args = %q", got)
+	}
+}
+
+func TestVet(t *testing.T) {
+	log := fakeCue(t, "")
+	s, err := optionator.New(&server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Vet(context.Background(), s, "schema.cue"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(log)
+	if !strings.HasPrefix(string(data), "vet schema.cue ") || !strings.Contains(string(data), `"Password": "hunter2"`) {
+		t.Errorf("cue got %q", data)
+	}
+
+	fakeCue(t, "echo 'Port: invalid value 0' >&2; exit 1")
+	if err := Vet(context.Background(), s, "schema.cue"); err == nil || !strings.Contains(err.Error(), "Port: invalid value 0") {
+		t.Errorf("expected vet error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Package docgen renders optionator struct metadata as a static HTML
+// configuration reference.
+package docgen
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Section is the configuration reference of one struct.
+type Section struct {
+	Name   string
+	Fields []optionator.FieldInfo
+}
+
+// SectionFor describes T and returns it as a section with the given name,
+// leaving out hidden fields.
+func SectionFor[T any](name string) (Section, error) {
+	fields, err := optionator.Describe[T]()
+	if err != nil {
+		return Section{}, err
+	}
+	var shown []optionator.FieldInfo
+	for _, f := range fields {
+		if !f.Hidden {
+			shown = append(shown, f)
+		}
+	}
+	return Section{Name: name, Fields: shown}, nil
+}
+
+// WriteHTML renders the sections as a single self-contained HTML page with a
+// searchable table per field group of each section and an anchor per field.
+func WriteHTML(w io.Writer, title string, sections ...Section) error {
+	return page.Execute(w, struct {
+		Title    string
+		Sections []Section
+	}{title, sections})
+}
+
+// anchor returns the id of a field row, e.g. "server-nested-port".
+func anchor(section, path string) string {
+	return strings.ToLower(strings.ReplaceAll(section+"-"+path, ".", "-"))
+}
+
+// rules lists the validation rules that apply to a field.
+func rules(f optionator.FieldInfo) string {
+	if f.Required {
+		return "required"
+	}
+	return ""
+}
+
+var page = template.Must(template.New("page").Funcs(template.FuncMap{
+	"anchor": anchor,
+	"rules":  rules,
+	"lower":  strings.ToLower,
+	"groups": optionator.GroupFields,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f4f4f4; }
+tr:target { background: #fff6d5; }
+code { font-size: 0.95em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="Filter fields" autofocus>
+{{range $s := .Sections}}
+<h2 id="{{lower $s.Name}}">{{$s.Name}}</h2>
+{{- range $g := groups $s.Fields}}
+{{- if $g.Name}}
+<h3 id="{{anchor $s.Name $g.Name}}">{{$g.Name}}</h3>
+{{- end}}
+<table>
+<thead><tr><th>Field</th><th>Type</th><th>Default</th><th>Validation</th><th>Description</th></tr></thead>
+<tbody>
+{{- range $g.Fields}}
+<tr id="{{anchor $s.Name .Path}}"><td><a href="#{{anchor $s.Name .Path}}"><code>{{.Path}}</code></a></td><td><code>{{.Type}}</code></td><td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td><td>{{rules .}}</td><td>{{if and .Stability (ne .Stability "stable")}}<em>{{.Stability}}</em> {{end}}{{.Description}}{{if .Example}} Example: <code>{{.Example}}</code>{{end}}{{if .Docs}} <a href="{{.Docs}}">Docs</a>{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{end}}
+<script>
+document.getElementById("search").addEventListener("input", function (e) {
+  var q = e.target.value.toLowerCase();
+  document.querySelectorAll("tbody tr").forEach(function (row) {
+    row.style.display = row.textContent.toLowerCase().indexOf(q) >= 0 ? "" : "none";
+  });
+});
+</script>
+</body>
+</html>
+`))
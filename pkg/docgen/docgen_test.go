@@ -0,0 +1,44 @@
+package docgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nested struct {
+	Port int `default:"8080" required:"true"`
+}
+
+type server struct {
+	Address string        `default:"0.0.0.0" desc:"Listen address" example:"127.0.0.1" docs:"https://example.com/addr"`
+	Timeout time.Duration `default:"30s" group:"Networking" stability:"beta"`
+	Nested  nested
+}
+
+func TestWriteHTML(t *testing.T) {
+	section, err := SectionFor[server]("Server")
+	if err != nil {
+		t.Fatalf("SectionFor: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, "Config <reference>", section); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`id="server-nested-port"`,
+		"<code>30s</code>",
+		"<td>required</td>",
+		"<td><em>beta</em> </td>",
+		`<h3 id="server-networking">Networking</h3>`,
+		`<h3 id="server-nested">Nested</h3>`,
+		`Listen address Example: <code>127.0.0.1</code> <a href="https://example.com/addr">Docs</a>`,
+		"Config &lt;reference&gt;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package helm generates the values.yaml and values.schema.json of a Helm
+// chart from the optionator metadata of the config struct the chart
+// renders, so chart values cannot drift from the Go settings they feed.
+//
+// Keys are named by the NamingStrategy of the Config passed in; charts
+// conventionally use optionator.CamelCase.
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chetan-giradkar/Optionator/pkg/openapi"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// WriteValues writes a values.yaml skeleton for T: every field that is not
+// hidden with its default, or an empty value when it has none, under
+// comments from its desc tag. Required fields without a default are written
+// commented out, so that installing the chart without them fails schema
+// validation. Secret fields are left empty.
+func WriteValues[T any](w io.Writer, config optionator.Config) error {
+	fields, err := optionator.DescribeWithConfig[T](config)
+	if err != nil {
+		return err
+	}
+	root, err := openapi.ForConfig[T](config)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	var open []string
+	for _, f := range fields {
+		if f.Hidden {
+			continue
+		}
+		keys := strings.Split(config.NamingStrategy.Key(f.Path), ".")
+		depth := 0
+		for depth < len(open) && depth < len(keys)-1 && open[depth] == keys[depth] {
+			depth++
+		}
+		open = open[:depth]
+		for ; depth < len(keys)-1; depth++ {
+			fmt.Fprintf(&b, "%s%s:\n", indent(depth), keys[depth])
+			open = append(open, keys[depth])
+		}
+		prefix := indent(depth)
+		if f.Description != "" {
+			for _, line := range strings.Split(f.Description, "\n") {
+				fmt.Fprintf(&b, "%s# %s\n", prefix, line)
+			}
+		}
+		s := lookup(root, keys)
+		key := keys[len(keys)-1]
+		if f.Required && s.Default == nil {
+			fmt.Fprintf(&b, "%s# Required.\n%s# %s:\n", prefix, prefix, key)
+			continue
+		}
+		fmt.Fprintf(&b, "%s%s: %s\n", prefix, key, yamlValue(s))
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSchema writes the values.schema.json matching WriteValues: the
+// openapi schema of T as a JSON Schema draft-04 document, which Helm
+// validates values against on install, upgrade, lint and template.
+func WriteSchema[T any](w io.Writer, config optionator.Config) error {
+	root, err := openapi.ForConfig[T](config)
+	if err != nil {
+		return err
+	}
+	untype(root)
+	data, err := json.MarshalIndent(struct {
+		Dialect string `json:"$schema"`
+		*openapi.Schema
+	}{"http://json-schema.org/draft-04/schema#", root}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// untype lifts the object type of the values whose shape the struct does
+// not fix, which Kubernetes requires but which would reject a string or a
+// list in Helm.
+func untype(s *openapi.Schema) {
+	if s == nil {
+		return
+	}
+	if s.PreserveUnknownFields {
+		s.Type, s.PreserveUnknownFields = "", false
+	}
+	for _, p := range s.Properties {
+		untype(p)
+	}
+	untype(s.Items)
+	untype(s.AdditionalProperties)
+}
+
+func lookup(root *openapi.Schema, keys []string) *openapi.Schema {
+	s := root
+	for _, k := range keys {
+		s = s.Properties[k]
+	}
+	return s
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// yamlValue is the value written for s: its default, or an empty value of
+// its type. Compact JSON is valid YAML flow syntax.
+func yamlValue(s *openapi.Schema) string {
+	value := s.Default
+	if value == nil {
+		switch s.Type {
+		case "string":
+			value = ""
+		case "integer", "number":
+			value = 0
+		case "boolean":
+			value = false
+		case "array":
+			value = []any{}
+		default:
+			value = map[string]any{}
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
@@ -0,0 +1,72 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type tls struct {
+	CertFile string   `required:"true" desc:"Path of the certificate"`
+	Ciphers  []string `default:"a,b"`
+}
+
+type values struct {
+	Replicas int           `default:"3" desc:"Pod count"`
+	Timeout  time.Duration `default:"30s"`
+	Token    string        `default:"x" secret:"true"`
+	Debug    bool          `hidden:"true"`
+	TLS      tls
+	Plugin   any
+}
+
+var config = optionator.Config{DefaultTag: "default", RequiredTag: "required", NamingStrategy: optionator.CamelCase}
+
+func TestWriteValues(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteValues[values](&buf, config); err != nil {
+		t.Fatalf("WriteValues: %v", err)
+	}
+	want := `# Pod count
+replicas: 3
+timeout: "30s"
+token: ""
+tls:
+  # Path of the certificate
+  # Required.
+  # certFile:
+  ciphers: ["a","b"]
+plugin: {}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("values.yaml:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSchema[values](&buf, config); err != nil {
+		t.Fatalf("WriteSchema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`"$schema": "http://json-schema.org/draft-04/schema#"`,
+		`"required": [
+        "certFile"
+      ]`,
+		`"plugin": {}`,
+		`"debug": {`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("schema lacks %s:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/chetan-giradkar/Optionator/pkg/kongcli
+
+go 1.18
+
+require (
+	github.com/alecthomas/kong v1.16.1
+	github.com/chetan-giradkar/Optionator v0.0.0
+)
+
+replace github.com/chetan-giradkar/Optionator => ../..
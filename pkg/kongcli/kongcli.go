@@ -0,0 +1,94 @@
+// Package kongcli derives kong flags from the metadata of a configuration
+// struct. Bind returns Flags holding a flag for every field BindFlags would
+// bind; embed them in a kong parser with Embed, and after parsing apply the
+// flags set on the command line with Option or New, before defaults and
+// required validation, so a command built with kong gets the same options
+// as one built with the standard flag package.
+//
+// The package is a module of its own, so the optionator module stays free
+// of dependencies.
+package kongcli
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Flags are the flags of a configuration type T.
+type Flags[T any] struct {
+	config optionator.Config
+	specs  []optionator.FlagSpec
+	// values points to a struct built for kong with one pointer field per
+	// flag, left nil unless the flag is set.
+	values reflect.Value
+}
+
+var (
+	stringPtr = reflect.TypeOf((*string)(nil))
+	boolPtr   = reflect.TypeOf((*bool)(nil))
+)
+
+// Bind returns a flag for every field of T that optionator.DescribeFlags
+// lists under config, in field order. The flags have no defaults of their
+// own; the default tag shows in the help text, and applies in New.
+func Bind[T any](config optionator.Config) (*Flags[T], error) {
+	specs, err := optionator.DescribeFlags[T](config)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]reflect.StructField, len(specs))
+	for i, spec := range specs {
+		help := spec.Usage
+		if spec.Default != "" {
+			help += fmt.Sprintf(" (default %q)", spec.Default)
+		}
+		// Kong interpolates ${var} in help, so escape dollars.
+		help = strings.ReplaceAll(help, "$", "$$")
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: stringPtr,
+			Tag:  reflect.StructTag(fmt.Sprintf("name:%s help:%s", strconv.Quote(spec.Name), strconv.Quote(help))),
+		}
+		if spec.Bool {
+			// --no-name turns off a flag that defaults to true.
+			fields[i].Type = boolPtr
+			fields[i].Tag += ` negatable:""`
+		}
+	}
+	return &Flags[T]{config: config, specs: specs, values: reflect.New(reflect.StructOf(fields))}, nil
+}
+
+// Embed returns the kong option adding the flags to the root of a parser.
+func (f *Flags[T]) Embed() kong.Option {
+	return kong.Embed(f.values.Interface())
+}
+
+// Option returns an Option applying the flags set explicitly on the command
+// line; the parser must have parsed it.
+func (f *Flags[T]) Option() optionator.Option[T] {
+	set := map[string]string{}
+	for i, spec := range f.specs {
+		field := f.values.Elem().Field(i)
+		if field.IsNil() {
+			continue
+		}
+		if spec.Bool {
+			set[spec.Name] = strconv.FormatBool(field.Elem().Bool())
+		} else {
+			set[spec.Name] = field.Elem().String()
+		}
+	}
+	return optionator.WithFlags[T](f.config, set)
+}
+
+// New constructs target with the config of f, applying opts and then the
+// flags set on the command line.
+func (f *Flags[T]) New(target T, opts ...optionator.Option[T]) (T, error) {
+	opts = append(opts[:len(opts):len(opts)], f.Option())
+	return optionator.NewWithConfig(target, f.config, opts...)
+}
@@ -0,0 +1,75 @@
+package kongcli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type server struct {
+	Host    string        `default:"localhost" desc:"interface to listen on, e.g. \"0.0.0.0\" or ${HOST}"`
+	Port    int           `default:"8080"`
+	Verbose bool          `default:"true"`
+	Timeout time.Duration `default:"5s"`
+	Token   string        `required:"true" from:"env,flag"`
+	Secret  string        `from:"env"`
+}
+
+func parse(t *testing.T, args ...string) (*server, error) {
+	t.Helper()
+	flags, err := Bind[*server](optionator.Config{DefaultTag: "default", RequiredTag: "required"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cli struct{}
+	parser, err := kong.New(&cli, flags.Embed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags.New(&server{})
+}
+
+func TestFlags(t *testing.T) {
+	cfg, err := parse(t, "--port", "9090", "--no-verbose", "--token", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 9090 || cfg.Verbose || cfg.Timeout != 5*time.Second || cfg.Token != "t" {
+		t.Errorf("got %+v", cfg)
+	}
+	if _, err := parse(t, "--port", "9090"); err == nil || !strings.Contains(err.Error(), "Token") {
+		t.Errorf("Expected the required Token to be enforced, got %v", err)
+	}
+	if _, err := parse(t, "--token", "t", "--port", "x"); err == nil || !strings.Contains(err.Error(), "flag port") {
+		t.Errorf("Expected a parse error for port, got %v", err)
+	}
+	if _, err := parse(t, "--token", "t", "--secret", "s"); err == nil || !strings.Contains(err.Error(), "may only be set from env") {
+		t.Errorf("Expected the from tag to refuse the flag, got %v", err)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	flags, err := Bind[*server](optionator.Config{DefaultTag: "default", RequiredTag: "required"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cli struct{}
+	var out bytes.Buffer
+	parser, err := kong.New(&cli, flags.Embed(), kong.Writers(&out, &out), kong.Exit(func(int) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.Parse([]string{"--help"})
+	for _, want := range []string{"--host=HOST", `"0.0.0.0" or ${HOST}`, `(default "8080")`, "--[no-]verbose"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in help:\n%s", want, out.String())
+		}
+	}
+}
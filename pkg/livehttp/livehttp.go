@@ -0,0 +1,133 @@
+// Package livehttp applies a Live configuration to a net/http server.
+//
+// Not every server setting can change under a running server. The
+// net/http.Server timeouts and header limit are read by connections as they
+// are served, so assigning them while serving is a data race; they apply
+// only to a server built after the change. Settings enforced per request by
+// the Handler middleware apply to the next request. Fields records which is
+// which, and Watch reports changes that need a restart.
+//
+// gRPC servers take their limits as options to grpc.NewServer, so all of
+// them belong to the restart class; the same Watch pattern applies.
+package livehttp
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Settings are the server settings this package knows how to apply. An
+// application maps its own configuration to Settings with a function
+// passed to Handler, NewServer and Watch.
+type Settings struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// RequestTimeout bounds each request's context; zero means no bound.
+	RequestTimeout time.Duration
+	// MaxBodyBytes limits request bodies; zero means no limit.
+	MaxBodyBytes int64
+	// MaxInFlight limits concurrent requests, rejecting the excess with
+	// 503 Service Unavailable; zero means no limit.
+	MaxInFlight int64
+}
+
+// Applicability tells when a change to a setting takes effect.
+type Applicability int
+
+const (
+	// NextRequest settings are enforced by Handler on every request.
+	NextRequest Applicability = iota
+	// Restart settings are fixed when the http.Server starts serving.
+	Restart
+)
+
+func (a Applicability) String() string {
+	if a == Restart {
+		return "restart"
+	}
+	return "next request"
+}
+
+// Fields maps each field of Settings to when a change to it applies.
+var Fields = map[string]Applicability{
+	"ReadTimeout":       Restart,
+	"ReadHeaderTimeout": Restart,
+	"WriteTimeout":      Restart,
+	"IdleTimeout":       Restart,
+	"MaxHeaderBytes":    Restart,
+	"RequestTimeout":    NextRequest,
+	"MaxBodyBytes":      NextRequest,
+	"MaxInFlight":       NextRequest,
+}
+
+// Handler wraps next so that every request is subject to the current
+// RequestTimeout, MaxBodyBytes and MaxInFlight of live.
+func Handler[T any](live *optionator.Live[T], settings func(T) Settings, next http.Handler) http.Handler {
+	var inFlight int64
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := settings(live.Load())
+		if n := atomic.AddInt64(&inFlight, 1); s.MaxInFlight > 0 && n > s.MaxInFlight {
+			atomic.AddInt64(&inFlight, -1)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer atomic.AddInt64(&inFlight, -1)
+		if s.MaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+		}
+		if s.RequestTimeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), s.RequestTimeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewServer returns a server for addr with the restart settings of the
+// current value of live and h wrapped by Handler.
+func NewServer[T any](addr string, live *optionator.Live[T], settings func(T) Settings, h http.Handler) *http.Server {
+	s := settings(live.Load())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           Handler(live, settings, h),
+		ReadTimeout:       s.ReadTimeout,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+	}
+}
+
+// Watch calls onRestart, after each reload of live that changes settings
+// a running server cannot pick up, with the names of those settings. It
+// returns a function that stops watching.
+func Watch[T any](live *optionator.Live[T], settings func(T) Settings, onRestart func(fields []string)) (cancel func()) {
+	return live.Subscribe(func(old, new T) {
+		if fields := RestartFields(settings(old), settings(new)); len(fields) > 0 {
+			onRestart(fields)
+		}
+	})
+}
+
+// RestartFields returns the names of the Restart settings that differ
+// between old and new, in declaration order.
+func RestartFields(old, new Settings) []string {
+	var fields []string
+	o, n := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < o.NumField(); i++ {
+		name := o.Type().Field(i).Name
+		if Fields[name] == Restart && o.Field(i).Interface() != n.Field(i).Interface() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,65 @@
+package livehttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type appConfig struct {
+	MaxBody     int64         `default:"8"`
+	ReadTimeout time.Duration `default:"5s"`
+}
+
+func settings(c *appConfig) Settings {
+	return Settings{MaxBodyBytes: c.MaxBody, ReadTimeout: c.ReadTimeout}
+}
+
+func TestHandlerAndWatch(t *testing.T) {
+	values := map[string]any{}
+	config := optionator.Config{DefaultTag: "default", RequiredTag: "required", Sources: []optionator.Source{optionator.MapSource{Values: values}}}
+	live, err := optionator.NewLive(func() *appConfig { return &appConfig{} }, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Handler(live, settings, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		if _, err := r.Body.Read(buf); err != nil && !strings.Contains(err.Error(), "EOF") {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+	post := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("0123456789")))
+		return rec.Code
+	}
+	if code := post(); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want the 8-byte limit enforced", code)
+	}
+
+	var restart []string
+	Watch(live, settings, func(fields []string) { restart = fields })
+	values["MaxBody"] = 64
+	if err := live.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code := post(); code != http.StatusOK {
+		t.Errorf("status = %d after raising the limit", code)
+	}
+	if restart != nil {
+		t.Errorf("restart requested for %v", restart)
+	}
+	values["ReadTimeout"] = "10s"
+	if err := live.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restart, []string{"ReadTimeout"}) {
+		t.Errorf("restart = %v", restart)
+	}
+}
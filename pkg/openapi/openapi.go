@@ -0,0 +1,224 @@
+// Package openapi generates the OpenAPI v3 structural schema of a config
+// struct from its optionator metadata, in the form Kubernetes accepts in a
+// CustomResourceDefinition, so the struct tags stay the single source of
+// truth for defaults, required fields and enums.
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Schema is an OpenAPI v3 schema object, with the Kubernetes extensions
+// structural schemas use.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	// PreserveUnknownFields is set for polymorphic sections and other
+	// values whose shape the struct does not fix.
+	PreserveUnknownFields bool `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+}
+
+// For returns the schema of T, a struct or a pointer to one.
+func For[T any]() (*Schema, error) {
+	return ForConfig[T](optionator.Config{DefaultTag: "default", RequiredTag: "required"})
+}
+
+// ForConfig is like For but reads tags according to config and names
+// properties by its NamingStrategy, such as optionator.CamelCase for the
+// Kubernetes convention.
+//
+// Properties carry the field's type, desc tag as description, default,
+// oneof values as enum and numeric min and max bounds. Nested objects that
+// hold defaults default to {}, so the API server applies the defaults
+// inside them when the object is left out. Secret fields get no default.
+func ForConfig[T any](config optionator.Config) (*Schema, error) {
+	fields, err := optionator.DescribeWithConfig[T](config)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := defaultValues[T](config)
+	if err != nil {
+		return nil, err
+	}
+	root := &Schema{Type: "object"}
+	for _, f := range fields {
+		keys := strings.Split(config.NamingStrategy.Key(f.Path), ".")
+		parent := root
+		for _, k := range keys[:len(keys)-1] {
+			child, ok := parent.Properties[k]
+			if !ok {
+				child = &Schema{Type: "object"}
+				setProperty(parent, k, child)
+			}
+			parent = child
+		}
+		key := keys[len(keys)-1]
+		s := fieldSchema(f)
+		if f.Default != "" && !f.Secret {
+			if v, ok := lookup(defaults, keys); ok && v != nil {
+				s.Default = v
+				markDefaulted(root, keys)
+			}
+		}
+		setProperty(parent, key, s)
+		if f.Required {
+			parent.Required = append(parent.Required, key)
+		}
+	}
+	return root, nil
+}
+
+func setProperty(parent *Schema, key string, s *Schema) {
+	if parent.Properties == nil {
+		parent.Properties = map[string]*Schema{}
+	}
+	parent.Properties[key] = s
+}
+
+// markDefaulted defaults the objects along keys to {}.
+func markDefaulted(root *Schema, keys []string) {
+	s := root
+	for _, k := range keys[:len(keys)-1] {
+		s = s.Properties[k]
+		if s.Default == nil {
+			s.Default = map[string]any{}
+		}
+	}
+}
+
+// defaultValues returns the values of a T holding only its defaults, keyed
+// like the schema properties.
+func defaultValues[T any](config optionator.Config) (map[string]any, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	config.Sources = nil
+	target, _ := optionator.NewLenient[any](reflect.New(t).Interface(), config)
+	return optionator.ToMap(target, optionator.ExportOptions{Naming: config.NamingStrategy})
+}
+
+func lookup(values map[string]any, keys []string) (any, bool) {
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := values[k].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		values = next
+	}
+	v, ok := values[keys[len(keys)-1]]
+	return v, ok
+}
+
+// fieldSchema is the schema of a leaf field with its tag constraints.
+func fieldSchema(f optionator.FieldInfo) *Schema {
+	s := TypeSchema(f.Type)
+	s.Description = f.Description
+	tag := f.Tag()
+	if oneof := tag.Get("oneof"); oneof != "" {
+		for _, v := range strings.Split(oneof, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				s.Enum = append(s.Enum, enumValue(s.Type, v))
+			}
+		}
+	}
+	if s.Type == "integer" || s.Type == "number" {
+		if arg, ok := tag.Lookup("min"); ok {
+			s.ExclusiveMinimum = strings.HasPrefix(arg, "(")
+			s.Minimum = parseBound(strings.TrimLeft(arg, "(["))
+		}
+		if arg, ok := tag.Lookup("max"); ok {
+			s.ExclusiveMaximum = strings.HasSuffix(arg, ")")
+			s.Maximum = parseBound(strings.TrimRight(arg, ")]"))
+		}
+	}
+	return s
+}
+
+func parseBound(text string) *float64 {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func enumValue(typ, v string) any {
+	switch typ {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// TypeSchema returns the schema of values of type t as sources and exports
+// write them: durations, times, functions and text types as strings.
+func TypeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string"}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String, reflect.Func:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: TypeSchema(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: TypeSchema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: TypeSchema(t.Elem())}
+	}
+	return &Schema{Type: "object", PreserveUnknownFields: true}
+}
@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type tls struct {
+	CertFile   string `required:"true" desc:"Path of the certificate"`
+	MinVersion string `default:"1.2" oneof:"1.2,1.3"`
+}
+
+type spec struct {
+	Replicas int           `default:"3" min:"1" max:"10)"`
+	Timeout  time.Duration `default:"30s" desc:"Request timeout"`
+	Mode     string        `oneof:"fast,safe"`
+	Password string        `default:"hunter2" secret:"true"`
+	Hosts    []string
+	Labels   map[string]string
+	Plugin   any
+	TLS      tls
+}
+
+func TestForConfig(t *testing.T) {
+	s, err := ForConfig[spec](optionator.Config{DefaultTag: "default", RequiredTag: "required", NamingStrategy: optionator.CamelCase})
+	if err != nil {
+		t.Fatalf("ForConfig: %v", err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`"replicas":{"type":"integer","format":"int64","default":3,"minimum":1,"maximum":10,"exclusiveMaximum":true}`,
+		`"timeout":{"type":"string","description":"Request timeout","default":"30s"}`,
+		`"mode":{"type":"string","enum":["fast","safe"]}`,
+		`"password":{"type":"string"}`,
+		`"hosts":{"type":"array","items":{"type":"string"}}`,
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}}`,
+		`"plugin":{"type":"object","x-kubernetes-preserve-unknown-fields":true}`,
+		`"tls":{"type":"object","default":{},"properties":{`,
+		`"minVersion":{"type":"string","default":"1.2","enum":["1.2","1.3"]}`,
+		`"required":["certFile"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("schema lacks %s:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package optionator
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxUnixPath is the longest socket path accepted on every supported
+// platform; sun_path is 104 bytes on BSDs and macOS, 108 on Linux.
+const maxUnixPath = 103
+
+// checkAddr validates a string field tagged addr:"<kind>[,resolve]". The
+// kinds are:
+//
+//	hostport    host:port, where the host may be empty and the port 0 for a
+//	            listen address
+//	tcpaddr     host:port with a nonzero port that resolves to a TCP address
+//	unixsocket  a socket path short enough to bind, in an existing directory
+//
+// With resolve, a hostport host must also resolve.
+func checkAddr(field reflect.Value, arg string) error {
+	if field.Kind() != reflect.String {
+		return fmt.Errorf("addr tag on non-string type %v", field.Type())
+	}
+	addr := field.String()
+	opts := strings.Split(arg, ",")
+	resolve := false
+	for _, opt := range opts[1:] {
+		if opt != "resolve" {
+			return fmt.Errorf("unknown addr option %q", opt)
+		}
+		resolve = true
+	}
+	switch opts[0] {
+	case "hostport":
+		if err := checkHostPort(addr, 0); err != nil {
+			return err
+		}
+		if resolve {
+			host, _, _ := net.SplitHostPort(addr)
+			if host != "" {
+				if _, err := net.LookupHost(host); err != nil {
+					return err
+				}
+			}
+		}
+	case "tcpaddr":
+		if err := checkHostPort(addr, 1); err != nil {
+			return err
+		}
+		if _, err := net.ResolveTCPAddr("tcp", addr); err != nil {
+			return err
+		}
+	case "unixsocket":
+		if len(addr) > maxUnixPath {
+			return fmt.Errorf("socket path %q is longer than %d bytes", addr, maxUnixPath)
+		}
+		if info, err := os.Stat(filepath.Dir(addr)); err != nil {
+			return fmt.Errorf("socket directory: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("socket directory %s is not a directory", filepath.Dir(addr))
+		}
+	default:
+		return fmt.Errorf("unknown addr kind %q", opts[0])
+	}
+	return nil
+}
+
+// checkHostPort checks the syntax of host:port and that the port lies
+// between minPort and 65535; port 0 asks the system for any free port.
+func checkHostPort(addr string, minPort int) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(host, " /") {
+		return fmt.Errorf("invalid host %q", host)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	if n < minPort || n > 65535 {
+		return fmt.Errorf("port %d out of range %d-65535", n, minPort)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package optionator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Redacted replaces the value of fields tagged `secret:"true"` wherever
+// configuration values are reported.
+const Redacted = "[REDACTED]"
+
+// Secret tag values that show a hint of the value in place of Redacted,
+// so support can tell which key or account is configured.
+const (
+	// SecretLast4 shows the last four characters, as "****1234". Values
+	// shorter than eight characters are redacted fully.
+	SecretLast4 = "last4"
+	// SecretHash shows the first 12 hex digits of the HMAC-SHA256 of the
+	// value under the key set with SetSecretHashKey, as
+	// "hmac:28e1559abcf2". Without a key it shows a plain SHA-256 prefix,
+	// as "sha256:9f86d081884c", which anyone can match against guesses:
+	// short or guessable values such as PINs and account numbers are then
+	// recovered by brute force.
+	SecretHash = "hash"
+)
+
+var secretHashKey atomic.Value // []byte
+
+// SetSecretHashKey sets the key of the HMAC that SecretHash shows. Hashes
+// match across processes sharing the key, so support can compare them, while
+// a reader without the key cannot test guesses against them. A nil key
+// restores the plain SHA-256.
+func SetSecretHashKey(key []byte) {
+	secretHashKey.Store(append([]byte(nil), key...))
+}
+
+// isSecret reports whether a secret tag value marks the field secret.
+func isSecret(tag string) bool {
+	return tag == "true" || tag == SecretLast4 || tag == SecretHash
+}
+
+// secretMask returns the mask a secret tag value asks for, if any.
+func secretMask(tag string) string {
+	if tag == SecretLast4 || tag == SecretHash {
+		return tag
+	}
+	return ""
+}
+
+// maskSecret renders the value of a secret field for reports according to
+// mask: Redacted, or the hint SecretLast4 or SecretHash give.
+func maskSecret(mask string, value any) string {
+	if mask == "" || value == nil {
+		return Redacted
+	}
+	text := fmt.Sprint(value)
+	switch mask {
+	case SecretLast4:
+		if r := []rune(text); len(r) >= 8 {
+			return "****" + string(r[len(r)-4:])
+		}
+	case SecretHash:
+		if key, _ := secretHashKey.Load().([]byte); len(key) > 0 {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(text))
+			return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:12]
+		}
+		sum := sha256.Sum256([]byte(text))
+		return "sha256:" + hex.EncodeToString(sum[:])[:12]
+	}
+	return Redacted
+}
+
+// AuditEntry records the change of one field by a Live reload.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor,omitempty"`
+	Path     string    `json:"path"`
+	Old      string    `json:"old"`
+	New      string    `json:"new"`
+	Revision uint64    `json:"revision"`
+}
+
+// AuditSink stores audit entries. Implementations must only ever append.
+type AuditSink interface {
+	Record(entries []AuditEntry) error
+}
+
+// JSONAuditSink writes each entry as a line of JSON to W.
+type JSONAuditSink struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (s *JSONAuditSink) Record(entries []AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(s.W)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor returns a context attributing changes made with it to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, if any.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// fieldChange is a leaf field whose value differs between two configs.
+type fieldChange struct {
+	info     FieldInfo
+	old, new reflect.Value // invalid when behind a nil pointer
+}
+
+// diffFields compares the leaf fields of two values of the same struct type.
+func diffFields(old, new reflect.Value, config Config) []fieldChange {
+	var changes []fieldChange
+	for _, fi := range describeType(old.Type(), config) {
+		o, oOK := lookupIndexes(old, fi.indexes)
+		n, nOK := lookupIndexes(new, fi.indexes)
+		if oOK == nOK && (!oOK || reflect.DeepEqual(o.Interface(), n.Interface())) {
+			continue
+		}
+		c := fieldChange{info: fi}
+		if oOK {
+			c.old = o
+		}
+		if nOK {
+			c.new = n
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// displayValue renders a field value for reports, redacting secrets.
+func displayValue(fi FieldInfo, v reflect.Value) string {
+	if fi.Secret {
+		return maskSecret(fi.Mask, plainValue(v))
+	}
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "<nil>"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// auditEntries describes the changes from old to new as audit entries.
+func auditEntries(ctx context.Context, old, new reflect.Value, config Config, revision uint64) []AuditEntry {
+	now := config.now()
+	actor := ActorFromContext(ctx)
+	var entries []AuditEntry
+	for _, c := range diffFields(old, new, config) {
+		entries = append(entries, AuditEntry{
+			Time:     now,
+			Actor:    actor,
+			Path:     c.info.Path,
+			Old:      displayValue(c.info, c.old),
+			New:      displayValue(c.info, c.new),
+			Revision: revision,
+		})
+	}
+	return entries
+}
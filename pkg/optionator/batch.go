@@ -0,0 +1,46 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+)
+
+// NewBatch constructs every prototype, applying the shared options and then
+// per[i] to prototypes[i]. Sources are loaded once for the whole batch and
+// each instance binds its own copy of their values. per may be nil or must
+// have one entry per prototype. The first failure stops the batch.
+func NewBatch[T any](prototypes []T, shared []Option[T], per [][]Option[T]) ([]T, error) {
+	return NewBatchWithConfig(prototypes, defaultConfig, shared, per)
+}
+
+// NewBatchWithConfig is like NewBatch but uses the provided config.
+func NewBatchWithConfig[T any](prototypes []T, config Config, shared []Option[T], per [][]Option[T]) ([]T, error) {
+	if per != nil && len(per) != len(prototypes) {
+		return nil, fmt.Errorf("got %d option lists for %d prototypes", len(per), len(prototypes))
+	}
+	ctx := withTargetOf[T](context.Background())
+	loaded, err := fetchSources(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	load := func(context.Context) ([]loadedSource, error) {
+		cp := make([]loadedSource, len(loaded))
+		for i, ls := range loaded {
+			cp[i] = loadedSource{ls.src, deepCopy(ls.values)}
+		}
+		return cp, nil
+	}
+	out := make([]T, len(prototypes))
+	for i, proto := range prototypes {
+		opts := shared
+		if per != nil {
+			opts = append(append([]Option[T]{}, shared...), per[i]...)
+		}
+		v, err := newWithLoader(ctx, proto, config, opts, load)
+		if err != nil {
+			return nil, fmt.Errorf("batch item %d: %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+//go:build !race
+
+package optionator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type benchListener struct {
+	Host string `default:"localhost"`
+	Port int    `default:"8080" min:"1"`
+}
+
+type benchServer struct {
+	Name     string        `default:"svc"`
+	Timeout  time.Duration `default:"5s"`
+	Ratio    float64       `default:"0.5"`
+	Enabled  bool          `default:"true"`
+	Tags     []string
+	Listener benchListener
+	Admin    *benchListener
+}
+
+// TestDefaultsDoNotAllocate guards the defaults path on cached types: once
+// the metadata of a type is cached, applying its defaults must not allocate.
+func TestDefaultsDoNotAllocate(t *testing.T) {
+	ctx := context.Background()
+	s := benchServer{Admin: &benchListener{}}
+	v := reflect.ValueOf(&s).Elem()
+	if err := setDefaultRecursively(ctx, v, defaultConfig); err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		admin := s.Admin
+		*admin = benchListener{}
+		s = benchServer{Admin: admin}
+		if err := setDefaultRecursively(ctx, v, defaultConfig); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("applying defaults allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkDefaults(b *testing.B) {
+	ctx := context.Background()
+	s := benchServer{Admin: &benchListener{}}
+	v := reflect.ValueOf(&s).Elem()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		admin := s.Admin
+		*admin = benchListener{}
+		s = benchServer{Admin: admin}
+		if err := setDefaultRecursively(ctx, v, defaultConfig); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New(&benchServer{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewWithOption(b *testing.B) {
+	opt := With[*benchServer]("Name", "api")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := New(&benchServer{}, opt); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
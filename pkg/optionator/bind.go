@@ -0,0 +1,354 @@
+package optionator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// binder assigns the values loaded from one source onto a struct.
+type binder struct {
+	config Config
+	// weak enables the lenient conversions of weakConvert.
+	weak bool
+	// ctx carries the report that use of aliased keys is recorded on.
+	ctx context.Context
+	// source is the kind of the source being bound, checked against the
+	// from tags of the fields it sets.
+	source SourceKind
+}
+
+// bindMap assigns values onto the struct v, recursing into nested structs.
+// Keys that match no field are reported as warnings, with the nearest field
+// name as a suggestion, unless the strictness in effect says otherwise, and
+// kept for Report.Extra; keys starting with $ are directives and always
+// ignored.
+func (b binder) bindMap(v reflect.Value, values map[string]any, prefix string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	index := getFieldIndex(v.Type(), b.config)
+	metadata := index.fields
+	for key, value := range values {
+		fm, alias, ok := index.match(key)
+		if !ok {
+			fm, ok = b.matchNamed(metadata, key)
+		}
+		if !ok && !strings.HasPrefix(key, "$") {
+			recordExtra(b.ctx, prefix+key, value)
+			err := fmt.Errorf("unknown key %s%s", prefix, key)
+			if near := nearestField(metadata, key, b.config.NamingStrategy); near != "" {
+				err = fmt.Errorf("%w; did you mean %s?", err, near)
+			}
+			if err := tolerate(b.ctx, b.config, StrictnessWarn, "", err); err != nil {
+				return err
+			}
+		}
+		if !ok || value == nil {
+			continue
+		}
+		path := prefix + fm.Name
+		if !allowsSource(fm, b.source) {
+			err := fmt.Errorf("field %s may only be set from %s", path, strings.Join(fm.From, ", "))
+			if err := salvage(b.ctx, path, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if alias {
+			if hasNameKey(index, values, fm) {
+				continue
+			}
+			if b.ctx != nil {
+				warn(b.ctx, path, "old key %s%s used; rename it to %s", prefix, key, fm.Name)
+			}
+		}
+		field := v.FieldByIndex(fm.Index)
+		if nested, ok := value.(map[string]any); ok && isKindField(fm) {
+			if err := b.bindKind(v, metadata, fm, values, nested, path); err != nil {
+				if err := salvage(b.ctx, path, err); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if nested, ok := value.(map[string]any); ok && isNestedStruct(fm.Type) {
+			if err := b.bindMap(field, nested, path+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.assign(field, value, path); err != nil {
+			if err := salvage(b.ctx, path, fmt.Errorf("field %s: %w", path, err)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchNamed finds the field whose key under the naming strategy is key,
+// ignoring case. Keys from environment sources are compared in their
+// variable name form, so MAX_CONNS matches max-conns under KebabCase.
+func (b binder) matchNamed(metadata []fieldMetadata, key string) (fieldMetadata, bool) {
+	naming := b.config.NamingStrategy
+	if naming == nil && b.source != FromEnv {
+		return fieldMetadata{}, false
+	}
+	for _, fm := range metadata {
+		name := fm.Name
+		if naming != nil {
+			name = naming(name)
+		}
+		if strings.EqualFold(key, name) || b.source == FromEnv && key == envWord(name) {
+			return fm, true
+		}
+	}
+	return fieldMetadata{}, false
+}
+
+// allowsSource reports whether a source of the given kind may set fm.
+// Fields without a from tag accept every source.
+func allowsSource(fm fieldMetadata, kind SourceKind) bool {
+	return allowsKind(fm.From, kind)
+}
+
+// allowsKind reports whether the from list, empty for no restriction,
+// admits kind.
+func allowsKind(from []string, kind SourceKind) bool {
+	if len(from) == 0 {
+		return true
+	}
+	for _, f := range from {
+		if SourceKind(f) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// nearestField returns the key of the field closest to key by edit
+// distance, ignoring case, or "" if none is close enough to be a likely
+// misspelling.
+func nearestField(metadata []fieldMetadata, key string, naming NamingStrategy) string {
+	best, bestDist := "", len(key)/3+1
+	for _, fm := range metadata {
+		name := fm.Name
+		if naming != nil {
+			name = naming(name)
+		}
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d <= bestDist && (best == "" || d < bestDist) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// hasNameKey reports whether values holds fm under its own name, which
+// wins over its aliases.
+func hasNameKey(index *fieldIndex, values map[string]any, fm fieldMetadata) bool {
+	for key, value := range values {
+		if other, alias, ok := index.match(key); ok && !alias && value != nil && other.Name == fm.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// assign sets field from a decoded source value. Strings are parsed like
+// default tags, numbers convert between numeric kinds as long as no
+// information is lost, or the strictness in effect tolerates it, and lists
+// and objects fill slices and maps. path names the field in warnings.
+func (b binder) assign(field reflect.Value, value any, path string) error {
+	ft := field.Type()
+	if len(b.config.DecodeHooks) > 0 {
+		converted, err := runDecodeHooks(b.config.DecodeHooks, ft, value)
+		if err != nil {
+			return err
+		}
+		value = converted
+	}
+	if b.weak {
+		converted, err := weakConvert(value, ft)
+		if err != nil {
+			return err
+		}
+		value = converted
+	}
+	val := reflect.ValueOf(value)
+	if !val.IsValid() {
+		// A null inside a list or object leaves the element at its zero.
+		field.Set(reflect.Zero(ft))
+		return nil
+	}
+	if n, ok := value.(json.Number); ok {
+		if ft.Kind() == reflect.String {
+			return fmt.Errorf("cannot use number %s as %v", n, ft)
+		}
+		text := n.String()
+		if b.config.HumanNumbers {
+			text = humanNumber(text, ft)
+		}
+		err := parseAndSetDefault(field, text, ft)
+		if f, ferr := n.Float64(); err != nil && ferr == nil && isNumber(ft.Kind()) && ft != durationType {
+			// Only a lossy conversion is tolerable; a number that fits
+			// but is not written as an integer, such as 1e3, stays an error.
+			converted, lossErr := convertNumber(reflect.ValueOf(f), ft)
+			if lossErr != nil && tolerate(b.ctx, b.config, StrictnessStrict, path, lossErr) == nil {
+				field.Set(converted)
+				return nil
+			}
+		}
+		return err
+	}
+	if s, ok := value.(string); ok && ft.Kind() != reflect.String && isParsable(ft) {
+		if b.config.HumanNumbers {
+			s = humanNumber(s, ft)
+		}
+		return b.tolerateRange(field, parseAndSetDefault(field, s, ft), path)
+	}
+	if val.Type().AssignableTo(ft) {
+		field.Set(val)
+		return nil
+	}
+	switch {
+	case isNumber(val.Kind()) && isNumber(ft.Kind()):
+		converted, err := convertNumber(val, ft)
+		if err != nil {
+			if err := tolerate(b.ctx, b.config, StrictnessStrict, path, err); err != nil {
+				return err
+			}
+		}
+		field.Set(converted)
+		return nil
+	case val.Kind() == reflect.String && ft.Kind() == reflect.String,
+		val.Kind() == reflect.Bool && ft.Kind() == reflect.Bool:
+		field.Set(val.Convert(ft))
+		return nil
+	case val.Kind() == reflect.Slice && ft.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(ft, val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			if err := b.assign(s.Index(i), val.Index(i).Interface(), path); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		field.Set(s)
+		return nil
+	case val.Kind() == reflect.Slice && ft.Kind() == reflect.Array:
+		if val.Len() != ft.Len() {
+			return fmt.Errorf("%v needs %d elements, got %d", ft, ft.Len(), val.Len())
+		}
+		a := reflect.New(ft).Elem()
+		for i := 0; i < val.Len(); i++ {
+			if err := b.assign(a.Index(i), val.Index(i).Interface(), path); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		field.Set(a)
+		return nil
+	case val.Kind() == reflect.Map && ft.Kind() == reflect.Map && ft.Key().Kind() == reflect.String:
+		m := reflect.MakeMapWithSize(ft, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			elem := reflect.New(ft.Elem()).Elem()
+			if err := b.assign(elem, iter.Value().Interface(), path); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			m.SetMapIndex(iter.Key().Convert(ft.Key()), elem)
+		}
+		field.Set(m)
+		return nil
+	case val.Kind() == reflect.Map && isNestedStruct(ft):
+		if nested, ok := value.(map[string]any); ok {
+			return b.bindMap(field, nested, "")
+		}
+	}
+	return fmt.Errorf("cannot use %v (%T) as %v", value, value, ft)
+}
+
+// tolerateRange sets field to the truncated value of a number too large
+// for it if the strictness in effect tolerates the loss, and otherwise
+// returns err.
+func (b binder) tolerateRange(field reflect.Value, err error, path string) error {
+	var re *rangeError
+	if !errors.As(err, &re) || re.t != field.Type() {
+		return err
+	}
+	if err := tolerate(b.ctx, b.config, StrictnessStrict, path, err); err != nil {
+		return err
+	}
+	field.Set(re.value.Convert(re.t))
+	return nil
+}
+
+// convertNumber converts a numeric value to another numeric type, failing if
+// the value does not survive the round trip. Narrowing to float32 is allowed.
+func convertNumber(val reflect.Value, t reflect.Type) (reflect.Value, error) {
+	converted := val.Convert(t)
+	lossy := converted.Convert(val.Type()).Interface() != val.Interface()
+	if t.Kind() == reflect.Float32 && (val.Kind() == reflect.Float64) {
+		lossy = math.IsInf(converted.Float(), 0) && !math.IsInf(val.Float(), 0)
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			lossy = lossy || val.Int() < 0
+		case reflect.Float32, reflect.Float64:
+			lossy = lossy || val.Float() < 0
+		}
+	}
+	if lossy {
+		return converted, fmt.Errorf("cannot use %v as %v without losing precision", val.Interface(), t)
+	}
+	return converted, nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package optionator
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// checkMin validates a numeric or time.Duration field against min:"<bound>".
+// The bound is inclusive unless written with a leading parenthesis, as in
+// min:"(0s".
+func checkMin(field reflect.Value, arg string) error {
+	exclusive := strings.HasPrefix(arg, "(")
+	bound := strings.TrimPrefix(strings.TrimPrefix(arg, "("), "[")
+	c, err := compareBound(field, bound)
+	if err != nil {
+		return err
+	}
+	if c < 0 || (exclusive && c == 0) {
+		return fmt.Errorf("%v is below the minimum %s", field.Interface(), arg)
+	}
+	return nil
+}
+
+// checkMax is the counterpart of checkMin; an exclusive bound is written with
+// a trailing parenthesis, as in max:"10m)".
+func checkMax(field reflect.Value, arg string) error {
+	exclusive := strings.HasSuffix(arg, ")")
+	bound := strings.TrimSuffix(strings.TrimSuffix(arg, ")"), "]")
+	c, err := compareBound(field, bound)
+	if err != nil {
+		return err
+	}
+	if c > 0 || (exclusive && c == 0) {
+		return fmt.Errorf("%v is above the maximum %s", field.Interface(), arg)
+	}
+	return nil
+}
+
+// compareBound compares field with bound, parsed as the field's type, and
+// returns -1, 0 or +1.
+func compareBound(field reflect.Value, bound string) (int, error) {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		return compareInts(field.Int(), int64(d)), nil
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		return compareInts(field.Int(), n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		switch v := field.Uint(); {
+		case v < n:
+			return -1, nil
+		case v > n:
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil && reflect.PtrTo(field.Type()).Implements(textUnmarshalerType) {
+			// Bounds of types such as Rate are written in their own syntax.
+			b := reflect.New(field.Type())
+			if err = b.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(bound)); err == nil {
+				f = b.Elem().Float()
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid bound %q: %w", bound, err)
+		}
+		switch v := field.Float(); {
+		case v < f:
+			return -1, nil
+		case v > f:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("min/max tag on non-numeric type %v", field.Type())
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// checkBefore validates a time.Time field tagged before:"<time>", which
+// requires the value to be strictly earlier. The bound is RFC 3339, a date
+// such as 2030-01-01, or now, optionally offset as in now+24h, read from
+// Config.Clock.
+func checkBefore(field reflect.Value, arg string) error {
+	t, bound, err := timeBound(field, arg)
+	if err != nil {
+		return err
+	}
+	if !t.Before(bound) {
+		return fmt.Errorf("%s is not before %s", t.Format(time.RFC3339), arg)
+	}
+	return nil
+}
+
+// checkAfter is the counterpart of checkBefore.
+func checkAfter(field reflect.Value, arg string) error {
+	t, bound, err := timeBound(field, arg)
+	if err != nil {
+		return err
+	}
+	if !t.After(bound) {
+		return fmt.Errorf("%s is not after %s", t.Format(time.RFC3339), arg)
+	}
+	return nil
+}
+
+func timeBound(field reflect.Value, arg string) (value, bound time.Time, err error) {
+	if field.Type() != timeType {
+		return value, bound, fmt.Errorf("before/after tag on non-time type %v", field.Type())
+	}
+	bound, err = time.Parse(time.RFC3339, arg)
+	if err != nil {
+		bound, err = time.Parse("2006-01-02", arg)
+	}
+	if err != nil {
+		return value, bound, fmt.Errorf("invalid time bound %q", arg)
+	}
+	return field.Interface().(time.Time), bound, nil
+}
@@ -0,0 +1,165 @@
+package optionator
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// modulePath is the module optionator is built from, looked up in the build
+// information to report its version.
+const modulePath = "github.com/chetan-giradkar/Optionator"
+
+// DebugBundle constructs target as NewWithReport does and writes a zip
+// archive for attaching to bug reports to w. The archive holds:
+//
+//	version.txt       the optionator and Go versions
+//	config.json       the effective configuration
+//	sources/*.json    the values each source supplied, in load order
+//	report.txt        the findings and timings of the construction
+//	fields.json       the metadata and tags of every field not hidden
+//
+// Secret fields are redacted everywhere, including the source payloads. A
+// failed construction is recorded in report.txt rather than returned; the
+// error returned is about writing the archive.
+func DebugBundle[T any](w io.Writer, target T, config Config, opts ...Option[T]) error {
+	var report Report
+	var loaded []loadedSource
+	ctx := withReport(context.Background(), &report)
+	target, err := newWithLoader(ctx, target, config, opts, func(ctx context.Context) ([]loadedSource, error) {
+		var err error
+		loaded, err = fetchSources(ctx, config)
+		return loaded, err
+	})
+	finishReport(&report, target, config, err)
+
+	v, err := structValue(target)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	add := func(name string, write func(io.Writer) error) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		return write(f)
+	}
+	if err := add("version.txt", func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "optionator %s\ngo %s\n", moduleVersion(), runtime.Version())
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := add("config.json", func(w io.Writer) error {
+		return WriteJSON(w, target, ExportOptions{})
+	}); err != nil {
+		return err
+	}
+	for i, ls := range loaded {
+		values := redactValues(v.Type(), ls.values, config)
+		name := fmt.Sprintf("sources/%02d-%s.json", i, strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(ls.src.Name()))
+		if err := add(name, func(w io.Writer) error { return writeIndentedJSON(w, values) }); err != nil {
+			return err
+		}
+	}
+	if err := add("report.txt", func(w io.Writer) error {
+		for _, f := range report.Findings {
+			if _, err := fmt.Fprintln(w, f); err != nil {
+				return err
+			}
+		}
+		for _, t := range report.Timings {
+			if _, err := fmt.Fprintf(w, "timing: %s %s: %v\n", t.Phase, t.Name, t.Duration); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := add("fields.json", func(w io.Writer) error {
+		type field struct {
+			Path     string
+			Type     string
+			Default  string `json:",omitempty"`
+			Required bool   `json:",omitempty"`
+			Secret   bool   `json:",omitempty"`
+			Group    string `json:",omitempty"`
+			Tag      string `json:",omitempty"`
+		}
+		var fields []field
+		for _, fi := range describeType(v.Type(), config) {
+			if fi.Hidden {
+				continue
+			}
+			fields = append(fields, field{fi.Path, fi.Type.String(), fi.Default, fi.Required, fi.Secret, fi.Group, string(fi.tag)})
+		}
+		return writeIndentedJSON(w, fields)
+	}); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// moduleVersion returns the version of optionator in the running binary.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// redactValues returns a copy of source values for struct type t with the
+// values of secret fields masked. Sections of polymorphic
+// fields are redacted according to the kind they name.
+func redactValues(t reflect.Type, values map[string]any, config Config) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	index := getFieldIndex(t, config)
+	out := make(map[string]any, len(values))
+	for key, value := range values {
+		fm, _, ok := index.match(key)
+		nested, isMap := value.(map[string]any)
+		switch {
+		case !ok:
+			out[key] = value
+		case fm.Secret:
+			out[key] = maskSecret(fm.Mask, value)
+		case isMap && isNestedStruct(fm.Type):
+			out[key] = redactValues(fm.Type, nested, config)
+		case isMap && isKindField(fm):
+			name, _ := nested[KindKey].(string)
+			if kind, err := newKind(fm.Type, name); err == nil {
+				out[key] = redactValues(kind.Type(), nested, config)
+			} else {
+				out[key] = Redacted
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
@@ -0,0 +1,1097 @@
+package optionator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// celExprs caches parsed cel tags.
+var celExprs sync.Map // map[string]celExpr
+
+type celExpr struct {
+	root  celNode
+	depth int
+}
+
+// checkCEL validates a field tagged with a cel expression, which must
+// evaluate to true. The expression sees the field as self and the struct
+// holding it as this, as in `cel:"self > 0 && self < this.MaxConns"`.
+//
+// The tag takes a subset of CEL without third-party dependencies:
+//
+//	Expr     = Or [ "?" Or ":" Expr ]
+//	Or       = And { "||" And }
+//	And      = Relation { "&&" Relation }
+//	Relation = Sum { ( "<" | "<=" | ">" | ">=" | "==" | "!=" | "in" ) Sum }
+//	Sum      = Product { ( "+" | "-" ) Product }
+//	Product  = Unary { ( "*" | "/" | "%" ) Unary }
+//	Unary    = { "!" | "-" } Member
+//	Member   = Primary { "." Ident [ "(" [ Args ] ")" ] | "[" Expr "]" }
+//	Primary  = Ident [ "(" [ Args ] ")" ] | "(" Expr ")" | "[" [ Args ] "]" | Literal
+//	Args     = Expr { "," Expr }
+//	Literal  = Int | Double | String | "true" | "false" | "null"
+//
+// Ints are decimal or 0x hexadecimal, doubles have a fraction or exponent,
+// and strings are single- or double-quoted with Go escapes. Values are
+// bool, int, double, string, duration, lists, maps and structs; unsigned
+// fields are ints, and one beyond the int range is an error, as is integer
+// overflow. Only exported fields can be selected, and string-keyed maps
+// can be indexed with "." too. The functions are size, int, double, string
+// and duration, called as size(x) or x.size(), and the string methods
+// startsWith, endsWith and contains. Durations compare with
+// duration("5s"). Config can disable cel tags or limit their nesting.
+func checkCEL(field, this reflect.Value, expr string, config Config) error {
+	if err := config.dynamic("cel tag"); err != nil {
+		return err
+	}
+	e, err := parseCEL(expr)
+	if err != nil {
+		return err
+	}
+	if max := config.evalLimits().MaxDepth; e.depth > max {
+		return fmt.Errorf("cel %q nests deeper than the limit of %d", expr, max)
+	}
+	result, err := celEval{self: field, this: this}.eval(e.root)
+	if err != nil {
+		return fmt.Errorf("cel %q: %w", expr, err)
+	}
+	ok, isBool := result.(bool)
+	if !isBool {
+		return fmt.Errorf("cel %q evaluates to %s, not bool", expr, celTypeName(result))
+	}
+	if !ok {
+		self, _ := celValue(field)
+		if v, isValue := self.(reflect.Value); isValue && v.CanInterface() {
+			self = v.Interface()
+		}
+		return fmt.Errorf("%v does not satisfy %s", self, expr)
+	}
+	return nil
+}
+
+func parseCEL(expr string) (celExpr, error) {
+	if e, ok := celExprs.Load(expr); ok {
+		return e.(celExpr), nil
+	}
+	toks, err := lexCEL(expr)
+	if err != nil {
+		return celExpr{}, fmt.Errorf("invalid cel expression %q: %w", expr, err)
+	}
+	p := &celParser{toks: toks}
+	root, err := p.expr()
+	if err == nil && p.peek().kind != celEOF {
+		err = fmt.Errorf("unexpected %s at offset %d", p.peek(), p.peek().pos)
+	}
+	if err != nil {
+		return celExpr{}, fmt.Errorf("invalid cel expression %q: %w", expr, err)
+	}
+	parsed := celExpr{root, celDepth(root)}
+	celExprs.Store(expr, parsed)
+	return parsed, nil
+}
+
+// Tokens of cel expressions.
+const (
+	celEOF = iota
+	celIdentTok
+	celLitTok
+	celOpTok
+)
+
+type celToken struct {
+	kind  int
+	text  string
+	value any // of literals
+	pos   int
+}
+
+func (t celToken) String() string {
+	if t.kind == celEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// lexCEL splits expr into tokens.
+func lexCEL(expr string) ([]celToken, error) {
+	var toks []celToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(expr) && (expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j++
+			}
+			word := expr[i:j]
+			switch word {
+			case "true", "false":
+				toks = append(toks, celToken{celLitTok, word, word == "true", i})
+			case "null":
+				toks = append(toks, celToken{celLitTok, word, nil, i})
+			case "in":
+				toks = append(toks, celToken{celOpTok, word, nil, i})
+			default:
+				toks = append(toks, celToken{celIdentTok, word, nil, i})
+			}
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			tok, n, err := lexNumber(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("offset %d: %w", i, err)
+			}
+			tok.pos = i
+			toks = append(toks, tok)
+			i += n
+		case c == '"' || c == '\'':
+			s, n, err := lexString(expr[i:])
+			if err != nil {
+				return nil, fmt.Errorf("offset %d: %w", i, err)
+			}
+			toks = append(toks, celToken{celLitTok, expr[i : i+n], s, i})
+			i += n
+		default:
+			op := ""
+			if i+1 < len(expr) {
+				switch two := expr[i : i+2]; two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					op = two
+				}
+			}
+			if op == "" && strings.IndexByte("<>+-*/%!?:.,()[]", c) >= 0 {
+				op = expr[i : i+1]
+			}
+			if op == "" {
+				return nil, fmt.Errorf("offset %d: unexpected character %q", i, c)
+			}
+			toks = append(toks, celToken{celOpTok, op, nil, i})
+			i += len(op)
+		}
+	}
+	return append(toks, celToken{kind: celEOF, pos: len(expr)}), nil
+}
+
+// lexNumber reads the int or double literal at the start of s.
+func lexNumber(s string) (celToken, int, error) {
+	n := 0
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n = 2
+		for n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) >= 0 {
+			n++
+		}
+		v, err := strconv.ParseInt(s[:n], 0, 64)
+		if err != nil {
+			return celToken{}, 0, fmt.Errorf("int literal %s out of range", s[:n])
+		}
+		return celToken{kind: celLitTok, text: s[:n], value: v}, n, nil
+	}
+	digits := func() {
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+	}
+	digits()
+	double := false
+	if n < len(s) && s[n] == '.' {
+		double = true
+		n++
+		digits()
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		double = true
+		n++
+		if n < len(s) && (s[n] == '+' || s[n] == '-') {
+			n++
+		}
+		digits()
+	}
+	if double {
+		v, err := strconv.ParseFloat(s[:n], 64)
+		if err != nil {
+			return celToken{}, 0, fmt.Errorf("invalid double literal %s", s[:n])
+		}
+		return celToken{kind: celLitTok, text: s[:n], value: v}, n, nil
+	}
+	v, err := strconv.ParseInt(s[:n], 10, 64)
+	if err != nil {
+		return celToken{}, 0, fmt.Errorf("int literal %s out of range", s[:n])
+	}
+	return celToken{kind: celLitTok, text: s[:n], value: v}, n, nil
+}
+
+// lexString reads the quoted string at the start of s, returning its value
+// and length.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	rest := s[1:]
+	for {
+		if rest == "" || rest[0] == '\n' {
+			return "", 0, errors.New("unterminated string literal")
+		}
+		if rest[0] == quote {
+			return b.String(), len(s) - len(rest) + 1, nil
+		}
+		r, _, tail, err := strconv.UnquoteChar(rest, quote)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid string literal: %w", err)
+		}
+		b.WriteRune(r)
+		rest = tail
+	}
+}
+
+// Nodes of parsed cel expressions.
+type (
+	celNode   interface{}
+	celLit    struct{ value any }
+	celIdent  struct{ name string }
+	celSelect struct {
+		x     celNode
+		field string
+	}
+	celIndex struct{ x, index celNode }
+	// celCall is a call of fn, on target unless it is nil.
+	celCall struct {
+		target celNode
+		fn     string
+		args   []celNode
+	}
+	celUnary struct {
+		op string
+		x  celNode
+	}
+	celBinary struct {
+		op   string
+		x, y celNode
+	}
+	celCond struct{ cond, then, els celNode }
+	celList struct{ elems []celNode }
+)
+
+// maxCELNesting bounds the recursion of the parser, whatever the limits of
+// the Config checking the expression later.
+const maxCELNesting = 256
+
+type celParser struct {
+	toks    []celToken
+	pos     int
+	nesting int
+}
+
+func (p *celParser) peek() celToken { return p.toks[p.pos] }
+
+func (p *celParser) next() celToken {
+	t := p.toks[p.pos]
+	if t.kind != celEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is operator op.
+func (p *celParser) accept(op string) bool {
+	if t := p.peek(); t.kind == celOpTok && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *celParser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("expected %q, found %s at offset %d", op, p.peek(), p.peek().pos)
+	}
+	return nil
+}
+
+func (p *celParser) expr() (celNode, error) {
+	if p.nesting++; p.nesting > maxCELNesting {
+		return nil, fmt.Errorf("nested more than %d levels deep", maxCELNesting)
+	}
+	defer func() { p.nesting-- }()
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return celCond{cond, then, els}, nil
+}
+
+// celLevels are the binary operators by increasing precedence.
+var celLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"<", "<=", ">", ">=", "==", "!=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// binary parses a left-associative chain of the operators of level and
+// above.
+func (p *celParser) binary(level int) (celNode, error) {
+	if level == len(celLevels) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != celOpTok || !containsString(celLevels[level], t.text) {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = celBinary{t.text, x, y}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *celParser) unary() (celNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			if p.nesting++; p.nesting > maxCELNesting {
+				return nil, fmt.Errorf("nested more than %d levels deep", maxCELNesting)
+			}
+			defer func() { p.nesting-- }()
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return celUnary{op, x}, nil
+		}
+	}
+	return p.member()
+}
+
+func (p *celParser) member() (celNode, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != celIdentTok {
+				return nil, fmt.Errorf("expected field name, found %s at offset %d", name, name.pos)
+			}
+			if p.accept("(") {
+				args, err := p.args(")")
+				if err != nil {
+					return nil, err
+				}
+				x = celCall{x, name.text, args}
+			} else {
+				x = celSelect{x, name.text}
+			}
+		case p.accept("["):
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = celIndex{x, index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *celParser) primary() (celNode, error) {
+	t := p.next()
+	switch {
+	case t.kind == celLitTok:
+		return celLit{t.value}, nil
+	case t.kind == celIdentTok:
+		if p.accept("(") {
+			args, err := p.args(")")
+			if err != nil {
+				return nil, err
+			}
+			return celCall{nil, t.text, args}, nil
+		}
+		return celIdent{t.text}, nil
+	case t.kind == celOpTok && t.text == "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case t.kind == celOpTok && t.text == "[":
+		elems, err := p.args("]")
+		if err != nil {
+			return nil, err
+		}
+		return celList{elems}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+// args parses a comma-separated list of expressions up to and including
+// the closing operator.
+func (p *celParser) args(closing string) ([]celNode, error) {
+	var args []celNode
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// celDepth is the nesting depth of the expression rooted at n.
+func celDepth(n celNode) int {
+	max := func(nodes ...celNode) int {
+		m := 0
+		for _, n := range nodes {
+			if d := celDepth(n); d > m {
+				m = d
+			}
+		}
+		return m
+	}
+	switch n := n.(type) {
+	case celSelect:
+		return 1 + max(n.x)
+	case celIndex:
+		return 1 + max(n.x, n.index)
+	case celCall:
+		return 1 + max(append([]celNode{n.target}, n.args...)...)
+	case celUnary:
+		return 1 + max(n.x)
+	case celBinary:
+		return 1 + max(n.x, n.y)
+	case celCond:
+		return 1 + max(n.cond, n.then, n.els)
+	case celList:
+		return 1 + max(n.elems...)
+	case nil:
+		return 0
+	}
+	return 1
+}
+
+// celValue converts v to the value expressions work on: bool, int64,
+// float64, string or time.Duration, or v itself for structs and
+// collections. Unsigned values beyond the int range are an error.
+func celValue(v reflect.Value) (any, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows int", v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	}
+	return v, nil
+}
+
+// celTypeName names the cel type of value x in errors.
+func celTypeName(x any) string {
+	switch x := x.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case time.Duration:
+		return "duration"
+	case []any:
+		return "list"
+	case reflect.Value:
+		switch x.Kind() {
+		case reflect.Slice, reflect.Array:
+			return "list"
+		case reflect.Map:
+			return "map"
+		}
+		return x.Type().String()
+	}
+	return fmt.Sprintf("%T", x)
+}
+
+func noOverload(op string, args ...any) error {
+	names := make([]string, len(args))
+	for i, a := range args {
+		names[i] = celTypeName(a)
+	}
+	return fmt.Errorf("%w: %s(%s)", errNoOverload, op, strings.Join(names, ", "))
+}
+
+var (
+	errNoOverload = errors.New("no such overload")
+	errOverflow   = errors.New("integer overflow")
+)
+
+// celEval evaluates parsed expressions for one field.
+type celEval struct {
+	self, this reflect.Value
+}
+
+func (ev celEval) eval(n celNode) (any, error) {
+	switch n := n.(type) {
+	case celLit:
+		return n.value, nil
+	case celIdent:
+		switch n.name {
+		case "self":
+			return celValue(ev.self)
+		case "this":
+			return celValue(ev.this)
+		}
+		return nil, fmt.Errorf("undeclared reference to %s", n.name)
+	case celSelect:
+		x, err := ev.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		return celField(x, n.field)
+	case celIndex:
+		x, err := ev.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		index, err := ev.eval(n.index)
+		if err != nil {
+			return nil, err
+		}
+		return celElem(x, index)
+	case celList:
+		list := make([]any, len(n.elems))
+		for i, e := range n.elems {
+			v, err := ev.eval(e)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case celUnary:
+		x, err := ev.eval(n.x)
+		if err != nil {
+			return nil, err
+		}
+		return celNegate(n.op, x)
+	case celCond:
+		cond, err := ev.eval(n.cond)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := cond.(bool)
+		if !ok {
+			return nil, noOverload("_?_:_", cond)
+		}
+		if b {
+			return ev.eval(n.then)
+		}
+		return ev.eval(n.els)
+	case celBinary:
+		return ev.binary(n)
+	case celCall:
+		return ev.call(n)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", n)
+}
+
+// celField selects the exported field name of a struct, or the entry name
+// of a string-keyed map.
+func celField(x any, name string) (any, error) {
+	v, ok := x.(reflect.Value)
+	if ok && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		return celElem(x, name)
+	}
+	if !ok || v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot select %s from %s", name, celTypeName(x))
+	}
+	sf, ok := v.Type().FieldByName(name)
+	if !ok || !sf.IsExported() {
+		return nil, fmt.Errorf("no such field %s", name)
+	}
+	f, err := v.FieldByIndexErr(sf.Index)
+	if err != nil {
+		return nil, nil
+	}
+	return celValue(f)
+}
+
+// celElem indexes a list by int or a map by key.
+func celElem(x, index any) (any, error) {
+	switch list := x.(type) {
+	case []any:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, noOverload("_[_]", x, index)
+		}
+		if i < 0 || i >= int64(len(list)) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return list[i], nil
+	case reflect.Value:
+		switch list.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, ok := index.(int64)
+			if !ok {
+				return nil, noOverload("_[_]", x, index)
+			}
+			if i < 0 || i >= int64(list.Len()) {
+				return nil, fmt.Errorf("index %d out of range", i)
+			}
+			return celValue(list.Index(int(i)))
+		case reflect.Map:
+			iter := list.MapRange()
+			for iter.Next() {
+				key, err := celValue(iter.Key())
+				if err != nil {
+					return nil, err
+				}
+				if celEqual(key, index) {
+					return celValue(iter.Value())
+				}
+			}
+			return nil, fmt.Errorf("no such key: %v", index)
+		}
+	}
+	return nil, noOverload("_[_]", x, index)
+}
+
+func celNegate(op string, x any) (any, error) {
+	switch x := x.(type) {
+	case bool:
+		if op == "!" {
+			return !x, nil
+		}
+	case int64:
+		if op == "-" {
+			if x == math.MinInt64 {
+				return nil, errOverflow
+			}
+			return -x, nil
+		}
+	case float64:
+		if op == "-" {
+			return -x, nil
+		}
+	case time.Duration:
+		if op == "-" {
+			if x == math.MinInt64 {
+				return nil, errOverflow
+			}
+			return -x, nil
+		}
+	}
+	return nil, noOverload(op+"_", x)
+}
+
+func (ev celEval) binary(n celBinary) (any, error) {
+	x, err := ev.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		xb, ok := x.(bool)
+		if !ok {
+			return nil, noOverload("_"+n.op+"_", x)
+		}
+		if xb == (n.op == "||") {
+			return xb, nil
+		}
+		y, err := ev.eval(n.y)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := y.(bool); !ok {
+			return nil, noOverload("_"+n.op+"_", x, y)
+		}
+		return y, nil
+	}
+	y, err := ev.eval(n.y)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==", "!=":
+		return celEqual(x, y) == (n.op == "=="), nil
+	case "in":
+		return celIn(x, y)
+	}
+	var result any
+	switch xv := x.(type) {
+	case int64:
+		switch yv := y.(type) {
+		case int64:
+			result, err = celInts(n.op, xv, yv)
+		case float64:
+			result, err = celOrdered(n.op, float64(xv), yv)
+		case time.Duration:
+			if n.op == "*" {
+				var r any
+				r, err = celInts(n.op, xv, int64(yv))
+				if err == nil {
+					result = time.Duration(r.(int64))
+				}
+			}
+		}
+	case float64:
+		switch yv := y.(type) {
+		case int64:
+			result, err = celOrdered(n.op, xv, float64(yv))
+		case float64:
+			result, err = celOrdered(n.op, xv, yv)
+		}
+	case string:
+		if yv, ok := y.(string); ok {
+			result, err = celOrdered(n.op, xv, yv)
+		}
+	case time.Duration:
+		switch yv := y.(type) {
+		case time.Duration:
+			switch n.op {
+			case "+", "-":
+				var r any
+				r, err = celInts(n.op, int64(xv), int64(yv))
+				if err == nil {
+					result = time.Duration(r.(int64))
+				}
+			default:
+				result, err = celOrdered(n.op, xv, yv)
+			}
+		case int64:
+			if n.op == "*" || n.op == "/" {
+				var r any
+				r, err = celInts(n.op, int64(xv), yv)
+				if err == nil {
+					result = time.Duration(r.(int64))
+				}
+			}
+		}
+	}
+	if result == nil && err == nil {
+		err = noOverload("_"+n.op+"_", x, y)
+	}
+	return result, err
+}
+
+// celOrdered applies a comparison, or + and for doubles the other
+// arithmetic operators, to two values of the same ordered type.
+func celOrdered[T ~int64 | ~float64 | ~string](op string, x, y T) (any, error) {
+	switch op {
+	case "<":
+		return x < y, nil
+	case "<=":
+		return x <= y, nil
+	case ">":
+		return x > y, nil
+	case ">=":
+		return x >= y, nil
+	}
+	if xf, ok := any(x).(float64); ok {
+		yf := any(y).(float64)
+		switch op {
+		case "+":
+			return xf + yf, nil
+		case "-":
+			return xf - yf, nil
+		case "*":
+			return xf * yf, nil
+		case "/":
+			return xf / yf, nil
+		}
+	}
+	if xs, ok := any(x).(string); ok && op == "+" {
+		return xs + any(y).(string), nil
+	}
+	return nil, noOverload("_"+op+"_", x, y)
+}
+
+// celInts is celOrdered for ints, whose arithmetic fails on overflow.
+func celInts(op string, x, y int64) (any, error) {
+	switch op {
+	case "+":
+		if y > 0 && x > math.MaxInt64-y || y < 0 && x < math.MinInt64-y {
+			return nil, errOverflow
+		}
+		return x + y, nil
+	case "-":
+		if y < 0 && x > math.MaxInt64+y || y > 0 && x < math.MinInt64+y {
+			return nil, errOverflow
+		}
+		return x - y, nil
+	case "*":
+		r := x * y
+		if x != 0 && (r/x != y || x == -1 && y == math.MinInt64) {
+			return nil, errOverflow
+		}
+		return r, nil
+	case "/", "%":
+		if y == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if x == math.MinInt64 && y == -1 {
+			return nil, errOverflow
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	}
+	return celOrdered(op, x, y)
+}
+
+func celEqual(x, y any) bool {
+	switch xv := x.(type) {
+	case int64:
+		if yv, ok := y.(float64); ok {
+			return float64(xv) == yv
+		}
+	case float64:
+		if yv, ok := y.(int64); ok {
+			return xv == float64(yv)
+		}
+	case []any:
+		return celListEqual(x, y)
+	case reflect.Value:
+		if xv.Kind() == reflect.Slice || xv.Kind() == reflect.Array {
+			return celListEqual(x, y)
+		}
+		yv, ok := y.(reflect.Value)
+		if !ok || !xv.CanInterface() || !yv.CanInterface() {
+			return false
+		}
+		return reflect.DeepEqual(xv.Interface(), yv.Interface())
+	}
+	if _, ok := y.(reflect.Value); ok {
+		return false
+	}
+	if _, ok := y.([]any); ok {
+		return false
+	}
+	return x == y
+}
+
+// celElems returns the elements of list x, or false if it is not one.
+func celElems(x any) ([]any, bool) {
+	switch x := x.(type) {
+	case []any:
+		return x, true
+	case reflect.Value:
+		if x.Kind() != reflect.Slice && x.Kind() != reflect.Array {
+			return nil, false
+		}
+		elems := make([]any, x.Len())
+		for i := range elems {
+			e, err := celValue(x.Index(i))
+			if err != nil {
+				return nil, false
+			}
+			elems[i] = e
+		}
+		return elems, true
+	}
+	return nil, false
+}
+
+func celListEqual(x, y any) bool {
+	xs, ok := celElems(x)
+	if !ok {
+		return false
+	}
+	ys, ok := celElems(y)
+	if !ok || len(xs) != len(ys) {
+		return false
+	}
+	for i := range xs {
+		if !celEqual(xs[i], ys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// celIn reports whether x is an element of list y or a key of map y.
+func celIn(x, y any) (any, error) {
+	if v, ok := y.(reflect.Value); ok && v.Kind() == reflect.Map {
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := celValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if celEqual(x, key) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	elems, ok := celElems(y)
+	if !ok {
+		return nil, noOverload("@in", x, y)
+	}
+	for _, e := range elems {
+		if celEqual(x, e) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (ev celEval) call(n celCall) (any, error) {
+	args := make([]any, 0, len(n.args)+1)
+	if n.target != nil {
+		target, err := ev.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, a := range n.args {
+		v, err := ev.eval(a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	switch n.fn {
+	case "size", "int", "double", "string", "duration":
+		if len(args) == 1 {
+			return celConvert(n.fn, args[0])
+		}
+	case "startsWith", "endsWith", "contains":
+		if n.target == nil || len(args) != 2 {
+			break
+		}
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			break
+		}
+		switch n.fn {
+		case "startsWith":
+			return strings.HasPrefix(s, sub), nil
+		case "endsWith":
+			return strings.HasSuffix(s, sub), nil
+		}
+		return strings.Contains(s, sub), nil
+	default:
+		return nil, fmt.Errorf("undeclared reference to %s", n.fn)
+	}
+	return nil, noOverload(n.fn, args...)
+}
+
+// celConvert applies the one-argument function fn to arg.
+func celConvert(fn string, arg any) (any, error) {
+	switch fn {
+	case "size":
+		switch a := arg.(type) {
+		case string:
+			return int64(len([]rune(a))), nil
+		case []any:
+			return int64(len(a)), nil
+		case reflect.Value:
+			switch a.Kind() {
+			case reflect.Slice, reflect.Map, reflect.Array:
+				return int64(a.Len()), nil
+			}
+		}
+	case "int":
+		switch a := arg.(type) {
+		case int64:
+			return a, nil
+		case float64:
+			if math.IsNaN(a) || a < math.MinInt64 || a >= math.MaxInt64 {
+				return nil, errOverflow
+			}
+			return int64(a), nil
+		case time.Duration:
+			return int64(a), nil
+		case string:
+			return strconv.ParseInt(a, 10, 64)
+		}
+	case "double":
+		switch a := arg.(type) {
+		case int64:
+			return float64(a), nil
+		case float64:
+			return a, nil
+		case string:
+			return strconv.ParseFloat(a, 64)
+		}
+	case "string":
+		switch a := arg.(type) {
+		case string:
+			return a, nil
+		case bool, int64, float64, time.Duration:
+			return fmt.Sprint(a), nil
+		}
+	case "duration":
+		if a, ok := arg.(string); ok {
+			return time.ParseDuration(a)
+		}
+	}
+	return nil, noOverload(fn, arg)
+}
@@ -0,0 +1,87 @@
+package optionator
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// now returns the current time from c.Clock, or time.Now if it is unset.
+func (c Config) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
+}
+
+// lookupEnv reads an environment variable through c.LookupEnv, or
+// os.LookupEnv if it is unset.
+func (c Config) lookupEnv(name string) (string, bool) {
+	if c.LookupEnv != nil {
+		return c.LookupEnv(name)
+	}
+	return os.LookupEnv(name)
+}
+
+// relativeTime resolves text of the form base, base+<duration> or
+// base-<duration> against the clock of c, as in "$now+24h" with base
+// "$now". ok is false if text has another form.
+func (c Config) relativeTime(text, base string) (t time.Time, ok bool, err error) {
+	rest := strings.TrimPrefix(text, base)
+	if len(rest) == len(text) || rest != "" && rest[0] != '+' && rest[0] != '-' {
+		return t, false, nil
+	}
+	var offset time.Duration
+	if rest != "" {
+		if offset, err = time.ParseDuration(rest); err != nil {
+			return t, true, err
+		}
+	}
+	return c.now().Add(offset), true, nil
+}
+
+// resolveDefault rewrites a default tag of a field of type t into the form
+// parseAndSetDefault accepts under c: human numbers are normalized and
+// $now defaults of time fields become timestamps.
+func (c Config) resolveDefault(tag string, t reflect.Type) (string, error) {
+	if c.HumanNumbers {
+		tag = humanNumber(tag, t)
+	}
+	if t == timeType {
+		if now, ok, err := c.relativeTime(tag, "$now"); ok {
+			if err == nil {
+				err = c.dynamic("$now default")
+			}
+			return now.Format(time.RFC3339Nano), err
+		}
+	}
+	return tag, nil
+}
+
+type targetTypeKey struct{}
+
+// withTargetType returns a context recording that sources load values for
+// struct type t, so they can look values up by field.
+func withTargetType(ctx context.Context, t reflect.Type) context.Context {
+	return context.WithValue(ctx, targetTypeKey{}, t)
+}
+
+// targetTypeFrom returns the struct type recorded by withTargetType.
+func targetTypeFrom(ctx context.Context) (reflect.Type, bool) {
+	t, ok := ctx.Value(targetTypeKey{}).(reflect.Type)
+	return t, ok
+}
+
+// withTargetOf is withTargetType for T, a struct or pointer to a struct.
+func withTargetOf[T any](ctx context.Context) context.Context {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ctx
+	}
+	return withTargetType(ctx, t)
+}
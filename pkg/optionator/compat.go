@@ -0,0 +1,128 @@
+package optionator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaChangeKind classifies a difference between two versions of a
+// configuration struct.
+type SchemaChangeKind int
+
+const (
+	// FieldAdded is a new optional field, or a required one with a default.
+	FieldAdded SchemaChangeKind = iota
+	// FieldRemoved is a field that no longer exists; configs setting it
+	// now have an unknown key.
+	FieldRemoved
+	// FieldRenamed is a field whose old path appears in the alias tag of a
+	// new one, so configs using the old name still load.
+	FieldRenamed
+	// TypeChanged is a field whose type differs.
+	TypeChanged
+	// DefaultChanged is a field whose default tag differs.
+	DefaultChanged
+	// NewRequiredField is a field, new or existing, that is now required
+	// without a default, so configs that did not set it fail.
+	NewRequiredField
+)
+
+func (k SchemaChangeKind) String() string {
+	switch k {
+	case FieldAdded:
+		return "field added"
+	case FieldRemoved:
+		return "field removed"
+	case FieldRenamed:
+		return "field renamed"
+	case TypeChanged:
+		return "type changed"
+	case DefaultChanged:
+		return "default changed"
+	case NewRequiredField:
+		return "new required field"
+	}
+	return fmt.Sprintf("SchemaChangeKind(%d)", int(k))
+}
+
+// SchemaChange is one difference found by CompareSchemas.
+type SchemaChange struct {
+	Path string
+	Kind SchemaChangeKind
+	// Old and New describe the values that changed, such as the types or
+	// defaults; for renames, the old and new paths.
+	Old, New string
+	// Breaking is set for changes that can make a config that loaded with
+	// the old version fail or mean something else with the new one.
+	Breaking bool
+}
+
+func (c SchemaChange) String() string {
+	s := fmt.Sprintf("%s: %s", c.Path, c.Kind)
+	if c.Old != "" || c.New != "" {
+		s += fmt.Sprintf(" (%s -> %s)", c.Old, c.New)
+	}
+	if c.Breaking {
+		s += " [breaking]"
+	}
+	return s
+}
+
+// CompareSchemas compares the Describe output of two versions of a struct
+// and returns their differences ordered by path. Types are compared by
+// name. A CI job can fail on any change with Breaking set.
+func CompareSchemas(old, new []FieldInfo) []SchemaChange {
+	oldByPath := map[string]FieldInfo{}
+	for _, fi := range old {
+		oldByPath[fi.Path] = fi
+	}
+	renamed := map[string]string{}
+	var changes []SchemaChange
+	for _, fi := range new {
+		prev, existed := oldByPath[fi.Path]
+		if !existed {
+			for _, alias := range tagList(fi.tag.Get("alias")) {
+				path := alias
+				if i := strings.LastIndex(fi.Path, "."); i >= 0 {
+					path = fi.Path[:i+1] + alias
+				}
+				if p, ok := oldByPath[path]; ok {
+					prev, existed = p, true
+					renamed[path] = fi.Path
+					changes = append(changes, SchemaChange{Path: fi.Path, Kind: FieldRenamed, Old: path, New: fi.Path})
+					break
+				}
+			}
+		}
+		required := fi.Required && fi.Default == ""
+		switch {
+		case !existed && required:
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: NewRequiredField, Breaking: true})
+			continue
+		case !existed:
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: FieldAdded})
+			continue
+		}
+		if prev.Type.String() != fi.Type.String() {
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: TypeChanged, Old: prev.Type.String(), New: fi.Type.String(), Breaking: true})
+		}
+		if prev.Default != fi.Default {
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: DefaultChanged, Old: prev.Default, New: fi.Default})
+		}
+		if required && !(prev.Required && prev.Default == "") {
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: NewRequiredField, Breaking: true})
+		}
+	}
+	newPaths := map[string]bool{}
+	for _, fi := range new {
+		newPaths[fi.Path] = true
+	}
+	for _, fi := range old {
+		if _, ok := renamed[fi.Path]; !ok && !newPaths[fi.Path] {
+			changes = append(changes, SchemaChange{Path: fi.Path, Kind: FieldRemoved, Breaking: true})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
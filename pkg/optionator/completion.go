@@ -0,0 +1,172 @@
+package optionator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CompletionHint tells a shell how to complete a flag's value.
+type CompletionHint string
+
+const (
+	// HintFile completes file paths; set with `complete:"file"`.
+	HintFile CompletionHint = "file"
+	// HintDir completes directories; set with `complete:"dir"`.
+	HintDir CompletionHint = "dir"
+)
+
+// Completion describes one flag bound by BindFlags for shell completion.
+type Completion struct {
+	Flag        string
+	Description string
+	// Bool flags take no value.
+	Bool bool
+	// Values lists the accepted values, from a oneof tag or the names
+	// registered with RegisterFunc for a function-typed field.
+	Values []string
+	Hint   CompletionHint
+}
+
+// Completions returns the completion data of the flags BindFlags registers
+// for T, for CLI adapters to generate completions from.
+func Completions[T any]() ([]Completion, error) {
+	fields, err := Describe[T]()
+	if err != nil {
+		return nil, err
+	}
+	var comps []Completion
+	for _, fi := range fields {
+		if fi.Hidden || !isParsable(fi.Type) {
+			continue
+		}
+		c := Completion{
+			Flag:        FlagName(fi.Path),
+			Description: fi.Description,
+			Bool:        fi.Type.Kind() == reflect.Bool,
+			Values:      tagList(fi.tag.Get("oneof")),
+			Hint:        CompletionHint(fi.tag.Get("complete")),
+		}
+		if fi.Type.Kind() == reflect.Func {
+			c.Values = funcNames(fi.Type)
+		}
+		comps = append(comps, c)
+	}
+	return comps, nil
+}
+
+// funcNames lists the names registered for function type t, sorted.
+func funcNames(t reflect.Type) []string {
+	var names []string
+	funcs.Range(func(key, _ any) bool {
+		if k := key.(funcKey); k.Type == t {
+			names = append(names, k.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// WriteCompletion writes a completion script for prog in shell, one of
+// "bash", "zsh" or "fish", completing the flags in comps.
+func WriteCompletion(w io.Writer, shell, prog string, comps []Completion) error {
+	var b bytes.Buffer
+	switch shell {
+	case "bash":
+		writeBash(&b, prog, comps)
+	case "zsh":
+		writeZsh(&b, prog, comps)
+	case "fish":
+		writeFish(&b, prog, comps)
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func writeBash(b *bytes.Buffer, prog string, comps []Completion) {
+	fn := "_" + nonIdent.ReplaceAllString(prog, "_") + "_complete"
+	fmt.Fprintf(b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	var flags []string
+	for _, c := range comps {
+		flags = append(flags, "-"+c.Flag)
+		var gen string
+		switch {
+		case len(c.Values) > 0:
+			gen = fmt.Sprintf("-W %q", strings.Join(c.Values, " "))
+		case c.Hint == HintFile:
+			gen = "-f"
+		case c.Hint == HintDir:
+			gen = "-d"
+		case c.Bool:
+			continue
+		default:
+			gen = "-o default"
+		}
+		fmt.Fprintf(b, "\t-%[1]s|--%[1]s)\n\t\tCOMPREPLY=($(compgen %[2]s -- \"$cur\"))\n\t\treturn ;;\n", c.Flag, gen)
+	}
+	b.WriteString("\tesac\n")
+	fmt.Fprintf(b, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(flags, " "))
+	fmt.Fprintf(b, "}\ncomplete -F %s %s\n", fn, prog)
+}
+
+var zshEscaper = strings.NewReplacer("[", "\\[", "]", "\\]", ":", "\\:", "'", "'\\''")
+
+func writeZsh(b *bytes.Buffer, prog string, comps []Completion) {
+	fmt.Fprintf(b, "#compdef %s\n\n_arguments \\\n", prog)
+	for i, c := range comps {
+		spec := "-" + c.Flag
+		if c.Description != "" {
+			spec += "[" + zshEscaper.Replace(c.Description) + "]"
+		}
+		switch {
+		case c.Bool:
+		case len(c.Values) > 0:
+			spec += ":value:(" + zshEscaper.Replace(strings.Join(c.Values, " ")) + ")"
+		case c.Hint == HintFile:
+			spec += ":file:_files"
+		case c.Hint == HintDir:
+			spec += ":directory:_files -/"
+		default:
+			spec += ":value:"
+		}
+		fmt.Fprintf(b, "\t'%s'", spec)
+		if i < len(comps)-1 {
+			b.WriteString(" \\")
+		}
+		b.WriteString("\n")
+	}
+}
+
+var fishEscaper = strings.NewReplacer("\\", "\\\\", "'", "\\'")
+
+func writeFish(b *bytes.Buffer, prog string, comps []Completion) {
+	for _, c := range comps {
+		fmt.Fprintf(b, "complete -c %s -o %s", prog, c.Flag)
+		if c.Description != "" {
+			fmt.Fprintf(b, " -d '%s'", fishEscaper.Replace(c.Description))
+		}
+		switch {
+		case c.Bool:
+		case len(c.Values) > 0:
+			fmt.Fprintf(b, " -x -a '%s'", fishEscaper.Replace(strings.Join(c.Values, " ")))
+		case c.Hint == HintFile:
+			b.WriteString(" -r -F")
+		case c.Hint == HintDir:
+			b.WriteString(" -x -a '(__fish_complete_directories)'")
+		default:
+			b.WriteString(" -x")
+		}
+		b.WriteString("\n")
+	}
+}
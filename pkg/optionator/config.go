@@ -1,14 +1,99 @@
 package optionator
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/internal/tagrules"
+)
+
+// Tag compatibility modes accepted by Config.TagCompatibility.
+const (
+	// CompatEnvconfig reads kelseyhightower/envconfig tags: default, required and ignored.
+	CompatEnvconfig = "envconfig"
+	// CompatCaarlos0 reads caarlos0/env tags: envDefault and the required/notEmpty options of env.
+	CompatCaarlos0 = "caarlos0"
 )
 
 // Config holds customizable tag names for defaults and required fields.
 type Config struct {
 	DefaultTag  string
 	RequiredTag string
+	// TagCompatibility, when set, makes the default and required metadata come
+	// from another library's tags instead of DefaultTag and RequiredTag.
+	TagCompatibility string
+	// CollectStats records, after each successful construction, whether the
+	// fields with defaults kept them. See Stats.
+	CollectStats bool
+	// Tracer, when set, receives spans for each construction phase.
+	Tracer Tracer
+	// Sources are loaded in order after defaults and before options.
+	Sources []Source
+	// ValueDecrypter decrypts source string values that start with
+	// EncryptedValuePrefix before they are bound.
+	ValueDecrypter Decrypter
+	// Profile selects the matching profile documents of file sources, such
+	// as "prod" or "dev".
+	Profile string
+	// TemplateFiles runs file sources through text/template before decoding,
+	// with the env, file, default and required functions available. It is
+	// off by default because it lets config files read the environment, and
+	// other files when EvalLimits.AllowFiles is set.
+	TemplateFiles bool
+	// IgnoreZeroOptions makes With leave a field alone when given its zero
+	// value, so options can be built from a partially filled struct.
+	IgnoreZeroOptions bool
+	// SlowThreshold, when positive, makes NewWithReport warn about each
+	// source load or option application that takes longer.
+	SlowThreshold time.Duration
+	// Strictness decides whether unknown source keys, lossy numbers and bad
+	// default tags fail construction, are warned about or are ignored.
+	Strictness Strictness
+	// SkipDefaults leaves default tags as documentation: they are not
+	// applied during construction but still appear in Describe and exports,
+	// and WithReset applies them on request.
+	SkipDefaults bool
+	// HumanNumbers lets numeric defaults and source values be written as
+	// 1_000_000, 1,000,000 or 1e6. Integer fields accept scientific
+	// notation only for whole numbers.
+	HumanNumbers bool
+	// DecodeHooks convert values set by With and sources before the
+	// built-in conversions apply, for types optionator does not know.
+	DecodeHooks []DecodeHook
+	// AllowExperimental lets sources and options set fields tagged
+	// stability:"experimental". Without it, setting one fails construction,
+	// or under a lenient Strictness is ignored or warned about.
+	AllowExperimental bool
+	// NamingStrategy, when set, derives source keys, flag names and
+	// environment variable names from field names, such as max_conns with
+	// SnakeCase. Go field names are accepted as keys either way.
+	NamingStrategy NamingStrategy
+	// Clock, when set, replaces time.Now for $now defaults, before:"now"
+	// and after:"now" bounds and audit timestamps, so tests do not depend
+	// on the wall clock.
+	Clock func() time.Time
+	// LookupEnv, when set, replaces os.LookupEnv for EnvSource and the env
+	// function of TemplateFiles.
+	LookupEnv func(name string) (string, bool)
+	// DisableDynamic fails construction on any dynamic evaluation: file
+	// templates, cel tags, derived and $now defaults. It suits deployments
+	// whose security review rules out evaluating config contents.
+	DisableDynamic bool
+	// EvalLimits bounds the dynamic evaluation that is allowed.
+	EvalLimits EvalLimits
+	// OnPhaseTimes, when set, receives after each construction the time
+	// it spent in each phase.
+	OnPhaseTimes func(PhaseTimes)
+	// ProfileLabels runs each construction phase under the pprof labels
+	// optionator.type and optionator.phase, so CPU profiles attribute
+	// construction time per struct and phase. The goroutine's labels are
+	// restored to those of the construction's context afterwards.
+	ProfileLabels bool
 }
 
 var defaultConfig = Config{
@@ -16,25 +101,209 @@ var defaultConfig = Config{
 	RequiredTag: "required",
 }
 
+// fieldTags extracts the default value and required flag of a struct field
+// according to the configured tag names or compatibility mode. A field that
+// the mode marks as ignored is reported with ignored set to true.
+func (c Config) fieldTags(sf reflect.StructField) (def string, required, ignored bool) {
+	switch c.TagCompatibility {
+	case CompatEnvconfig:
+		if sf.Tag.Get("ignored") == "true" {
+			return "", false, true
+		}
+		return sf.Tag.Get("default"), sf.Tag.Get("required") == "true", false
+	case CompatCaarlos0:
+		env, ok := sf.Tag.Lookup("env")
+		if env == "-" {
+			return "", false, true
+		}
+		if ok {
+			for _, opt := range strings.Split(env, ",")[1:] {
+				if opt == "required" || opt == "notEmpty" {
+					required = true
+				}
+			}
+		}
+		return sf.Tag.Get("envDefault"), required, false
+	}
+	return sf.Tag.Get(c.DefaultTag), isRequired(sf.Tag.Get(c.RequiredTag)), false
+}
+
+// isRequired reports whether a required tag value demands a value.
+func isRequired(tag string) bool {
+	return tagrules.Required(tag)
+}
+
 // NewWithConfig creates a new configuration object using the provided config.
 func NewWithConfig[T any](target T, config Config, opts ...Option[T]) (T, error) {
+	return newWithContext(context.Background(), target, config, opts)
+}
+
+// newWithContext is NewWithConfig with a context for loading sources.
+func newWithContext[T any](ctx context.Context, target T, config Config, opts []Option[T]) (T, error) {
+	return newWithLoader(ctx, target, config, opts, func(ctx context.Context) ([]loadedSource, error) {
+		return fetchSources(ctx, config)
+	})
+}
+
+// newWithLoader constructs target, getting the source values from load.
+func newWithLoader[T any](ctx context.Context, target T, config Config, opts []Option[T], load sourceLoader) (T, error) {
+	if fields, ok := lookupSchema[T](); ok {
+		return newFromSchema(target, fields, opts)
+	}
+	if !reflectionEnabled {
+		return target, errors.New("no schema registered for target type")
+	}
 	v := reflect.ValueOf(target)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		return target, errors.New("target must be a pointer to a struct")
 	}
+	switch config.TagCompatibility {
+	case "", CompatEnvconfig, CompatCaarlos0:
+	default:
+		return target, fmt.Errorf("unknown tag compatibility mode %q", config.TagCompatibility)
+	}
+	span := startSpan(config, "optionator.New")
+	span.SetAttribute("type", v.Elem().Type().String())
+	if config.Profile != "" {
+		span.SetAttribute("profile", config.Profile)
+	}
+	ctx, profile := withPhaseProfile(ctx, config, v.Elem().Type().String())
+	if profile != nil {
+		stop := phase(ctx, "metadata")
+		describeCached(v.Elem().Type(), config)
+		stop()
+	}
+	if len(opts) > 0 {
+		inFlight.Store(target, flight{config, ctx})
+		defer inFlight.Delete(target)
+	}
+	ctx = withDerivedLater(withTargetOf[T](ctx))
+	err := build(ctx, v.Elem(), target, config, opts, load)
+	if err == nil && config.Tracer != nil {
+		if fp, fpErr := Fingerprint(target); fpErr == nil {
+			span.SetAttribute("config.fingerprint", fp)
+		}
+	}
+	span.End(err)
+	profile.done(err)
+	return target, err
+}
+
+// inFlight maps the targets under construction to their flight, so options
+// can honour settings such as IgnoreZeroOptions.
+var inFlight sync.Map // map[any]flight
+
+// flight is the Config and context of a construction in progress.
+type flight struct {
+	config Config
+	ctx    context.Context
+}
+
+// configFor returns the Config target is being constructed with, or
+// defaultConfig when the option is applied outside of construction.
+func configFor(target any) Config {
+	if f, ok := inFlight.Load(target); ok {
+		return f.(flight).config
+	}
+	return defaultConfig
+}
+
+// contextFor returns the context target is being constructed with, or
+// context.Background() outside of construction.
+func contextFor(target any) context.Context {
+	if f, ok := inFlight.Load(target); ok {
+		return f.(flight).ctx
+	}
+	return context.Background()
+}
+
+// build runs the construction pipeline on v, the struct target points to.
+func build[T any](ctx context.Context, v reflect.Value, target T, config Config, opts []Option[T], load sourceLoader) error {
 	// Set defaults recursively.
-	if err := setDefaultRecursively(v.Elem(), config); err != nil {
-		return target, err
+	span := startSpan(config, "defaults")
+	stop := phase(ctx, "defaults")
+	err := salvage(ctx, "", setDefaultRecursively(ctx, v, config))
+	stop()
+	span.End(err)
+	if err != nil {
+		return err
+	}
+	// Load sources over the defaults.
+	stop = phase(ctx, "sources")
+	loaded, err := load(ctx)
+	stop()
+	if err != nil {
+		return err
+	}
+	stop = phase(ctx, "binding")
+	err = bindSources(ctx, v, loaded, config)
+	if err == nil {
+		err = salvage(ctx, "", reconcileKinds(ctx, v, config))
+	}
+	stop()
+	if err != nil {
+		return err
 	}
-	// Apply provided options to override defaults.
-	for _, opt := range opts {
-		if err := opt(target); err != nil {
-			return target, err
+	return finish(ctx, v, target, config, opts)
+}
+
+// finish applies opts to target, the pointer to struct v, and validates the
+// result. It is the part of construction that Reconfigure repeats on a copy
+// of a live value.
+func finish[T any](ctx context.Context, v reflect.Value, target T, config Config, opts []Option[T]) error {
+	// Apply provided options to override defaults, remembering the values
+	// they replace if any field wants to hear about changes.
+	stop := phase(ctx, "options")
+	defer func() { stop() }()
+	var before reflect.Value
+	if len(opts) > 0 && !isReload(ctx) && hasOnSet(v.Type(), config) {
+		before = snapshot(v)
+	}
+	for i, opt := range opts {
+		span := startSpan(config, "option")
+		span.SetAttribute("index", i)
+		start := time.Now()
+		err := opt(target)
+		timeSince(ctx, config, "option", fmt.Sprintf("option %d", i), start)
+		span.End(err)
+		if err := salvage(ctx, "", err); err != nil {
+			return err
+		}
+	}
+	if derivedLater(ctx) {
+		if err := salvage(ctx, "", setDerivedRecursively(v, config)); err != nil {
+			return err
+		}
+	}
+	if len(opts) > 0 {
+		if err := salvage(ctx, "", reconcileKinds(ctx, v, config)); err != nil {
+			return err
+		}
+	}
+	if before.IsValid() {
+		if err := salvage(ctx, "", runOnSet(before, v, config)); err != nil {
+			return err
 		}
 	}
+	if err := checkExperimental(ctx, v, config); err != nil {
+		return err
+	}
 	// Validate required fields.
-	if err := validateRequiredFields(v.Elem(), config); err != nil {
-		return target, err
+	stop()
+	stop = phase(ctx, "validate")
+	span := startSpan(config, "validate")
+	var err error
+	if isLenient(ctx) {
+		validateAll(ctx, v, config)
+	} else {
+		err = validate(v, config)
+	}
+	span.End(err)
+	if err != nil {
+		return err
+	}
+	if config.CollectStats {
+		recordStats(v, config)
 	}
-	return target, nil
+	return nil
 }
@@ -0,0 +1,112 @@
+package optionator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithWeakTyping wraps src so that its values are converted leniently when
+// bound, for loosely typed inputs such as hand-edited files:
+//
+//   - numbers and booleans become strings for string fields
+//   - numbers become booleans (non-zero is true) and booleans become 1 or 0
+//   - numeric strings with an integral value, such as "3.0", fill int fields
+//   - the empty string is the zero value for non-string fields
+//   - a comma-separated string or a single value fills a slice
+func WithWeakTyping(src Source) Source {
+	return weakSource{src}
+}
+
+type weakSource struct{ Source }
+
+func (weakSource) WeaklyTyped() bool { return true }
+
+func (s weakSource) Optional() bool { return isOptional(s.Source) }
+
+func (s weakSource) Kind() SourceKind { return sourceKind(s.Source) }
+
+// isWeaklyTyped reports whether values from src are converted leniently.
+func isWeaklyTyped(src Source) bool {
+	w, ok := src.(interface{ WeaklyTyped() bool })
+	return ok && w.WeaklyTyped()
+}
+
+// weakConvert rewrites value into a form the strict binding rules accept for
+// a field of type t. Values it has no rule for are returned unchanged.
+func weakConvert(value any, t reflect.Type) (any, error) {
+	if s, ok := value.(string); ok && s == "" && t.Kind() != reflect.String && t.Kind() != reflect.Slice {
+		return reflect.Zero(t).Interface(), nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case json.Number:
+			return v.String(), nil
+		}
+		if rv := reflect.ValueOf(value); isNumber(rv.Kind()) {
+			return strings.TrimSpace(fmtNumber(rv)), nil
+		}
+	case reflect.Bool:
+		if n, ok := value.(json.Number); ok {
+			f, err := n.Float64()
+			return f != 0, err
+		}
+		if rv := reflect.ValueOf(value); isNumber(rv.Kind()) {
+			return !rv.IsZero(), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if t == durationType {
+			break
+		}
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return 1, nil
+			}
+			return 0, nil
+		case string, json.Number:
+			// Integers are left to the exact strict parser; only forms
+			// like "3.0" or "1e3" go through float64.
+			text := strings.TrimSpace(reflect.ValueOf(v).String())
+			if !strings.ContainsAny(text, ".eE") {
+				return text, nil
+			}
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				return f, nil
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if reflect.ValueOf(value).Kind() == reflect.Slice {
+			break
+		}
+		if s, ok := value.(string); ok && (t.Kind() == reflect.Array || t.Elem().Kind() != reflect.Uint8) {
+			if s == "" {
+				return []any{}, nil
+			}
+			parts := strings.Split(s, ",")
+			list := make([]any, len(parts))
+			for i, p := range parts {
+				list[i] = strings.TrimSpace(p)
+			}
+			return list, nil
+		}
+		return []any{value}, nil
+	}
+	return value, nil
+}
+
+func fmtNumber(v reflect.Value) string {
+	switch {
+	case v.CanInt():
+		return strconv.FormatInt(v.Int(), 10)
+	case v.CanUint():
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+}
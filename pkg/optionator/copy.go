@@ -0,0 +1,102 @@
+package optionator
+
+import "reflect"
+
+// NewValue deep-copies proto, runs the construction pipeline on the copy and
+// returns it, leaving proto untouched. This makes it safe to share a
+// prototype across goroutines. On error the zero value is returned.
+func NewValue[T any](proto T, opts ...Option[*T]) (T, error) {
+	return NewValueWithConfig(proto, defaultConfig, opts...)
+}
+
+// NewValueWithConfig is like NewValue but uses the provided config.
+func NewValueWithConfig[T any](proto T, config Config, opts ...Option[*T]) (T, error) {
+	cp := deepCopy(proto)
+	if _, err := NewWithConfig(&cp, config, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return cp, nil
+}
+
+// deepCopy returns a copy of v that shares no pointers, slices or maps with
+// it. Functions and channels are shared, and unexported struct fields are
+// copied shallowly since reflection cannot set them.
+func deepCopy[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	copyValue(dst, src, copies{})
+	return dst.Interface().(T)
+}
+
+// copies maps the pointers already copied to their copies. A pointer to a
+// struct and one to its first field share an address, so the key includes
+// the type.
+type copies map[copyKey]reflect.Value
+
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// copyValue deep-copies src into dst. Seen maps already copied pointers to
+// their copies so shared and cyclic pointers are preserved.
+func copyValue(dst, src reflect.Value, seen copies) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() || isTextType(src.Type()) {
+			dst.Set(src)
+			return
+		}
+		key := copyKey{src.Pointer(), src.Type()}
+		if cp, ok := seen[key]; ok {
+			dst.Set(cp)
+			return
+		}
+		cp := reflect.New(src.Type().Elem())
+		seen[key] = cp
+		copyValue(cp.Elem(), src.Elem(), seen)
+		dst.Set(cp)
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).PkgPath == "" {
+				copyValue(dst.Field(i), src.Field(i), seen)
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		cp := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(cp.Index(i), src.Index(i), seen)
+		}
+		dst.Set(cp)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i), seen)
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		cp := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			copyValue(elem, iter.Value(), seen)
+			cp.SetMapIndex(iter.Key(), elem)
+		}
+		dst.Set(cp)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		elem := reflect.New(src.Elem().Type()).Elem()
+		copyValue(elem, src.Elem(), seen)
+		dst.Set(elem)
+	default:
+		dst.Set(src)
+	}
+}
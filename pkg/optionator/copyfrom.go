@@ -0,0 +1,89 @@
+package optionator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// FieldMap maps the field at path From of a CopyFrom source onto the field
+// at path To of the target. An empty To leaves the source field uncopied.
+type FieldMap struct {
+	From, To string
+}
+
+// CopyFrom returns an Option that copies the non-zero fields of src, a
+// struct or pointer to one of another type, onto the fields of the target
+// with the same dotted path, or with the path given by a mapping. Source
+// fields with no counterpart are ignored, unless mapped. Values are
+// converted when their types differ, and copied values share no pointers,
+// slices or maps with src.
+func CopyFrom[T, S any](src S, mapping ...FieldMap) Option[T] {
+	return func(target T) error {
+		v, s := reflect.ValueOf(target), reflect.ValueOf(src)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return errors.New("target must be a pointer to a struct")
+		}
+		for s.Kind() == reflect.Ptr {
+			if s.IsNil() {
+				return nil
+			}
+			s = s.Elem()
+		}
+		if s.Kind() != reflect.Struct {
+			return fmt.Errorf("cannot copy from %v: not a struct", s.Type())
+		}
+		paths := map[string]string{}
+		for _, m := range mapping {
+			paths[m.From] = m.To
+		}
+		for _, from := range describeType(s.Type(), defaultConfig) {
+			to, mapped := paths[from.Path]
+			delete(paths, from.Path)
+			if !mapped {
+				to = from.Path
+			} else if to == "" {
+				continue
+			}
+			fi, ok := lookupPath(v.Elem().Type(), configFor(target), to)
+			if !ok {
+				if mapped {
+					return fmt.Errorf("no such field: %s", to)
+				}
+				continue
+			}
+			value, ok := lookupIndexes(s, from.indexes)
+			if !ok || isZeroValue(value) {
+				continue
+			}
+			if err := copyConverted(fieldByIndexes(v.Elem(), fi.indexes), value); err != nil {
+				return fmt.Errorf("cannot copy %s to %s: %w", from.Path, to, err)
+			}
+		}
+		for from := range paths {
+			return fmt.Errorf("no such source field: %s", from)
+		}
+		return nil
+	}
+}
+
+// copyConverted sets dst to a deep copy of src, converted to dst's type.
+func copyConverted(dst, src reflect.Value) error {
+	cp := reflect.New(src.Type()).Elem()
+	copyValue(cp, src, copies{})
+	switch {
+	case cp.Type().AssignableTo(dst.Type()):
+		dst.Set(cp)
+	case cp.Type().ConvertibleTo(dst.Type()) && !isNumber(cp.Kind()):
+		dst.Set(cp.Convert(dst.Type()))
+	case isNumber(cp.Kind()) && isNumber(dst.Kind()):
+		converted, err := convertNumber(cp, dst.Type())
+		if err != nil {
+			return err
+		}
+		dst.Set(converted)
+	default:
+		return fmt.Errorf("cannot convert %v to %v", src.Type(), dst.Type())
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package optionator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Decrypter decrypts configuration ciphertext. Implementations wrap an age
+// identity, a cloud KMS key or similar.
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// DecrypterFunc adapts a function to the Decrypter interface.
+type DecrypterFunc func(ciphertext []byte) ([]byte, error)
+
+func (f DecrypterFunc) Decrypt(ciphertext []byte) ([]byte, error) { return f(ciphertext) }
+
+// EncryptedValuePrefix marks a string source value holding base64-encoded
+// ciphertext to be decrypted with Config.ValueDecrypter.
+const EncryptedValuePrefix = "enc:v1:"
+
+// decryptValues returns a copy of values with every encrypted string,
+// however deeply nested, replaced by its plaintext.
+func decryptValues(values map[string]any, d Decrypter) (map[string]any, error) {
+	out := make(map[string]any, len(values))
+	for key, value := range values {
+		plain, err := decryptValue(value, d)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key, err)
+		}
+		out[key] = plain
+	}
+	return out, nil
+}
+
+func decryptValue(value any, d Decrypter) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.HasPrefix(v, EncryptedValuePrefix) {
+			return v, nil
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, EncryptedValuePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("decoding encrypted value: %w", err)
+		}
+		plain, err := d.Decrypt(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting value: %w", err)
+		}
+		return string(plain), nil
+	case map[string]any:
+		return decryptValues(v, d)
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			plain, err := decryptValue(elem, d)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = plain
+		}
+		return out, nil
+	}
+	return value, nil
+}
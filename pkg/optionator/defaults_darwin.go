@@ -0,0 +1,39 @@
+//go:build darwin
+
+package optionator
+
+import (
+	"context"
+	"os/exec"
+)
+
+// DefaultsSource returns a Source that reads the macOS user defaults of
+// domain, such as "com.example.app", into the fields of T tagged
+// `setting:"key"`. It runs `defaults export`; a domain with no defaults
+// yields no values.
+func DefaultsSource[T any](domain string) Source {
+	return defaultsSource[T]{domain}
+}
+
+type defaultsSource[T any] struct {
+	domain string
+}
+
+func (s defaultsSource[T]) Name() string { return "defaults:" + s.domain }
+
+func (defaultsSource[T]) Kind() SourceKind { return FromDefaults }
+
+func (s defaultsSource[T]) Load(ctx context.Context) (map[string]any, error) {
+	out, err := exec.CommandContext(ctx, "defaults", "export", s.domain, "-").Output()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := decodePlist(out)
+	if err != nil {
+		return nil, err
+	}
+	return settingValues[T](ConfigFromContext(ctx), func(key string) (any, bool) {
+		v, ok := settings[key]
+		return v, ok
+	})
+}
@@ -0,0 +1,245 @@
+package optionator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// definitions holds the field metadata registered in code, by struct type
+// and field name, as tag entries merged over the fields' own tags.
+var definitions struct {
+	sync.RWMutex
+	tags map[reflect.Type]map[string][]tagEntry
+}
+
+// tagEntry is one key:"value" pair of a struct tag.
+type tagEntry struct {
+	key, value string
+}
+
+// Definition registers metadata for the fields of struct type T in code, for
+// structs that cannot carry tags such as generated code or third-party
+// types. Each call takes effect immediately, as if the tag were on the
+// field, and replaces any tag of the same key the field has:
+//
+//	optionator.Define[Server]().
+//		Field("Address").Default("0.0.0.0").Required().
+//		Field("Port").Min(1).Max(65535)
+type Definition[T any] struct {
+	t reflect.Type
+}
+
+// FieldDefinition registers metadata for one field; see Definition.
+type FieldDefinition[T any] struct {
+	Definition[T]
+	name string
+}
+
+// Define returns the Definition of T, a struct or pointer to a struct.
+func Define[T any]() Definition[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("optionator: Define of non-struct type %v", t))
+	}
+	return Definition[T]{t}
+}
+
+// Field selects the field to register metadata for. It panics if T declares
+// no exported field of that name; fields of embedded structs are defined on
+// their own type.
+func (d Definition[T]) Field(name string) FieldDefinition[T] {
+	if sf, ok := d.t.FieldByName(name); !ok || sf.PkgPath != "" || len(sf.Index) != 1 {
+		panic(fmt.Sprintf("optionator: %v has no exported field %s", d.t, name))
+	}
+	return FieldDefinition[T]{d, name}
+}
+
+// Tag sets the tag key of the field to value.
+func (f FieldDefinition[T]) Tag(key, value string) FieldDefinition[T] {
+	defineTag(f.t, f.name, key, value)
+	return f
+}
+
+// Default sets the default of the field, read in place of the tag named by
+// Config.DefaultTag.
+func (f FieldDefinition[T]) Default(value string) FieldDefinition[T] {
+	return f.Tag("default", value)
+}
+
+// Required makes the field required, read in place of the tag named by
+// Config.RequiredTag.
+func (f FieldDefinition[T]) Required() FieldDefinition[T] {
+	return f.Tag("required", "true")
+}
+
+// Min sets the lower bound of the field, as a min tag.
+func (f FieldDefinition[T]) Min(bound any) FieldDefinition[T] {
+	return f.Tag("min", fmt.Sprint(bound))
+}
+
+// Max sets the upper bound of the field, as a max tag.
+func (f FieldDefinition[T]) Max(bound any) FieldDefinition[T] {
+	return f.Tag("max", fmt.Sprint(bound))
+}
+
+// OneOf restricts the field to the given values, as a oneof tag.
+func (f FieldDefinition[T]) OneOf(values ...string) FieldDefinition[T] {
+	return f.Tag("oneof", strings.Join(values, ","))
+}
+
+// Format checks the field against a registered format, as a format tag.
+func (f FieldDefinition[T]) Format(name string) FieldDefinition[T] {
+	return f.Tag("format", name)
+}
+
+// Desc sets the description of the field, as a desc tag.
+func (f FieldDefinition[T]) Desc(text string) FieldDefinition[T] {
+	return f.Tag("desc", text)
+}
+
+// Secret marks the field secret, as a secret tag.
+func (f FieldDefinition[T]) Secret() FieldDefinition[T] {
+	return f.Tag("secret", "true")
+}
+
+// Alias sets the old names of the field, as an alias tag.
+func (f FieldDefinition[T]) Alias(names ...string) FieldDefinition[T] {
+	return f.Tag("alias", strings.Join(names, ","))
+}
+
+func defineTag(t reflect.Type, field, key, value string) {
+	defer resetMetadataCaches()
+	definitions.Lock()
+	defer definitions.Unlock()
+	if definitions.tags == nil {
+		definitions.tags = map[reflect.Type]map[string][]tagEntry{}
+	}
+	fields := definitions.tags[t]
+	if fields == nil {
+		fields = map[string][]tagEntry{}
+		definitions.tags[t] = fields
+	}
+	entries := fields[field]
+	for i := range entries {
+		if entries[i].key == key {
+			entries[i].value = value
+			return
+		}
+	}
+	fields[field] = append(entries, tagEntry{key, value})
+}
+
+// resetMetadataCaches forgets the cached metadata of every type, for when
+// registered metadata changes.
+func resetMetadataCaches() {
+	for _, cache := range []*sync.Map{&metadataCache, &describeCache} {
+		cache.Range(func(key, _ any) bool {
+			cache.Delete(key)
+			return true
+		})
+	}
+	resetValidationCache()
+}
+
+// definedTag returns the tag of field sf of struct type t with the metadata
+// registered for it in code merged in. Registered default and required
+// entries go to the tags config reads them from.
+func definedTag(t reflect.Type, sf reflect.StructField, config Config) reflect.StructTag {
+	definitions.RLock()
+	entries := definitions.tags[t][sf.Name]
+	definitions.RUnlock()
+	if len(entries) == 0 {
+		return sf.Tag
+	}
+	rename := map[string]string{}
+	if config.TagCompatibility == "" {
+		rename["default"] = config.DefaultTag
+		rename["required"] = config.RequiredTag
+	}
+	merged := map[string]bool{}
+	var defined []tagEntry
+	for _, e := range entries {
+		if to, ok := rename[e.key]; ok {
+			e.key = to
+		}
+		merged[e.key] = true
+		defined = append(defined, e)
+	}
+	var parts []string
+	existing, _ := parseTag(sf.Tag)
+	for _, e := range existing {
+		if !merged[e.key] {
+			parts = append(parts, e.key+":"+strconv.Quote(e.value))
+		}
+	}
+	for _, e := range defined {
+		parts = append(parts, e.key+":"+strconv.Quote(e.value))
+	}
+	return reflect.StructTag(strings.Join(parts, " "))
+}
+
+// parseTag splits a struct tag into its entries, following the conventional
+// format that reflect.StructTag.Get reads. Malformed trailing text is
+// dropped, and reported by ok being false.
+func parseTag(tag reflect.StructTag) (entries []tagEntry, ok bool) {
+	s := string(tag)
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return entries, true
+		}
+		colon := strings.Index(s, `:"`)
+		if colon <= 0 || strings.ContainsAny(s[:colon], " \"") {
+			return entries, false
+		}
+		key := s[:colon]
+		s = s[colon+1:]
+		i := 1
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return entries, false
+		}
+		value, err := strconv.Unquote(s[:i+1])
+		if err != nil {
+			return entries, false
+		}
+		entries = append(entries, tagEntry{key, value})
+		s = s[i+1:]
+	}
+}
+
+// RegisterOverlay attaches tag metadata to the fields of struct type T,
+// typically a type from another package that configs embed directly, as if
+// each tag were written on the field named by its key:
+//
+//	optionator.RegisterOverlay[tls.Config](map[string]string{
+//		"MinVersion": `default:"771" min:"771"`,
+//	})
+//
+// Overlay tags are merged like those registered with Define, which they
+// share a registry with. It panics if T has no such field or a tag is
+// malformed.
+func RegisterOverlay[T any](tags map[string]string) {
+	d := Define[T]()
+	for name, tag := range tags {
+		f := d.Field(name)
+		entries, ok := parseTag(reflect.StructTag(tag))
+		if !ok || len(entries) == 0 {
+			panic(fmt.Sprintf("optionator: malformed overlay tag for %v.%s: %s", d.t, name, tag))
+		}
+		for _, e := range entries {
+			f.Tag(e.key, e.value)
+		}
+	}
+}
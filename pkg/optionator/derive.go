@@ -0,0 +1,118 @@
+package optionator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// Provenance values of fields of derived configurations.
+const (
+	ProvenanceInherited  = "inherited from parent"
+	ProvenanceOverridden = "overridden"
+)
+
+// lineages links derived configurations, by address, to their parents. An
+// entry is removed when its child is garbage collected.
+var lineages sync.Map // map[uintptr]lineage
+
+type lineage struct {
+	parent     any
+	config     Config
+	overridden map[string]bool
+}
+
+// Derive returns an independent deep copy of parent, a pointer to a struct,
+// with opts applied and validated, for per-connection or per-job configs
+// derived from a service config. Defaults and sources are not applied
+// again. The child remembers its parent: see ParentOf and Provenance.
+//
+// Options and validation use the Config parent was derived with, or the
+// one it is being constructed with when Derive runs inside an option;
+// otherwise, for a parent built by NewWithConfig, pass the same Config to
+// DeriveWithConfig.
+func Derive[T any](parent T, opts ...Option[T]) (T, error) {
+	config := configFor(parent)
+	if l, ok := lineageOf(parent); ok {
+		config = l.config
+	}
+	return DeriveWithConfig(parent, config, opts...)
+}
+
+// DeriveWithConfig is like Derive but applies opts and validates with
+// config, which the child keeps for further derivations.
+func DeriveWithConfig[T any](parent T, config Config, opts ...Option[T]) (T, error) {
+	v := reflect.ValueOf(parent)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		var zero T
+		return zero, errors.New("parent must be a non-nil pointer to a struct")
+	}
+	child := deepCopy(parent)
+	cv := reflect.ValueOf(child)
+	ctx := context.Background()
+	if len(opts) > 0 {
+		inFlight.Store(child, flight{config, ctx})
+		defer inFlight.Delete(child)
+	}
+	if err := finish(ctx, cv.Elem(), child, config, opts); err != nil {
+		return child, err
+	}
+	overridden := map[string]bool{}
+	for _, fi := range describeType(cv.Elem().Type(), config) {
+		was, _ := lookupIndexes(v.Elem(), fi.indexes)
+		now, _ := lookupIndexes(cv.Elem(), fi.indexes)
+		if !was.IsValid() || !now.IsValid() || !reflect.DeepEqual(was.Interface(), now.Interface()) {
+			overridden[fi.Path] = true
+		}
+	}
+	key := cv.Pointer()
+	lineages.Store(key, lineage{parent: parent, config: config, overridden: overridden})
+	runtime.SetFinalizer(child, func(any) { lineages.Delete(key) })
+	return child, nil
+}
+
+// ParentOf returns the configuration child was derived from with Derive.
+func ParentOf[T any](child T) (T, bool) {
+	if l, ok := lineageOf(child); ok {
+		parent, ok := l.parent.(T)
+		return parent, ok
+	}
+	var zero T
+	return zero, false
+}
+
+// Provenance tells where the field at a dotted path of a derived
+// configuration got its value: ProvenanceInherited if it still holds its
+// parent's value at derivation, ProvenanceOverridden if options changed it,
+// or "" if child was not derived or has no such field.
+func Provenance(child any, path string) string {
+	l, ok := lineageOf(child)
+	if !ok {
+		return ""
+	}
+	v, err := structValue(child)
+	if err != nil {
+		return ""
+	}
+	if _, ok := lookupPath(v.Type(), l.config, path); !ok {
+		return ""
+	}
+	if l.overridden[path] {
+		return ProvenanceOverridden
+	}
+	return ProvenanceInherited
+}
+
+func lineageOf(child any) (lineage, bool) {
+	v := reflect.ValueOf(child)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return lineage{}, false
+	}
+	l, ok := lineages.Load(v.Pointer())
+	if !ok {
+		return lineage{}, false
+	}
+	return l.(lineage), true
+}
@@ -0,0 +1,181 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// fieldRef matches a reference to a sibling field in a default tag, such as
+// the ${Host} in default:"http://${Host}:${Port}".
+var fieldRef = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// isDerived reports whether a default tag references other fields.
+func isDerived(def string) bool {
+	return fieldRef.MatchString(def)
+}
+
+// derivedLaterKey marks a construction whose derived defaults wait for
+// sources and options, so that ${Host} expands to the Host they set.
+type derivedLaterKey struct{}
+
+func withDerivedLater(ctx context.Context) context.Context {
+	return context.WithValue(ctx, derivedLaterKey{}, true)
+}
+
+func derivedLater(ctx context.Context) bool {
+	later, _ := ctx.Value(derivedLaterKey{}).(bool)
+	return later
+}
+
+// setDerivedRecursively sets the derived defaults of struct v and every
+// struct nested in it that are still unset once sources and options have
+// been applied.
+func setDerivedRecursively(v reflect.Value, config Config) error {
+	if config.SkipDefaults {
+		return nil
+	}
+	return eachDerived(v, config, func(v reflect.Value, metadata []fieldMetadata) error {
+		return setDerivedDefaults(v, metadata, config, false)
+	})
+}
+
+// clearDerived zeroes the fields with derived defaults of struct v and every
+// struct nested in it.
+func clearDerived(v reflect.Value, config Config) {
+	eachDerived(v, config, func(v reflect.Value, metadata []fieldMetadata) error {
+		for _, fm := range metadata {
+			if isDerived(fm.DefaultTag) {
+				field := v.FieldByIndex(fm.Index)
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+		return nil
+	})
+}
+
+// eachDerived calls fn, innermost first, on v and every struct nested in it
+// that has fields with derived defaults.
+func eachDerived(v reflect.Value, config Config, fn func(v reflect.Value, metadata []fieldMetadata) error) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	metadata := getTypeMetadata(v.Type(), config)
+	derived := false
+	for _, fm := range metadata {
+		if isNestedStruct(fm.Type) || isKindField(fm) {
+			if err := eachDerived(v.FieldByIndex(fm.Index), config, fn); err != nil {
+				return err
+			}
+		}
+		derived = derived || isDerived(fm.DefaultTag)
+	}
+	if !derived {
+		return nil
+	}
+	return fn(v, metadata)
+}
+
+// setDerivedDefaults sets the defaults of the fields of struct v that
+// reference sibling fields, after plain defaults are in place. A derived
+// default may reference another derived default, so they are evaluated in
+// dependency order; a cycle is an error, and so is exceeding the limits of
+// config. Only zero fields are set, and forced ones when force is set.
+func setDerivedDefaults(v reflect.Value, metadata []fieldMetadata, config Config, force bool) error {
+	if err := config.dynamic("derived defaults"); err != nil {
+		return err
+	}
+	limits := config.evalLimits()
+	byName := make(map[string]fieldMetadata, len(metadata))
+	for _, fm := range metadata {
+		byName[fm.Name] = fm
+	}
+	order, err := derivedOrder(metadata, byName, limits.MaxDepth)
+	if err != nil {
+		return err
+	}
+	expansions := 0
+	for _, fm := range order {
+		field := v.FieldByIndex(fm.Index)
+		if !(force && fm.ForceDefault) && !isZeroValue(field) {
+			continue
+		}
+		text := fieldRef.ReplaceAllStringFunc(fm.DefaultTag, func(ref string) string {
+			expansions++
+			return fmt.Sprint(v.FieldByIndex(byName[ref[2:len(ref)-1]].Index).Interface())
+		})
+		if expansions > limits.MaxExpansions {
+			return fmt.Errorf("derived defaults exceed the limit of %d expansions", limits.MaxExpansions)
+		}
+		if len(text) > limits.MaxOutput {
+			return fmt.Errorf("default for field %s expands beyond %d bytes", fm.Name, limits.MaxOutput)
+		}
+		if err := parseAndSetDefault(field, text, fm.Type); err != nil {
+			return fmt.Errorf("error setting default for field %s: %w", fm.Name, err)
+		}
+	}
+	return nil
+}
+
+// derivedOrder sorts the fields with derived defaults so that every field
+// comes after the derived fields it references. Chains of references longer
+// than maxDepth are an error.
+func derivedOrder(metadata []fieldMetadata, byName map[string]fieldMetadata, maxDepth int) ([]fieldMetadata, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order []fieldMetadata
+	var stack []string
+	var visit func(fm fieldMetadata) error
+	visit = func(fm fieldMetadata) error {
+		switch state[fm.Name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for stack[start] != fm.Name {
+				start++
+			}
+			cycle := append(append([]string{}, stack[start:]...), fm.Name)
+			return fmt.Errorf("default dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[fm.Name] = visiting
+		stack = append(stack, fm.Name)
+		if len(stack) > maxDepth {
+			return fmt.Errorf("default for field %s references a chain of more than %d derived defaults", stack[0], maxDepth)
+		}
+		for _, m := range fieldRef.FindAllStringSubmatch(fm.DefaultTag, -1) {
+			dep, ok := byName[m[1]]
+			if !ok {
+				return fmt.Errorf("default for field %s references unknown field %s", fm.Name, m[1])
+			}
+			if isDerived(dep.DefaultTag) {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[fm.Name] = done
+		order = append(order, fm)
+		return nil
+	}
+	for _, fm := range metadata {
+		if isDerived(fm.DefaultTag) {
+			if err := visit(fm); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,197 @@
+package optionator
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// FieldInfo describes a configurable leaf field of a struct.
+type FieldInfo struct {
+	// Path is the dotted path from the root struct, e.g. "Nested.Port".
+	Path     string
+	Name     string
+	Type     reflect.Type
+	Default  string
+	Required bool
+	// Flag is the feature flag named by the field's flag tag.
+	Flag string
+	// Secret is set by `secret:"true"`; the value is redacted in reports.
+	// `secret:"last4"` and `secret:"hash"` mark it secret too but show a
+	// hint of the value instead, as Mask says.
+	Secret bool
+	// Mask is SecretLast4 or SecretHash for partially shown secrets.
+	Mask string
+	// OnSet names the method called with the old and new value when the
+	// field changes through options or a Live reload.
+	OnSet string
+	// Description comes from the desc tag.
+	Description string
+	// Example is a sample value from the example tag.
+	Example string
+	// Docs is a link to further documentation from the docs tag.
+	Docs string
+	// Group is the section the field is documented under: its group tag,
+	// else the group tag of the nested struct holding it, else the path of
+	// that struct's top-level field. Top-level fields default to no group.
+	Group string
+	// Hidden is set by `hidden:"true"` on the field or a struct holding it.
+	// Hidden fields can still be set but are left out of flags, completions,
+	// samples, generated docs and debug bundles.
+	Hidden bool
+	// Stability is the level from the stability tag of the field or the
+	// nested struct holding it, such as StabilityExperimental.
+	Stability string
+
+	indexes [][]int
+	tag     reflect.StructTag
+}
+
+// Describe returns the metadata of every leaf field of T, which may be a
+// struct or a pointer to a struct. Nested structs are flattened into paths.
+func Describe[T any]() ([]FieldInfo, error) {
+	return DescribeWithConfig[T](defaultConfig)
+}
+
+// DescribeWithConfig is like Describe but reads tags according to config.
+func DescribeWithConfig[T any](config Config) ([]FieldInfo, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("type must be a struct or a pointer to a struct")
+	}
+	return append([]FieldInfo(nil), describeType(t, config)...), nil
+}
+
+// DescribeValue is like Describe for the type of target, for callers that
+// hold configurations of types they do not know statically.
+func DescribeValue(target any) ([]FieldInfo, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return nil, err
+	}
+	return append([]FieldInfo(nil), describeType(v.Type(), defaultConfig)...), nil
+}
+
+var describeCache sync.Map // map[metadataKey]*describedType
+
+// describedType is the flattened metadata of a struct type, with the
+// position of each field by path.
+type describedType struct {
+	fields []FieldInfo
+	paths  map[string]int
+}
+
+// describeType flattens the metadata of struct type t. The result is cached
+// and shared, so callers must not modify it.
+func describeType(t reflect.Type, config Config) []FieldInfo {
+	return describeCached(t, config).fields
+}
+
+func describeCached(t reflect.Type, config Config) *describedType {
+	key := metadataKey{
+		Type:             t,
+		DefaultTag:       config.DefaultTag,
+		RequiredTag:      config.RequiredTag,
+		TagCompatibility: config.TagCompatibility,
+	}
+	if cached, ok := describeCache.Load(key); ok {
+		return cached.(*describedType)
+	}
+	fields := describeNested(t, config, "", FieldInfo{}, nil, map[reflect.Type]bool{})
+	described := &describedType{fields: fields, paths: make(map[string]int, len(fields))}
+	for i, fi := range fields {
+		described.paths[fi.Path] = i
+	}
+	describeCache.Store(key, described)
+	return described
+}
+
+// describeNested flattens the fields of t, a struct held by the field
+// described by parent, whose group, hidden flag and stability its fields
+// inherit. Types already being described higher up the path are skipped to
+// avoid infinite recursion.
+func describeNested(t reflect.Type, config Config, prefix string, parent FieldInfo, indexes [][]int, visiting map[reflect.Type]bool) []FieldInfo {
+	visiting[t] = true
+	defer delete(visiting, t)
+	var fields []FieldInfo
+	for _, fm := range getTypeMetadata(t, config) {
+		path := fm.Name
+		if prefix != "" {
+			path = prefix + "." + fm.Name
+		}
+		idx := append(append([][]int{}, indexes...), fm.Index)
+		group := parent.Group
+		if fm.Group != "" {
+			group = fm.Group
+		}
+		hidden := parent.Hidden || fm.Hidden
+		stability := parent.Stability
+		if fm.Stability != "" {
+			stability = fm.Stability
+		}
+		if isNestedStruct(fm.Type) {
+			if group == "" {
+				group = path
+			}
+			nested := fm.Type
+			if nested.Kind() == reflect.Ptr {
+				nested = nested.Elem()
+			}
+			if !visiting[nested] {
+				fields = append(fields, describeNested(nested, config, path, FieldInfo{Group: group, Hidden: hidden, Stability: stability}, idx, visiting)...)
+			}
+			continue
+		}
+		fields = append(fields, FieldInfo{
+			Path:        path,
+			Name:        fm.Name,
+			Type:        fm.Type,
+			Default:     fm.DefaultTag,
+			Required:    fm.Required,
+			Flag:        fm.Flag,
+			Secret:      fm.Secret,
+			Mask:        fm.Mask,
+			OnSet:       fm.OnSet,
+			Description: fm.Description,
+			Example:     fm.Example,
+			Docs:        fm.Docs,
+			Group:       group,
+			Hidden:      hidden,
+			Stability:   stability,
+			indexes:     idx,
+			tag:         fm.Tag,
+		})
+	}
+	return fields
+}
+
+// FieldGroup is a named section of fields, as returned by GroupFields.
+type FieldGroup struct {
+	Name   string
+	Fields []FieldInfo
+}
+
+// Tag returns the struct tag of the field, including metadata registered
+// with Define or RegisterOverlay, for generators reading tags such as oneof
+// or min.
+func (fi FieldInfo) Tag() reflect.StructTag { return fi.tag }
+
+// GroupFields splits fields by Group, keeping the order in which groups and
+// fields first appear. Ungrouped fields form a group with an empty name.
+func GroupFields(fields []FieldInfo) []FieldGroup {
+	var groups []FieldGroup
+	at := map[string]int{}
+	for _, fi := range fields {
+		i, ok := at[fi.Group]
+		if !ok {
+			i = len(groups)
+			at[fi.Group] = i
+			groups = append(groups, FieldGroup{Name: fi.Group})
+		}
+		groups[i].Fields = append(groups[i].Fields, fi)
+	}
+	return groups
+}
@@ -0,0 +1,9 @@
+// Package optionator builds configuration structs from struct-tag defaults,
+// sources and functional options, then validates them.
+//
+// Construction is safe for concurrent use: New and its variants, With and
+// the other options, Describe, and the registries behind RegisterSchema,
+// RegisterDynamicSchema, RegisterFormat and RegisterRule may all be called
+// from multiple goroutines, as long as each construction has its own target.
+// Cached field metadata is shared and never modified after it is built.
+package optionator
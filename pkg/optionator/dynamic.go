@@ -0,0 +1,101 @@
+package optionator
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DynamicField describes one key of a dynamic configuration map.
+type DynamicField struct {
+	Name     string
+	Type     reflect.Type
+	Default  string
+	Required bool
+}
+
+// DynamicSchema describes a configuration whose shape is only known at run
+// time, such as a plugin's settings.
+type DynamicSchema struct {
+	Fields []DynamicField
+}
+
+var dynamicSchemas sync.Map // map[string]DynamicSchema
+
+// RegisterDynamicSchema registers schema under name for NewDynamic.
+func RegisterDynamicSchema(name string, schema DynamicSchema) {
+	dynamicSchemas.Store(name, schema)
+}
+
+// NewDynamic applies the schema registered under name to target: missing
+// keys get their defaults, present values are converted to the declared
+// types like source values, options are applied, and required keys are
+// checked. Keys not in the schema are left alone. A nil target is allocated.
+func NewDynamic(name string, target map[string]any, opts ...Option[map[string]any]) (map[string]any, error) {
+	schema, ok := dynamicSchemas.Load(name)
+	if !ok {
+		return target, fmt.Errorf("no dynamic schema registered as %q", name)
+	}
+	return NewDynamicWithSchema(schema.(DynamicSchema), target, opts...)
+}
+
+// NewDynamicWithSchema is like NewDynamic with an unregistered schema.
+func NewDynamicWithSchema(schema DynamicSchema, target map[string]any, opts ...Option[map[string]any]) (map[string]any, error) {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for _, f := range schema.Fields {
+		if value, ok := target[f.Name]; (ok && value != nil) || f.Default == "" {
+			continue
+		}
+		v := reflect.New(f.Type).Elem()
+		if err := parseAndSetDefault(v, f.Default, f.Type); err != nil {
+			return target, fmt.Errorf("error setting default for field %s: %w", f.Name, err)
+		}
+		target[f.Name] = v.Interface()
+	}
+	if err := coerceDynamic(schema, target); err != nil {
+		return target, err
+	}
+	for _, opt := range opts {
+		if err := opt(target); err != nil {
+			return target, err
+		}
+	}
+	// Options may set values of any type; normalize them again.
+	if err := coerceDynamic(schema, target); err != nil {
+		return target, err
+	}
+	for _, f := range schema.Fields {
+		value, ok := target[f.Name]
+		if f.Required && (!ok || value == nil || isZeroValue(reflect.ValueOf(value))) {
+			return target, fmt.Errorf("required field %s is zero", f.Name)
+		}
+	}
+	return target, nil
+}
+
+// WithEntry returns an Option that sets key in a dynamic configuration map.
+func WithEntry(key string, value any) Option[map[string]any] {
+	return func(target map[string]any) error {
+		target[key] = value
+		return nil
+	}
+}
+
+// coerceDynamic converts every present value to its declared type.
+func coerceDynamic(schema DynamicSchema, target map[string]any) error {
+	b := binder{config: defaultConfig}
+	for _, f := range schema.Fields {
+		value, ok := target[f.Name]
+		if !ok || value == nil {
+			continue
+		}
+		v := reflect.New(f.Type).Elem()
+		if err := b.assign(v, value, f.Name); err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		target[f.Name] = v.Interface()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package optionator
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// EnvSource is a Source that reads environment variables starting with
+// Prefix. The rest of a variable's name is its key, with a double
+// underscore separating nested structs, so APP_DB__MAX_CONNS sets
+// DB.MaxConns under Prefix "APP_" and SnakeCase naming. Values are text and
+// converted leniently.
+//
+// During construction the source looks up the variable named by
+// NamingStrategy.EnvName for each field, through Config.LookupEnv, or under
+// Config.TagCompatibility the one its envconfig or env tag names. Loaded
+// on its own, it lists the process environment instead.
+type EnvSource struct {
+	Prefix string
+}
+
+func (s EnvSource) Name() string { return "env:" + s.Prefix }
+
+func (EnvSource) Kind() SourceKind { return FromEnv }
+
+func (EnvSource) WeaklyTyped() bool { return true }
+
+func (s EnvSource) Load(ctx context.Context) (map[string]any, error) {
+	values := map[string]any{}
+	if t, ok := targetTypeFrom(ctx); ok {
+		config := ConfigFromContext(ctx)
+		for _, fi := range describeType(t, config) {
+			names := config.compatEnvNames(s.Prefix, t, fi)
+			if names == nil {
+				names = []string{config.NamingStrategy.EnvName(s.Prefix, fi.Path)}
+			}
+			for _, name := range names {
+				if value, ok := config.lookupEnv(name); ok {
+					setEnvValue(values, strings.Split(fi.Path, "."), value)
+					break
+				}
+			}
+		}
+		return values, nil
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, s.Prefix) && name != s.Prefix {
+			setEnvValue(values, strings.Split(strings.TrimPrefix(name, s.Prefix), "__"), value)
+		}
+	}
+	return values, nil
+}
+
+// compatEnvNames returns the variables the field fi of struct type t is
+// read from under the tag compatibility mode of c, in order of precedence,
+// or nil if the field's tags do not name one. Under CompatEnvconfig an
+// envconfig tag names the variable, after the prefix and the keys of the
+// structs holding the field, joined by underscores and upper-cased, with the
+// bare tag as a fallback. Under CompatCaarlos0 the env tag names it, after
+// the prefix and the envPrefix tags of the structs holding the field.
+func (c Config) compatEnvNames(prefix string, t reflect.Type, fi FieldInfo) []string {
+	if c.TagCompatibility == "" {
+		return nil
+	}
+	var path []reflect.StructField
+	for _, index := range fi.indexes {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		sf := t.FieldByIndex(index)
+		path = append(path, sf)
+		t = sf.Type
+	}
+	leaf, parents := path[len(path)-1], path[:len(path)-1]
+	switch c.TagCompatibility {
+	case CompatEnvconfig:
+		name := leaf.Tag.Get("envconfig")
+		if name == "" {
+			return nil
+		}
+		var keys []string
+		for _, sf := range parents {
+			key := sf.Tag.Get("envconfig")
+			if key == "" {
+				key = sf.Name
+			}
+			keys = append(keys, key)
+		}
+		full := strings.ToUpper(prefix + strings.Join(append(keys, name), "_"))
+		return []string{full, strings.ToUpper(name)}
+	case CompatCaarlos0:
+		name, _, _ := strings.Cut(leaf.Tag.Get("env"), ",")
+		if name == "" || name == "-" {
+			return nil
+		}
+		var b strings.Builder
+		b.WriteString(prefix)
+		for _, sf := range parents {
+			b.WriteString(sf.Tag.Get("envPrefix"))
+		}
+		return []string{b.String() + name}
+	}
+	return nil
+}
+
+// setEnvValue sets the key path of the tree of maps values to value,
+// unless a nested map already holds it.
+func setEnvValue(values map[string]any, path []string, value string) {
+	node := values
+	for _, p := range path[:len(path)-1] {
+		child, ok := node[p].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			node[p] = child
+		}
+		node = child
+	}
+	if _, nested := node[path[len(path)-1]].(map[string]any); !nested {
+		node[path[len(path)-1]] = value
+	}
+}
@@ -0,0 +1,97 @@
+package optionator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a validation failure of one field. It marshals to JSON as
+// {"path", "code", "message"}, so HTTP APIs can return it as is.
+type FieldError struct {
+	// Path is the dotted path of the field.
+	Path string `json:"path"`
+	// Code classifies the failure: "required" or "nonempty" for missing
+	// values, the failing tag such as "min", "oneof" or "cel", "policy"
+	// for registered rules, or "nil" for a nil struct pointer.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	err     error
+}
+
+func (e *FieldError) Error() string { return e.Message }
+
+func (e *FieldError) Unwrap() error { return e.err }
+
+func fieldError(path, code string, err error) *FieldError {
+	return &FieldError{Path: path, Code: code, Message: err.Error(), err: err}
+}
+
+// tagError is the failure of a tag check, remembering the tag.
+type tagError struct {
+	tag string
+	err error
+}
+
+func (e *tagError) Error() string { return e.err.Error() }
+
+func (e *tagError) Unwrap() error { return e.err }
+
+// ErrorGroup holds every validation failure of a configuration, in field
+// order. It unwraps to its FieldErrors for errors.Is and errors.As, and
+// marshals to JSON as an array of them.
+type ErrorGroup []*FieldError
+
+func (g ErrorGroup) Error() string {
+	if len(g) == 1 {
+		return g[0].Error()
+	}
+	msgs := make([]string, len(g))
+	for i, e := range g {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(g), strings.Join(msgs, "; "))
+}
+
+// clone copies the group and each of its FieldErrors.
+func (g ErrorGroup) clone() ErrorGroup {
+	if g == nil {
+		return nil
+	}
+	c := make(ErrorGroup, len(g))
+	for i, e := range g {
+		copied := *e
+		c[i] = &copied
+	}
+	return c
+}
+
+// Unwrap returns the errors of the group.
+func (g ErrorGroup) Unwrap() []error {
+	errs := make([]error, len(g))
+	for i, e := range g {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Validate checks the required, tag, cel and error rule constraints of
+// target, a struct or a pointer to one, without applying defaults or
+// options. Like construction, it returns nil or an ErrorGroup of every
+// failure.
+func Validate(target any) error {
+	return ValidateWithConfig(target, defaultConfig)
+}
+
+// ValidateWithConfig is like Validate but reads tags according to config.
+func ValidateWithConfig(target any, config Config) error {
+	v, err := structValue(target)
+	if err != nil {
+		return err
+	}
+	return validate(v, config)
+}
+
+// policyError is the FieldError of a rule finding.
+func policyError(f Finding) *FieldError {
+	return fieldError(f.Path, "policy", fmt.Errorf("policy violation: %s: %s", f.Path, f.Message))
+}
@@ -0,0 +1,341 @@
+package optionator
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ExportOptions controls how the Write functions and ToMap serialize a
+// configuration.
+type ExportOptions struct {
+	// NonDefault writes only the fields whose value differs from their
+	// default, or from the zero value when they have none, which suits
+	// snapshots meant to be loaded over the defaults again.
+	NonDefault bool
+	// SecretRef, if set, is written in place of each secret field's value,
+	// for example a reference such as "vault:secret/db#password". Secrets
+	// are written as Redacted, or masked as their secret tag asks, otherwise.
+	SecretRef func(path string) string
+	// Naming, if set, renames the keys written, as Config.NamingStrategy
+	// does for the keys sources accept.
+	Naming NamingStrategy
+
+	// sample writes every value as is, with notes describing each field.
+	sample bool
+}
+
+// WriteJSON writes the effective configuration of target, a pointer to a
+// struct, as an indented JSON object that a FileSource can load back.
+func WriteJSON(w io.Writer, target any, opts ExportOptions) error {
+	root, err := exportTree(target, opts)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	writeJSONNode(&b, root, "")
+	b.WriteString("\n")
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// ToMap returns the effective configuration of target, a pointer to a
+// struct, as the tree of maps a MapSource loads back: nested structs are
+// nested maps and values are plain Go values.
+func ToMap(target any, opts ExportOptions) (map[string]any, error) {
+	root, err := exportTree(target, opts)
+	if err != nil {
+		return nil, err
+	}
+	return root.toMap(), nil
+}
+
+func (n *exportNode) toMap() map[string]any {
+	m := make(map[string]any, len(n.keys))
+	for _, k := range n.keys {
+		if c, ok := n.values[k].(*exportNode); ok {
+			m[k] = c.toMap()
+		} else {
+			m[k] = n.values[k]
+		}
+	}
+	return m
+}
+
+// WriteYAML is like WriteJSON but writes YAML, with one mapping per nested
+// struct. Slices and maps are written in flow style.
+func WriteYAML(w io.Writer, target any, opts ExportOptions) error {
+	root, err := exportTree(target, opts)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	writeYAMLNode(&b, root, "")
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// WriteTOML is like WriteJSON but writes TOML, with one table per nested
+// struct. Nil values are left out since TOML has no null.
+func WriteTOML(w io.Writer, target any, opts ExportOptions) error {
+	root, err := exportTree(target, opts)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	writeTOMLNode(&b, root, "")
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// exportNode is a nested struct in field order.
+type exportNode struct {
+	keys   []string
+	values map[string]any // plain values or *exportNode
+	// notes are written as comments above keys, in YAML and TOML.
+	notes map[string][]string
+}
+
+func (n *exportNode) child(key string) *exportNode {
+	if c, ok := n.values[key].(*exportNode); ok {
+		return c
+	}
+	c := &exportNode{values: map[string]any{}, notes: map[string][]string{}}
+	n.keys = append(n.keys, key)
+	n.values[key] = c
+	return c
+}
+
+func (n *exportNode) set(key string, value any) {
+	n.keys = append(n.keys, key)
+	n.values[key] = value
+}
+
+// exportTree collects the values to export from target.
+func exportTree(target any, opts ExportOptions) (*exportNode, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return nil, err
+	}
+	root := &exportNode{values: map[string]any{}, notes: map[string][]string{}}
+	for _, fi := range describeType(v.Type(), defaultConfig) {
+		field, ok := lookupIndexes(v, fi.indexes)
+		if !ok {
+			continue
+		}
+		if opts.NonDefault && isDefault(fi, field) {
+			continue
+		}
+		if opts.sample && fi.Hidden {
+			continue
+		}
+		var value any
+		switch {
+		case opts.sample:
+			value = plainValue(field)
+		case fi.Secret && opts.SecretRef != nil:
+			value = opts.SecretRef(fi.Path)
+		case fi.Secret:
+			value = maskSecret(fi.Mask, plainValue(field))
+		default:
+			value = plainValue(field)
+		}
+		parts := strings.Split(opts.Naming.Key(fi.Path), ".")
+		node := root
+		for _, p := range parts[:len(parts)-1] {
+			node = node.child(p)
+		}
+		node.set(parts[len(parts)-1], value)
+		if opts.sample {
+			node.notes[parts[len(parts)-1]] = sampleNotes(fi)
+		}
+	}
+	return root, nil
+}
+
+// isDefault reports whether field holds the default of fi.
+func isDefault(fi FieldInfo, field reflect.Value) bool {
+	def := reflect.New(fi.Type).Elem()
+	if fi.Default != "" && !isDerived(fi.Default) {
+		if err := parseAndSetDefault(def, fi.Default, fi.Type); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(field.Interface(), def.Interface())
+}
+
+// plainValue converts v to the values a source would produce for it, so the
+// export loads back: durations, registered functions and types implementing
+// encoding.TextMarshaler as text, slices as []any and maps as
+// map[string]any.
+func plainValue(v reflect.Value) any {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return fmt.Sprint(v.Interface())
+	}
+	if isTextType(reflect.PtrTo(v.Type())) {
+		return formatTextType(v.Addr())
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Func:
+		name, _ := funcName(v)
+		if name == "" {
+			return nil
+		}
+		return name
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = plainValue(v.Index(i))
+		}
+		return list
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = plainValue(iter.Value())
+		}
+		return m
+	case reflect.Struct:
+		m := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				m[v.Type().Field(i).Name] = plainValue(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Chan, reflect.UnsafePointer:
+		return nil
+	}
+	return v.Interface()
+}
+
+// jsonText encodes a plain value as compact JSON, which is also valid YAML
+// flow syntax.
+func jsonText(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+func writeJSONNode(b *bytes.Buffer, n *exportNode, indent string) {
+	if len(n.keys) == 0 {
+		b.WriteString("{}")
+		return
+	}
+	b.WriteString("{\n")
+	for i, k := range n.keys {
+		b.WriteString(indent + "  " + jsonText(k) + ": ")
+		if c, ok := n.values[k].(*exportNode); ok {
+			writeJSONNode(b, c, indent+"  ")
+		} else {
+			b.WriteString(jsonText(n.values[k]))
+		}
+		if i < len(n.keys)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+}
+
+func writeYAMLNode(b *bytes.Buffer, n *exportNode, indent string) {
+	for _, k := range n.keys {
+		for _, note := range n.notes[k] {
+			fmt.Fprintf(b, "%s# %s\n", indent, note)
+		}
+		c, ok := n.values[k].(*exportNode)
+		switch {
+		case ok && len(c.keys) == 0:
+			fmt.Fprintf(b, "%s%s: {}\n", indent, k)
+		case ok:
+			fmt.Fprintf(b, "%s%s:\n", indent, k)
+			writeYAMLNode(b, c, indent+"  ")
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", indent, k, jsonText(n.values[k]))
+		}
+	}
+}
+
+func writeTOMLNode(b *bytes.Buffer, n *exportNode, table string) {
+	var tables []string
+	for _, k := range n.keys {
+		if _, ok := n.values[k].(*exportNode); ok {
+			tables = append(tables, k)
+			continue
+		}
+		text, ok := tomlValue(n.values[k])
+		for _, note := range n.notes[k] {
+			fmt.Fprintf(b, "# %s\n", note)
+		}
+		if ok {
+			fmt.Fprintf(b, "%s = %s\n", k, text)
+		} else if len(n.notes[k]) > 0 {
+			fmt.Fprintf(b, "# %s =\n", k)
+		}
+	}
+	for _, k := range tables {
+		name := k
+		if table != "" {
+			name = table + "." + k
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "[%s]\n", name)
+		writeTOMLNode(b, n.values[k].(*exportNode), name)
+	}
+}
+
+// tomlValue renders a plain value as TOML, reporting false for nil.
+func tomlValue(value any) (string, bool) {
+	switch value := value.(type) {
+	case nil:
+		return "", false
+	case []any:
+		parts := make([]string, 0, len(value))
+		for _, e := range value {
+			if text, ok := tomlValue(e); ok {
+				parts = append(parts, text)
+			}
+		}
+		return "[" + strings.Join(parts, ", ") + "]", true
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if text, ok := tomlValue(value[k]); ok {
+				parts = append(parts, jsonText(k)+" = "+text)
+			}
+		}
+		return "{" + strings.Join(parts, ", ") + "}", true
+	}
+	return jsonText(value), true
+}
@@ -0,0 +1,79 @@
+package optionator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// FlagProvider resolves feature flags. Its methods follow the OpenFeature
+// client API, minus the evaluation context, so an OpenFeature client can be
+// adapted with a thin wrapper. Implementations return defaultValue together
+// with an error when a flag cannot be resolved.
+type FlagProvider interface {
+	BooleanValue(ctx context.Context, flag string, defaultValue bool) (bool, error)
+	IntValue(ctx context.Context, flag string, defaultValue int64) (int64, error)
+	FloatValue(ctx context.Context, flag string, defaultValue float64) (float64, error)
+	StringValue(ctx context.Context, flag string, defaultValue string) (string, error)
+}
+
+// FlagSource returns a Source that resolves every field of T tagged with
+// `flag:"name"` through provider. Flags that fail to resolve are skipped so
+// the field keeps its default. Combined with Live, flag changes take effect
+// on the next Reload.
+func FlagSource[T any](provider FlagProvider) Source {
+	return flagSource[T]{provider}
+}
+
+type flagSource[T any] struct {
+	provider FlagProvider
+}
+
+func (s flagSource[T]) Name() string { return "featureflag" }
+
+func (flagSource[T]) Kind() SourceKind { return FromFeatureFlag }
+
+func (s flagSource[T]) Load(ctx context.Context) (map[string]any, error) {
+	fields, err := DescribeWithConfig[T](ConfigFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	for _, fi := range fields {
+		if fi.Flag == "" {
+			continue
+		}
+		var value any
+		switch kind := fi.Type.Kind(); {
+		case kind == reflect.Bool:
+			value, err = s.provider.BooleanValue(ctx, fi.Flag, false)
+		case kind == reflect.String:
+			value, err = s.provider.StringValue(ctx, fi.Flag, "")
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			value, err = s.provider.FloatValue(ctx, fi.Flag, 0)
+		case isNumber(kind):
+			value, err = s.provider.IntValue(ctx, fi.Flag, 0)
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		setPath(values, fi.Path, value)
+	}
+	return values, nil
+}
+
+// setPath stores value in a tree of maps at a dotted path.
+func setPath(m map[string]any, path string, value any) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+}
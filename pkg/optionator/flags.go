@@ -0,0 +1,235 @@
+package optionator
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// flagValue holds the raw text of a flag and whether it was set explicitly.
+type flagValue struct {
+	text   string
+	isBool bool
+	set    bool
+}
+
+func (f *flagValue) String() string { return f.text }
+
+func (f *flagValue) Set(s string) error {
+	f.text = s
+	f.set = true
+	return nil
+}
+
+// flagUsage is the usage text of a field's flag: its description, or its
+// path if it has none, followed by its type, example, stability and docs
+// link.
+func flagUsage(fi FieldInfo) string {
+	usage := fi.Description
+	if usage == "" {
+		usage = fi.Path
+	}
+	usage += fmt.Sprintf(" (%v)", fi.Type)
+	if fi.Example != "" {
+		usage += ", e.g. " + fi.Example
+	}
+	if fi.Required {
+		usage += " [required]"
+	}
+	if fi.Stability != "" && fi.Stability != StabilityStable {
+		usage += " [" + fi.Stability + "]"
+	}
+	if fi.Docs != "" {
+		usage += "; see " + fi.Docs
+	}
+	return usage
+}
+
+// IsBoolFlag lets boolean fields be passed as -name without a value.
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
+
+// BindFlags registers a flag on fs for every field of T that can be parsed
+// from text and is not hidden, named by FlagName and showing the field's default. The returned
+// Option applies only the flags set explicitly on the command line, so pass
+// it to New after fs.Parse; defaults and required validation then apply as
+// usual. Adapters for other CLI libraries are built the same way from
+// DescribeFlags and WithFlags.
+func BindFlags[T any](fs *flag.FlagSet) (Option[T], error) {
+	return BindFlagsWithConfig[T](fs, defaultConfig)
+}
+
+// BindFlagsWithConfig is like BindFlags but reads tags according to config
+// and names flags by its NamingStrategy, if set.
+func BindFlagsWithConfig[T any](fs *flag.FlagSet, config Config) (Option[T], error) {
+	specs, err := DescribeFlags[T](config)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]*flagValue, len(specs))
+	for i, spec := range specs {
+		values[i] = &flagValue{text: spec.Default, isBool: spec.Bool}
+		fs.Var(values[i], spec.Name, spec.Usage)
+	}
+	return func(target T) error {
+		if !fs.Parsed() {
+			return errors.New("flags must be parsed before applying them")
+		}
+		set := map[string]string{}
+		for i, spec := range specs {
+			if values[i].set {
+				set[spec.Name] = values[i].text
+			}
+		}
+		return WithFlags[T](config, set)(target)
+	}, nil
+}
+
+// FlagSpec is the command-line flag of a field, for adapters that register
+// flags with CLI libraries.
+type FlagSpec struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Name is the flag name: FlagName of the path, or its key under the
+	// NamingStrategy of the Config.
+	Name string
+	// Usage is the help text: the description or path, the type, example,
+	// required, stability and docs link.
+	Usage string
+	// Default is the default tag of the field, for display.
+	Default string
+	// Bool is set for boolean fields, whose flags take no value.
+	Bool bool
+	from []string
+}
+
+// DescribeFlags returns the flag of every field of T that can be parsed from
+// text and is not hidden, in field order.
+func DescribeFlags[T any](config Config) ([]FlagSpec, error) {
+	fields, err := DescribeWithConfig[T](config)
+	if err != nil {
+		return nil, err
+	}
+	var specs []FlagSpec
+	for _, fi := range fields {
+		if fi.Hidden || !isParsable(fi.Type) {
+			continue
+		}
+		specs = append(specs, FlagSpec{
+			Path:    fi.Path,
+			Name:    config.flagName(fi.Path),
+			Usage:   flagUsage(fi),
+			Default: fi.Default,
+			Bool:    fi.Type.Kind() == reflect.Bool,
+			from:    tagList(fi.tag.Get("from")),
+		})
+	}
+	return specs, nil
+}
+
+// WithFlags returns an Option applying the text of the flags set explicitly
+// on a command line, keyed by flag name as DescribeFlags with config names
+// them, in field order. Fields whose from tag excludes flags are refused.
+func WithFlags[T any](config Config, set map[string]string) Option[T] {
+	return func(target T) error {
+		specs, err := DescribeFlags[T](config)
+		if err != nil {
+			return err
+		}
+		for name := range set {
+			if !hasFlag(specs, name) {
+				return fmt.Errorf("flag %s: no such field", name)
+			}
+		}
+		for _, spec := range specs {
+			text, ok := set[spec.Name]
+			if !ok {
+				continue
+			}
+			if !allowsKind(spec.from, FromFlag) {
+				return fmt.Errorf("flag %s: field %s may only be set from %s", spec.Name, spec.Path, strings.Join(spec.from, ", "))
+			}
+			if err := WithText[T](spec.Path, text)(target); err != nil {
+				return fmt.Errorf("flag %s: %w", spec.Name, err)
+			}
+		}
+		return nil
+	}
+}
+
+func hasFlag(specs []FlagSpec, name string) bool {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupedUsage returns a usage function for fs, suitable for fs.Usage, that
+// prints the flags bound by BindFlags under a heading per field group, in
+// field order, and any other flags of fs last.
+func GroupedUsage[T any](fs *flag.FlagSet) func() {
+	return GroupedUsageWithConfig[T](fs, defaultConfig)
+}
+
+// GroupedUsageWithConfig is like GroupedUsage for flags bound with
+// BindFlagsWithConfig, whose names follow config.NamingStrategy.
+func GroupedUsageWithConfig[T any](fs *flag.FlagSet, config Config) func() {
+	return func() {
+		w := fs.Output()
+		if fs.Name() == "" {
+			fmt.Fprintf(w, "Usage:\n")
+		} else {
+			fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+		}
+		fields, _ := DescribeWithConfig[T](config)
+		printed := map[string]bool{}
+		for _, g := range GroupFields(fields) {
+			heading := false
+			for _, fi := range g.Fields {
+				f := fs.Lookup(config.flagName(fi.Path))
+				if f == nil {
+					continue
+				}
+				if !heading && g.Name != "" {
+					fmt.Fprintf(w, "\n%s:\n", g.Name)
+				}
+				heading = true
+				printFlag(fs, f)
+				printed[f.Name] = true
+			}
+		}
+		other := false
+		fs.VisitAll(func(f *flag.Flag) {
+			if printed[f.Name] {
+				return
+			}
+			if !other && len(printed) > 0 {
+				fmt.Fprintf(w, "\nOther:\n")
+			}
+			other = true
+			printFlag(fs, f)
+		})
+	}
+}
+
+// printFlag prints f in the format of flag.PrintDefaults.
+func printFlag(fs *flag.FlagSet, f *flag.Flag) {
+	name, usage := flag.UnquoteUsage(f)
+	line := "  -" + f.Name
+	if name != "" {
+		line += " " + name
+	}
+	if len(line) <= 4 {
+		line += "\t"
+	} else {
+		line += "\n    \t"
+	}
+	line += strings.ReplaceAll(usage, "\n", "\n    \t")
+	if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+		line += fmt.Sprintf(" (default %q)", f.DefValue)
+	}
+	fmt.Fprintln(fs.Output(), line)
+}
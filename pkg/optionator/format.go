@@ -0,0 +1,76 @@
+package optionator
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var formats sync.Map // map[string]func(string) error
+
+func init() {
+	RegisterFormat("email", checkEmail)
+	RegisterFormat("hostname", checkHostname)
+	RegisterFormat("semver", checkSemver)
+}
+
+// RegisterFormat makes check available to fields tagged format:"<name>". It
+// replaces any format already registered under name, including the built-in
+// email, hostname and semver.
+func RegisterFormat(name string, check func(value string) error) {
+	defer resetValidationCache()
+	formats.Store(name, check)
+}
+
+// checkFormat validates a string field against a registered format.
+func checkFormat(field reflect.Value, arg string) error {
+	if field.Kind() != reflect.String {
+		return fmt.Errorf("format tag on non-string type %v", field.Type())
+	}
+	check, ok := formats.Load(arg)
+	if !ok {
+		return fmt.Errorf("unknown format %q", arg)
+	}
+	return check.(func(string) error)(field.String())
+}
+
+// checkEmail accepts a bare address such as ops@example.com, without a
+// display name.
+func checkEmail(s string) error {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return fmt.Errorf("invalid email address %q", s)
+	}
+	return nil
+}
+
+var hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// checkHostname accepts RFC 1123 host names.
+func checkHostname(s string) error {
+	if len(s) > 253 {
+		return fmt.Errorf("hostname %q is longer than 253 characters", s)
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(s, "."), ".") {
+		if len(label) > 63 || !hostnameLabel.MatchString(label) {
+			return fmt.Errorf("invalid hostname %q", s)
+		}
+	}
+	return nil
+}
+
+// semverPattern is the pattern recommended by semver.org, allowing a leading v.
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// checkSemver accepts semantic versions such as 1.4.0 or v2.0.0-rc.1.
+func checkSemver(s string) error {
+	if !semverPattern.MatchString(s) {
+		return fmt.Errorf("invalid semantic version %q", s)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package optionator
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var funcs sync.Map // map[funcKey]reflect.Value
+
+type funcKey struct {
+	Type reflect.Type
+	Name string
+}
+
+// RegisterFunc names fn so that fields of type F can select it by name in a
+// default tag or a source, as in
+//
+//	Backoff func(int) time.Duration `default:"exponential"`
+//
+// Names are scoped to the function type. RegisterFunc panics if F is not a
+// function type.
+func RegisterFunc[F any](name string, fn F) {
+	v := reflect.ValueOf(&fn).Elem()
+	if v.Kind() != reflect.Func {
+		panic(fmt.Sprintf("optionator: RegisterFunc of non-function type %v", v.Type()))
+	}
+	funcs.Store(funcKey{v.Type(), name}, v)
+}
+
+// setFunc sets a function field to the function registered under name for
+// its type.
+func setFunc(field reflect.Value, name string) error {
+	fn, ok := funcs.Load(funcKey{field.Type(), name})
+	if !ok {
+		return fmt.Errorf("no function %q registered for %v", name, field.Type())
+	}
+	field.Set(fn.(reflect.Value))
+	return nil
+}
+
+// funcName returns the name fn was registered under, if any.
+func funcName(fn reflect.Value) (name string, ok bool) {
+	if fn.IsNil() {
+		return "", false
+	}
+	funcs.Range(func(key, value any) bool {
+		k, v := key.(funcKey), value.(reflect.Value)
+		if k.Type == fn.Type() && v.Pointer() == fn.Pointer() {
+			name, ok = k.Name, true
+			return false
+		}
+		return true
+	})
+	return name, ok
+}
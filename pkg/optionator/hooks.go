@@ -0,0 +1,25 @@
+package optionator
+
+import (
+	"reflect"
+)
+
+// DecodeHook converts data, of type from, on its way to a field of type to.
+// It returns the converted value and true, or false to leave data alone.
+// From is nil for nil data.
+type DecodeHook func(from, to reflect.Type, data any) (any, bool, error)
+
+// runDecodeHooks passes data through every hook in order, each seeing the
+// result of those before it.
+func runDecodeHooks(hooks []DecodeHook, to reflect.Type, data any) (any, error) {
+	for _, hook := range hooks {
+		converted, ok, err := hook(reflect.TypeOf(data), to, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			data = converted
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,235 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// KindKey is the key naming the kind of a polymorphic section inside a
+// source object, as in {"Storage": {"kind": "s3", "Bucket": "logs"}}.
+const KindKey = "kind"
+
+var kinds sync.Map // map[kindKey]kind
+
+type kindKey struct {
+	Iface reflect.Type
+	Name  string
+}
+
+type kind struct {
+	new  func() reflect.Value
+	Type reflect.Type
+}
+
+// RegisterKind registers the concrete configuration returned by newConfig,
+// a pointer to a struct, under name for fields of interface type I tagged
+// with a kind tag:
+//
+//	StorageKind string        `default:"local"`
+//	Storage     StorageConfig `kind:"s3|gcs|local"`
+//
+// The kind tag lists the kinds the field accepts. The kind is chosen by the
+// sibling discriminator field, named by a kindfield tag or by default the
+// field name followed by "Kind", or by KindKey inside a source object. The
+// selected struct is then defaulted, bound and validated like any nested
+// struct.
+func RegisterKind[I any](name string, newConfig func() I) {
+	defer resetValidationCache()
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("optionator: RegisterKind for non-interface type %v", iface))
+	}
+	newValue := func() reflect.Value { return reflect.ValueOf(newConfig()) }
+	kinds.Store(kindKey{iface, name}, kind{newValue, newValue().Type()})
+}
+
+// isKindField reports whether fm is a polymorphic section.
+func isKindField(fm fieldMetadata) bool {
+	if fm.Type.Kind() != reflect.Interface {
+		return false
+	}
+	_, ok := fm.Tag.Lookup("kind")
+	return ok
+}
+
+// newKind returns a new configuration of the given kind for iface.
+func newKind(iface reflect.Type, name string) (reflect.Value, error) {
+	k, ok := kinds.Load(kindKey{iface, name})
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown kind %q for %v", name, iface)
+	}
+	v := k.(kind).new()
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct || !v.Type().Implements(iface) {
+		return reflect.Value{}, fmt.Errorf("kind %q of %v must be a pointer to a struct", name, iface)
+	}
+	return v, nil
+}
+
+// kindOf returns the kind name registered for the value held by the
+// interface field, or "" if it is nil or unregistered.
+func kindOf(field reflect.Value) (name string) {
+	if field.IsNil() {
+		return ""
+	}
+	concrete := field.Elem().Type()
+	kinds.Range(func(key, value any) bool {
+		k := key.(kindKey)
+		if k.Iface == field.Type() && value.(kind).Type == concrete {
+			name = k.Name
+			return false
+		}
+		return true
+	})
+	return name
+}
+
+// discriminator returns the sibling field selecting the kind of fm, if any.
+func discriminator(v reflect.Value, metadata []fieldMetadata, fm fieldMetadata) (fieldMetadata, reflect.Value, bool) {
+	name := fm.Tag.Get("kindfield")
+	if name == "" {
+		name = fm.Name + "Kind"
+	}
+	for _, other := range metadata {
+		if other.Name == name && other.Type.Kind() == reflect.String {
+			return other, v.FieldByIndex(other.Index), true
+		}
+	}
+	return fieldMetadata{}, reflect.Value{}, false
+}
+
+// resolveKinds makes every polymorphic section of struct v hold the kind its
+// discriminator names, replacing it with a defaulted new value if not, and
+// records the kind of a section in an empty discriminator. With defaults,
+// sections that are kept also get their defaults.
+func resolveKinds(ctx context.Context, v reflect.Value, metadata []fieldMetadata, config Config, defaults bool) error {
+	for _, fm := range metadata {
+		if !isKindField(fm) {
+			continue
+		}
+		field := v.FieldByIndex(fm.Index)
+		_, disc, hasDisc := discriminator(v, metadata, fm)
+		have := kindOf(field)
+		if hasDisc && disc.String() != "" && disc.String() != have {
+			if err := setKind(ctx, field, disc.String(), config); err != nil {
+				return fmt.Errorf("field %s: %w", fm.Name, err)
+			}
+			continue
+		}
+		if defaults && !field.IsNil() {
+			if err := setDefaultRecursively(ctx, field.Elem(), config); err != nil {
+				return err
+			}
+		}
+		if hasDisc && disc.String() == "" {
+			disc.SetString(have)
+		}
+	}
+	return nil
+}
+
+// setKind replaces field with a defaulted new configuration of kind name.
+func setKind(ctx context.Context, field reflect.Value, name string, config Config) error {
+	inst, err := newKind(field.Type(), name)
+	if err != nil {
+		return err
+	}
+	if err := setDefaultRecursively(ctx, inst, config); err != nil {
+		return err
+	}
+	field.Set(inst)
+	return nil
+}
+
+// reconcileKinds runs resolveKinds over v and every struct nested in it,
+// after sources or options may have changed discriminators.
+func reconcileKinds(ctx context.Context, v reflect.Value, config Config) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	metadata := getTypeMetadata(v.Type(), config)
+	if err := resolveKinds(ctx, v, metadata, config, false); err != nil {
+		return err
+	}
+	for _, fm := range metadata {
+		if isNestedStruct(fm.Type) || isKindField(fm) {
+			if err := reconcileKinds(ctx, v.FieldByIndex(fm.Index), config); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindKind binds a source object onto the polymorphic section fm of struct
+// v. The kind comes from KindKey in the object, the discriminator's entry in
+// the enclosing values, the discriminator field, or the current value, in
+// that order.
+func (b binder) bindKind(v reflect.Value, metadata []fieldMetadata, fm fieldMetadata, values, nested map[string]any, path string) error {
+	field := v.FieldByIndex(fm.Index)
+	discMeta, disc, hasDisc := discriminator(v, metadata, fm)
+	name := ""
+	for k, val := range nested {
+		if strings.EqualFold(k, KindKey) {
+			name, _ = val.(string)
+		}
+	}
+	if name == "" && hasDisc {
+		index := getFieldIndex(v.Type(), b.config)
+		for k, val := range values {
+			if other, _, ok := index.match(k); ok && other.Name == discMeta.Name {
+				name, _ = val.(string)
+			}
+		}
+	}
+	if name == "" && hasDisc {
+		name = disc.String()
+	}
+	if name == "" {
+		name = kindOf(field)
+	}
+	if name == "" {
+		return fmt.Errorf("field %s: no kind given", path)
+	}
+	if name != kindOf(field) {
+		if err := setKind(b.ctx, field, name, b.config); err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+	}
+	if hasDisc {
+		disc.SetString(name)
+	}
+	fields := make(map[string]any, len(nested))
+	for k, val := range nested {
+		if !strings.EqualFold(k, KindKey) {
+			fields[k] = val
+		}
+	}
+	return b.bindMap(field.Elem(), fields, path+".")
+}
+
+// checkKind validates a polymorphic section against the kinds listed in its
+// kind tag.
+func checkKind(field reflect.Value, arg string) error {
+	if field.Kind() != reflect.Interface {
+		return fmt.Errorf("kind tag on non-interface type %v", field.Type())
+	}
+	name := kindOf(field)
+	if name == "" {
+		return fmt.Errorf("%v is not a registered kind of %v", field.Elem().Type(), field.Type())
+	}
+	for _, allowed := range strings.Split(arg, "|") {
+		if allowed == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("kind %q is not one of %s", name, arg)
+}
@@ -0,0 +1,56 @@
+package optionator
+
+import (
+	"context"
+	"reflect"
+)
+
+type lenientKey struct{}
+
+// NewLenient is like NewWithConfig but never gives up: it returns the
+// target populated as far as possible together with every problem found,
+// for tools such as linters, doc generators and migration scripts that
+// inspect whatever could be parsed. Fields that fail to bind keep their
+// defaults, failing options are skipped and every validation failure is
+// listed rather than just the first. Problems that would fail NewWithConfig
+// are findings of SeverityError; the warnings of NewWithReport are included.
+func NewLenient[T any](target T, config Config, opts ...Option[T]) (T, []Finding) {
+	var report Report
+	ctx := context.WithValue(withReport(context.Background(), &report), lenientKey{}, true)
+	target, err := newWithContext(ctx, target, config, opts)
+	if err != nil {
+		report.add("", SeverityError, "%v", err)
+	}
+	finishReport(&report, target, config, nil)
+	return target, report.Findings
+}
+
+// isLenient reports whether ctx belongs to a NewLenient construction.
+func isLenient(ctx context.Context) bool {
+	return ctx != nil && ctx.Value(lenientKey{}) != nil
+}
+
+// salvage returns err, found at path, unless ctx belongs to NewLenient, in
+// which case err is recorded as an error finding and construction goes on.
+func salvage(ctx context.Context, path string, err error) error {
+	if err == nil || !isLenient(ctx) {
+		return err
+	}
+	if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+		report.add(path, SeverityError, "%v", err)
+	}
+	return nil
+}
+
+// validateAll records every validation failure of struct v as a finding.
+func validateAll(ctx context.Context, v reflect.Value, config Config) {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	if report == nil {
+		return
+	}
+	validateFields(v, config, "", func(err *FieldError) bool {
+		report.add(err.Path, SeverityError, "%v", err)
+		return true
+	})
+	report.Findings = append(report.Findings, evalRules(v, config, SeverityError)...)
+}
@@ -0,0 +1,185 @@
+package optionator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Live holds a configuration that can be rebuilt while in use. Readers call
+// Load and always see a complete, validated value; Reload runs the whole
+// pipeline again, including sources, and swaps the result in atomically.
+type Live[T any] struct {
+	mu        sync.Mutex // serializes reloads and subscriber changes
+	current   atomic.Value
+	newTarget func() T
+	config    Config
+	opts      []Option[T]
+	subs      map[int]func(old, new T)
+	nextSub   int
+	audit     AuditSink
+	revision  uint64
+}
+
+// NewLive builds the initial value from newTarget, which must return a fresh
+// pointer to a struct on every call, and returns a Live holding it.
+func NewLive[T any](newTarget func() T, config Config, opts ...Option[T]) (*Live[T], error) {
+	l := &Live[T]{newTarget: newTarget, config: config, opts: opts, subs: map[int]func(old, new T){}}
+	v, err := newWithContext(context.Background(), newTarget(), config, opts)
+	if err != nil {
+		return nil, err
+	}
+	l.current.Store(box[T]{v})
+	return l, nil
+}
+
+// box wraps values so that atomic.Value accepts any T, including interfaces.
+type box[T any] struct{ v T }
+
+// Load returns the current value. It must be treated as read-only.
+func (l *Live[T]) Load() T {
+	return l.current.Load().(box[T]).v
+}
+
+// Reload rebuilds the value. If construction fails the current value is
+// kept and the error returned; otherwise the new value is swapped in and
+// subscribers are notified in the calling goroutine. Changes are attributed
+// to the actor set on ctx with WithActor.
+func (l *Live[T]) Reload(ctx context.Context) error {
+	notify, err := l.reload(ctx)
+	if err != nil {
+		return err
+	}
+	notify()
+	return nil
+}
+
+func (l *Live[T]) reload(ctx context.Context) (notify func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v, err := newWithContext(withReload(ctx), l.newTarget(), l.config, l.opts)
+	if err != nil {
+		return nil, err
+	}
+	return l.swap(ctx, v)
+}
+
+// Reconfigure applies opts to a copy of the current value of live,
+// validates it and swaps it in, notifying subscribers, without reloading
+// sources. The options are kept and applied again, after the original ones,
+// by later reloads, so runtime adjustments are not lost. If an option or
+// validation fails, the current value stays in place. Changes are
+// attributed to the actor set on ctx with WithActor.
+func Reconfigure[T any](ctx context.Context, live *Live[T], opts ...Option[T]) error {
+	notify, err := live.reconfigure(ctx, opts)
+	if err != nil {
+		return err
+	}
+	notify()
+	return nil
+}
+
+func (l *Live[T]) reconfigure(ctx context.Context, opts []Option[T]) (notify func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	target := deepCopy(l.Load())
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("target must be a pointer to a struct")
+	}
+	buildCtx := withReload(ctx)
+	inFlight.Store(target, flight{l.config, buildCtx})
+	err = finish(buildCtx, v.Elem(), target, l.config, opts)
+	inFlight.Delete(target)
+	if err != nil {
+		return nil, err
+	}
+	if notify, err = l.swap(ctx, target); err != nil {
+		return nil, err
+	}
+	l.opts = append(l.opts[:len(l.opts):len(l.opts)], opts...)
+	return notify, nil
+}
+
+// swap runs the onset callbacks, records the change with the audit sink and
+// stores v. If a callback or the sink fails the change is abandoned, and
+// nothing is recorded for a change a callback refused. The caller holds
+// l.mu, and calls notify once it has released it, so subscribers may use the
+// Live themselves.
+func (l *Live[T]) swap(ctx context.Context, v T) (notify func(), err error) {
+	old := l.Load()
+	if err := runOnSet(reflect.ValueOf(old).Elem(), reflect.ValueOf(v).Elem(), l.config); err != nil {
+		return nil, err
+	}
+	if l.audit != nil {
+		entries := auditEntries(ctx, reflect.ValueOf(old).Elem(), reflect.ValueOf(v).Elem(), l.config, l.revision+1)
+		if len(entries) > 0 {
+			if err := l.audit.Record(entries); err != nil {
+				return nil, fmt.Errorf("recording audit entries: %w", err)
+			}
+		}
+	}
+	l.revision++
+	l.current.Store(box[T]{v})
+	subs := make([]func(old, new T), 0, len(l.subs))
+	for _, fn := range l.subs {
+		subs = append(subs, fn)
+	}
+	return func() {
+		for _, fn := range subs {
+			fn(old, v)
+		}
+	}, nil
+}
+
+// SetAuditSink makes every subsequent change record one audit entry per
+// changed field with sink, values of secret fields redacted.
+func (l *Live[T]) SetAuditSink(sink AuditSink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.audit = sink
+}
+
+// Revision returns the number of changes applied since creation.
+func (l *Live[T]) Revision() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.revision
+}
+
+// Subscribe registers fn to be called with the old and new value after each
+// successful reload or Reconfigure. It runs outside the lock of l, so it may
+// call Revision, Reload or Reconfigure. It returns a function that cancels the subscription.
+func (l *Live[T]) Subscribe(fn func(old, new T)) (cancel func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id := l.nextSub
+	l.nextSub++
+	l.subs[id] = fn
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.subs, id)
+	}
+}
+
+// Watch reloads every interval until ctx is done, passing reload errors to
+// onError if it is not nil.
+func (l *Live[T]) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Reload(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
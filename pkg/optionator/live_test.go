@@ -0,0 +1,313 @@
+package optionator
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeFlags struct {
+	mu    sync.Mutex
+	bools map[string]bool
+	ints  map[string]int64
+}
+
+func (f *fakeFlags) BooleanValue(ctx context.Context, flag string, def bool) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v, ok := f.bools[flag]; ok {
+		return v, nil
+	}
+	return def, errors.New("flag not found")
+}
+
+func (f *fakeFlags) IntValue(ctx context.Context, flag string, def int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v, ok := f.ints[flag]; ok {
+		return v, nil
+	}
+	return def, errors.New("flag not found")
+}
+
+func (f *fakeFlags) FloatValue(ctx context.Context, flag string, def float64) (float64, error) {
+	return def, errors.New("flag not found")
+}
+
+func (f *fakeFlags) StringValue(ctx context.Context, flag string, def string) (string, error) {
+	return def, errors.New("flag not found")
+}
+
+type Batcher struct {
+	Enabled   bool `flag:"new-batcher-enabled"`
+	BatchSize int  `flag:"batch-size" default:"16"`
+	Workers   int  `flag:"workers" default:"2"`
+}
+
+func TestLiveWithFlagSource(t *testing.T) {
+	flags := &fakeFlags{bools: map[string]bool{"new-batcher-enabled": true}, ints: map[string]int64{"batch-size": 64}}
+	config := defaultConfig
+	config.Sources = []Source{FlagSource[*Batcher](flags)}
+	live, err := NewLive(func() *Batcher { return &Batcher{} }, config)
+	if err != nil {
+		t.Fatalf("NewLive: %v", err)
+	}
+	if b := live.Load(); !b.Enabled || b.BatchSize != 64 || b.Workers != 2 {
+		t.Fatalf("Unexpected initial value %+v", b)
+	}
+
+	var seen []int
+	var revisions []uint64
+	cancel := live.Subscribe(func(old, new *Batcher) {
+		seen = append(seen, old.BatchSize, new.BatchSize)
+		revisions = append(revisions, live.Revision())
+	})
+	flags.mu.Lock()
+	flags.ints["batch-size"] = 128
+	flags.mu.Unlock()
+	if err := live.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if live.Load().BatchSize != 128 || len(seen) != 2 || seen[0] != 64 || seen[1] != 128 {
+		t.Errorf("Expected reload to pick up the new flag, got %+v (notified %v)", live.Load(), seen)
+	}
+	if len(revisions) != 1 || revisions[0] != 1 {
+		t.Errorf("Expected subscribers to read the revision, got %v", revisions)
+	}
+	cancel()
+	if err := live.Reload(context.Background()); err != nil || len(seen) != 2 {
+		t.Errorf("Expected no notification after cancel, got %v (%v)", seen, err)
+	}
+}
+
+type auditRecorder struct{ entries []AuditEntry }
+
+func (r *auditRecorder) Record(entries []AuditEntry) error {
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func TestLiveAudit(t *testing.T) {
+	type Creds struct {
+		User     string `default:"svc"`
+		Password string `secret:"true"`
+	}
+	values := map[string]any{"Password": "hunter2"}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: values}}
+	live, err := NewLive(func() *Creds { return &Creds{} }, config)
+	if err != nil {
+		t.Fatalf("NewLive: %v", err)
+	}
+	sink := &auditRecorder{}
+	live.SetAuditSink(sink)
+	values["Password"] = "correct horse"
+	values["User"] = "admin"
+	if err := live.Reload(WithActor(context.Background(), "alice")); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(sink.entries) != 2 || live.Revision() != 1 {
+		t.Fatalf("Expected two entries at revision 1, got %+v", sink.entries)
+	}
+	for _, e := range sink.entries {
+		if e.Actor != "alice" || e.Revision != 1 {
+			t.Errorf("Unexpected attribution %+v", e)
+		}
+		if e.Path == "Password" && (e.Old != Redacted || e.New != Redacted) {
+			t.Errorf("Expected secret to be redacted, got %+v", e)
+		}
+		if e.Path == "User" && (e.Old != "svc" || e.New != "admin") {
+			t.Errorf("Unexpected user change %+v", e)
+		}
+	}
+
+	sink.entries = nil
+	if err := Reconfigure(WithActor(context.Background(), "bob"), live, With[*Creds]("User", "ops")); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.entries) != 1 || sink.entries[0].Actor != "bob" {
+		t.Errorf("Expected Reconfigure to be attributed to bob, got %+v", sink.entries)
+	}
+
+	type Limits struct {
+		Rate int `default:"1" onset:"Missing"`
+	}
+	rates := map[string]any{}
+	config.Sources = []Source{MapSource{Values: rates}}
+	limits, err := NewLive(func() *Limits { return &Limits{} }, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.entries = nil
+	limits.SetAuditSink(sink)
+	rates["Rate"] = 2
+	if err := limits.Reload(context.Background()); err == nil || len(sink.entries) != 0 || limits.Load().Rate != 1 {
+		t.Errorf("Expected a refused change to go unrecorded, got %v, %+v", err, sink.entries)
+	}
+}
+
+type Pool struct {
+	Size    int `default:"4" onset:"Resize"`
+	resized []int
+}
+
+func (p *Pool) Resize(old, new int) { p.resized = append(p.resized, old, new) }
+
+type PoolConfig struct {
+	Pool Pool
+}
+
+func TestOnSet(t *testing.T) {
+	cfg, err := New(&PoolConfig{}, func(c *PoolConfig) error {
+		c.Pool.Size = 8
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Pool.resized; len(got) != 2 || got[0] != 4 || got[1] != 8 {
+		t.Fatalf("resized = %v, want [4 8]", got)
+	}
+
+	size := 4
+	live, err := NewLive(func() *PoolConfig { return &PoolConfig{} }, defaultConfig, func(c *PoolConfig) error {
+		c.Pool.Size = size
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size = 16
+	if err := live.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := live.Load().Pool.resized; len(got) != 2 || got[0] != 4 || got[1] != 16 {
+		t.Fatalf("resized on reload = %v, want [4 16]", got)
+	}
+}
+
+// fakeMessage sends nil to acked when acknowledged and the reason when
+// rejected.
+type fakeMessage struct {
+	data  string
+	acked chan error
+}
+
+func (m fakeMessage) Data() []byte           { return []byte(m.data) }
+func (m fakeMessage) Ack() error             { m.acked <- nil; return nil }
+func (m fakeMessage) Nak(reason error) error { m.acked <- reason; return nil }
+
+func TestApplyPatches(t *testing.T) {
+	src := NewPatchSource("config.nested")
+	config := defaultConfig
+	config.Sources = []Source{src}
+	live, err := NewLive(func() *NestedConfig { return &NestedConfig{} }, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan Message)
+	done := make(chan error)
+	go func() { done <- ApplyPatches(context.Background(), live, src, msgs) }()
+
+	acked := make(chan error, 1)
+	for _, tt := range []struct {
+		patch  string
+		reason string
+		port   int
+		host   string
+	}{
+		{`{"Port": 9000, "Host": "a.example.com"}`, "", 9000, "a.example.com"},
+		{`{"Host": ""}`, "Host", 9000, "a.example.com"},
+		{`not json`, "invalid character", 9000, "a.example.com"},
+		{`{"Port": null}`, "", 8080, "a.example.com"},
+	} {
+		msgs <- fakeMessage{data: tt.patch, acked: acked}
+		reason := <-acked
+		switch {
+		case tt.reason == "" && reason != nil:
+			t.Errorf("%s: rejected with %v", tt.patch, reason)
+		case tt.reason != "" && (reason == nil || !strings.Contains(reason.Error(), tt.reason)):
+			t.Errorf("%s: reason = %v, want it to mention %s", tt.patch, reason, tt.reason)
+		}
+		var group ErrorGroup
+		if tt.reason == "Host" && !errors.As(reason, &group) {
+			t.Errorf("%s: reason %T is not an ErrorGroup", tt.patch, reason)
+		}
+		if cfg := live.Load(); cfg.Port != tt.port || cfg.Host != tt.host {
+			t.Errorf("%s: got %+v", tt.patch, cfg)
+		}
+	}
+	close(msgs)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverrideStore(t *testing.T) {
+	store := &OverrideStore{Path: filepath.Join(t.TempDir(), "overrides.json"), Version: 1}
+	config := defaultConfig
+	config.Sources = []Source{store}
+	newLive := func() *Live[*NestedConfig] {
+		live, err := NewLive(func() *NestedConfig { return &NestedConfig{} }, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return live
+	}
+	ctx := context.Background()
+	live := newLive()
+	if err := ApplyOverride(ctx, live, store, map[string]any{"Port": 9000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyOverride(ctx, live, store, map[string]any{"Host": ""}); err == nil {
+		t.Error("expected the invalid override to fail")
+	}
+	if got := live.Load().Port; got != 9000 {
+		t.Errorf("Port = %d", got)
+	}
+
+	// A restart replays the surviving override.
+	if got := newLive().Load(); got.Port != 9000 || got.Host != "localhost" {
+		t.Errorf("after restart got %+v", got)
+	}
+
+	store.Version = 2
+	if _, err := NewLive(func() *NestedConfig { return &NestedConfig{} }, config); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("got %v, want a version mismatch", err)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	values := map[string]any{"BatchSize": 32}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: values}}
+	live, err := NewLive(func() *Batcher { return &Batcher{} }, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []int
+	live.Subscribe(func(old, new *Batcher) { seen = append(seen, new.Workers) })
+	first := live.Load()
+	if err := Reconfigure(context.Background(), live, With[*Batcher]("Workers", 8)); err != nil {
+		t.Fatal(err)
+	}
+	if b := live.Load(); b.Workers != 8 || b.BatchSize != 32 || first.Workers != 2 {
+		t.Errorf("got %+v, first value %+v", b, first)
+	}
+	if err := Reconfigure(context.Background(), live, func(*Batcher) error { return errors.New("boom") }); err == nil || live.Load().Workers != 8 {
+		t.Errorf("failed Reconfigure must keep the current value, got %v", err)
+	}
+	values["BatchSize"] = 48
+	if err := live.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b := live.Load(); b.Workers != 8 || b.BatchSize != 48 {
+		t.Errorf("reload must keep reconfigured options: %+v", b)
+	}
+	if len(seen) != 2 || live.Revision() != 2 {
+		t.Errorf("subscribers saw %v, revision %d", seen, live.Revision())
+	}
+}
@@ -0,0 +1,131 @@
+package optionator
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Memo constructs configurations and shares the result between calls that
+// would build the same thing: the same overrides over the same source
+// values. Values returned by Get are shared and must be treated as
+// read-only. It keeps the DefaultMemoSize most recently used instances
+// unless SetSize changes the bound.
+type Memo[T any] struct {
+	newTarget func() T
+	config    Config
+	opts      []Option[T]
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *memoEntry[T], most recent first
+	entries map[string]*list.Element
+}
+
+type memoEntry[T any] struct {
+	key string
+	v   T
+}
+
+// DefaultMemoSize is the number of instances a new Memo keeps.
+const DefaultMemoSize = 128
+
+// NewMemo returns a Memo that builds from newTarget, which must return a
+// fresh pointer to a struct on every call, with config and opts.
+func NewMemo[T any](newTarget func() T, config Config, opts ...Option[T]) *Memo[T] {
+	return &Memo[T]{
+		newTarget: newTarget,
+		config:    config,
+		opts:      opts,
+		size:      DefaultMemoSize,
+		order:     list.New(),
+		entries:   map[string]*list.Element{},
+	}
+}
+
+// SetSize bounds the number of instances m keeps, evicting the least
+// recently used beyond it. Zero disables sharing.
+func (m *Memo[T]) SetSize(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.size = size
+	m.evict()
+}
+
+// evict drops the least recently used entries beyond the size. The caller
+// holds m.mu.
+func (m *Memo[T]) evict() {
+	for m.order.Len() > m.size && m.order.Len() > 0 {
+		e := m.order.Back()
+		m.order.Remove(e)
+		delete(m.entries, e.Value.(*memoEntry[T]).key)
+	}
+}
+
+// Get loads the configured sources and returns the instance built from them
+// and overrides, which has the shape of source values and is bound after
+// them. If the source values and overrides match an earlier call, the
+// instance from that call is returned without rebuilding.
+func (m *Memo[T]) Get(ctx context.Context, overrides map[string]any) (T, error) {
+	loaded, err := fetchSources(withTargetOf[T](ctx), m.config)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	loaded = append(loaded, loadedSource{MapSource{overrides}, overrides})
+	key, err := memoKey(loaded)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	m.mu.Lock()
+	if e, ok := m.entries[key]; ok {
+		m.order.MoveToFront(e)
+		m.mu.Unlock()
+		return e.Value.(*memoEntry[T]).v, nil
+	}
+	m.mu.Unlock()
+	v, err := newWithLoader(ctx, m.newTarget(), m.config, m.opts, func(context.Context) ([]loadedSource, error) {
+		return loaded, nil
+	})
+	if err != nil {
+		return v, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		// Another call built the same instance meanwhile; share its.
+		m.order.MoveToFront(e)
+		return e.Value.(*memoEntry[T]).v, nil
+	}
+	if m.size > 0 {
+		m.entries[key] = m.order.PushFront(&memoEntry[T]{key, v})
+		m.evict()
+	}
+	return v, nil
+}
+
+// Reset drops every cached instance.
+func (m *Memo[T]) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.order.Init()
+	m.entries = map[string]*list.Element{}
+}
+
+// memoKey fingerprints loaded source values. encoding/json sorts map keys,
+// so equal values always encode the same way.
+func memoKey(loaded []loadedSource) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, ls := range loaded {
+		if err := enc.Encode(ls.values); err != nil {
+			return "", fmt.Errorf("fingerprinting %s: %w", ls.src.Name(), err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -2,23 +2,90 @@ package optionator
 
 import (
 	"reflect"
+	"strings"
 	"sync"
+
+	"github.com/chetan-giradkar/Optionator/internal/tagrules"
 )
 
-var metadataCache sync.Map // map[reflect.Type][]fieldMetadata
+var metadataCache sync.Map // map[metadataKey]*fieldIndex
+
+// metadataKey identifies cached metadata. The same type yields different
+// metadata under different tag settings, so those are part of the key.
+type metadataKey struct {
+	Type             reflect.Type
+	DefaultTag       string
+	RequiredTag      string
+	TagCompatibility string
+}
 
 type fieldMetadata struct {
 	Index      []int
 	Name       string
 	DefaultTag string
 	Required   bool
-	Type       reflect.Type
+	// NonEmpty is set by required:"nonempty" and rejects empty slices and
+	// maps, which plain required accepts.
+	NonEmpty bool
+	// ForceDefault applies the default even over a non-zero value.
+	ForceDefault bool
+	Flag         string
+	Secret       bool
+	// Mask is the partial display of a secret, SecretLast4 or SecretHash,
+	// or empty for full redaction.
+	Mask  string
+	OnSet string
+	// Aliases are former names of the field, read by sources when the
+	// field's own name is absent.
+	Aliases []string
+	// From restricts the sources that may set the field to these kinds.
+	From []string
+	// Description, Example and Docs come from the desc, example and docs
+	// tags and document the field in generated docs and help output.
+	Description string
+	Example     string
+	Docs        string
+	// Group names the documentation section of the field, or of every field
+	// of a nested struct, from the group tag.
+	Group string
+	// Hidden keeps the field out of docs, flags and other listings.
+	Hidden bool
+	// Stability is the level from the stability tag.
+	Stability string
+	Type      reflect.Type
+	// Tag is the whole struct tag, read by tag validators.
+	Tag reflect.StructTag
+	// Checked is set when the tag carries a validation tag, so fields
+	// without one skip the tag checks.
+	Checked bool
+}
+
+// fieldIndex holds the metadata of a struct type along with maps from
+// field names and aliases, as written and lowercased, to positions in it,
+// so fields are found by name in constant time.
+type fieldIndex struct {
+	fields        []fieldMetadata
+	names         map[string]int
+	foldedNames   map[string]int
+	aliases       map[string]int
+	foldedAliases map[string]int
 }
 
 // getTypeMetadata now accepts a Config parameter to use the correct tag names.
 func getTypeMetadata(t reflect.Type, config Config) []fieldMetadata {
-	if cached, ok := metadataCache.Load(t); ok {
-		return cached.([]fieldMetadata)
+	return getFieldIndex(t, config).fields
+}
+
+// getFieldIndex returns the cached fieldIndex of struct type t.
+func getFieldIndex(t reflect.Type, config Config) *fieldIndex {
+	key := metadataKey{
+		Type:             t,
+		DefaultTag:       config.DefaultTag,
+		RequiredTag:      config.RequiredTag,
+		TagCompatibility: config.TagCompatibility,
+	}
+	if cached, ok := metadataCache.Load(key); ok {
+		return cached.(*fieldIndex)
 	}
 	var metadata []fieldMetadata
 	// Iterate over struct fields.
@@ -28,15 +95,87 @@ func getTypeMetadata(t reflect.Type, config Config) []fieldMetadata {
 		if sf.PkgPath != "" {
 			continue
 		}
+		sf.Tag = definedTag(t, sf, config)
+		def, required, ignored := config.fieldTags(sf)
+		if ignored || sf.Tag.Get("optionator") == "-" {
+			continue
+		}
 		fm := fieldMetadata{
-			Index:      sf.Index,
-			Name:       sf.Name,
-			DefaultTag: sf.Tag.Get(config.DefaultTag),
-			Required:   sf.Tag.Get(config.RequiredTag) == "true",
-			Type:       sf.Type,
+			Index:        sf.Index,
+			Name:         sf.Name,
+			DefaultTag:   def,
+			Required:     required,
+			NonEmpty:     required && config.TagCompatibility == "" && sf.Tag.Get(config.RequiredTag) == "nonempty",
+			ForceDefault: sf.Tag.Get("forcedefault") == "true",
+			Flag:         sf.Tag.Get("flag"),
+			Secret:       isSecret(sf.Tag.Get("secret")),
+			Mask:         secretMask(sf.Tag.Get("secret")),
+			OnSet:        sf.Tag.Get("onset"),
+			Aliases:      tagList(sf.Tag.Get("alias")),
+			From:         tagList(sf.Tag.Get("from")),
+			Description:  sf.Tag.Get("desc"),
+			Example:      sf.Tag.Get("example"),
+			Docs:         sf.Tag.Get("docs"),
+			Group:        sf.Tag.Get("group"),
+			Hidden:       sf.Tag.Get("hidden") == "true",
+			Stability:    sf.Tag.Get("stability"),
+			Type:         sf.Type,
+			Tag:          sf.Tag,
+			Checked:      hasCheckTags(sf.Tag),
 		}
 		metadata = append(metadata, fm)
 	}
-	metadataCache.Store(t, metadata)
-	return metadata
+	idx := newFieldIndex(metadata)
+	metadataCache.Store(key, idx)
+	return idx
+}
+
+func newFieldIndex(metadata []fieldMetadata) *fieldIndex {
+	idx := &fieldIndex{
+		fields:        metadata,
+		names:         map[string]int{},
+		foldedNames:   map[string]int{},
+		aliases:       map[string]int{},
+		foldedAliases: map[string]int{},
+	}
+	// The first field wins a name, as in declaration order.
+	add := func(m map[string]int, name string, i int) {
+		if _, ok := m[name]; !ok {
+			m[name] = i
+		}
+	}
+	for i, fm := range metadata {
+		add(idx.names, fm.Name, i)
+		add(idx.foldedNames, strings.ToLower(fm.Name), i)
+		for _, a := range fm.Aliases {
+			add(idx.aliases, a, i)
+			add(idx.foldedAliases, strings.ToLower(a), i)
+		}
+	}
+	return idx
+}
+
+// match finds the field a key names: by exact name, name ignoring case,
+// exact alias or alias ignoring case, in that order. alias reports a match
+// on an alias.
+func (idx *fieldIndex) match(key string) (fm fieldMetadata, alias, ok bool) {
+	if i, ok := idx.names[key]; ok {
+		return idx.fields[i], false, true
+	}
+	folded := strings.ToLower(key)
+	if i, ok := idx.foldedNames[folded]; ok {
+		return idx.fields[i], false, true
+	}
+	if i, ok := idx.aliases[key]; ok {
+		return idx.fields[i], true, true
+	}
+	if i, ok := idx.foldedAliases[folded]; ok {
+		return idx.fields[i], true, true
+	}
+	return fieldMetadata{}, false, false
+}
+
+// tagList splits a comma-separated tag value, dropping empty elements.
+func tagList(tag string) []string {
+	return tagrules.List(tag)
 }
@@ -0,0 +1,107 @@
+package optionator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// VersionKey holds the schema version of a configuration document. A
+// document without it is at version 0.
+const VersionKey = "$version"
+
+// Migration rewrites a document from one schema version to the next, e.g.
+// to rename or restructure fields. It may modify values in place.
+type Migration func(values map[string]any) (map[string]any, error)
+
+// Migrations upgrades documents written for older versions of a
+// configuration struct to the Current version.
+type Migrations struct {
+	Current int
+
+	mu    sync.RWMutex
+	steps map[int]Migration
+}
+
+// Register sets the migration from version from to from+1.
+func (m *Migrations) Register(from int, fn Migration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.steps == nil {
+		m.steps = map[int]Migration{}
+	}
+	m.steps[from] = fn
+}
+
+// Migrate upgrades values, at version from, to Current, and records Current
+// under VersionKey in the result. values itself is left untouched.
+func (m *Migrations) Migrate(values map[string]any, from int) (map[string]any, error) {
+	if from > m.Current {
+		return nil, fmt.Errorf("schema version %d is newer than %d", from, m.Current)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	values = deepCopy(values)
+	for v := from; v < m.Current; v++ {
+		step, ok := m.steps[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", v)
+		}
+		var err error
+		if values, err = step(values); err != nil {
+			return nil, fmt.Errorf("migrating from schema version %d: %w", v, err)
+		}
+	}
+	values[VersionKey] = m.Current
+	return values, nil
+}
+
+// WithMigrations wraps src so that its values are migrated to m.Current,
+// reading their version from VersionKey, before they are bound.
+func WithMigrations(src Source, m *Migrations) Source {
+	return migratingSource{src, m}
+}
+
+type migratingSource struct {
+	Source
+	migrations *Migrations
+}
+
+func (s migratingSource) WeaklyTyped() bool { return isWeaklyTyped(s.Source) }
+
+func (s migratingSource) Optional() bool { return isOptional(s.Source) }
+
+func (s migratingSource) Kind() SourceKind { return sourceKind(s.Source) }
+
+func (s migratingSource) Load(ctx context.Context) (map[string]any, error) {
+	values, err := s.Source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := schemaVersion(values[VersionKey])
+	if err != nil {
+		return nil, err
+	}
+	return s.migrations.Migrate(values, version)
+}
+
+// schemaVersion reads a VersionKey value.
+func schemaVersion(v any) (int, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		return int(v), nil
+	case json.Number:
+		n, err := strconv.Atoi(v.String())
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", VersionKey, v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid %s %v", VersionKey, v)
+}
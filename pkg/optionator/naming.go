@@ -0,0 +1,112 @@
+package optionator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// kebabCase converts a Go identifier such as "TLSConfig" or "MaxConns" to
+// its kebab-case form ("tls-config", "max-conns").
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// FlagName returns the command-line flag name derived from a field path:
+// each path element is kebab-cased and elements are joined with dots.
+func FlagName(path string) string {
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		parts[i] = kebabCase(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// NamingStrategy derives the key of a field in sources, exports and flags
+// from its Go name, so a config file can say max_conns without a tag on
+// every field. Keys still match Go field names case-insensitively.
+type NamingStrategy func(name string) string
+
+// Built-in naming strategies.
+var (
+	// SnakeCase names MaxConns max_conns.
+	SnakeCase NamingStrategy = snakeCase
+	// KebabCase names MaxConns max-conns.
+	KebabCase NamingStrategy = kebabCase
+	// CamelCase names MaxConns maxConns and TLSConfig tlsConfig.
+	CamelCase NamingStrategy = camelCase
+)
+
+func snakeCase(name string) string {
+	return strings.ReplaceAll(kebabCase(name), "-", "_")
+}
+
+func camelCase(name string) string {
+	words := strings.Split(kebabCase(name), "-")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// Key returns the key of the field at a dotted path: each path element is
+// renamed by s, or kept as is if s is nil, and elements are joined with
+// dots.
+func (s NamingStrategy) Key(path string) string {
+	if s == nil {
+		return path
+	}
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		parts[i] = s(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// EnvName returns the environment variable EnvSource reads the field at a
+// dotted path from: prefix followed by the path renamed by s, upper-cased,
+// with nested elements joined by a double underscore.
+func (s NamingStrategy) EnvName(prefix, path string) string {
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		if s != nil {
+			p = s(p)
+		}
+		parts[i] = envWord(p)
+	}
+	return prefix + strings.Join(parts, "__")
+}
+
+// envWord upper-cases a key and replaces the characters that are not
+// portable in variable names with underscores.
+func envWord(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, key)
+}
+
+// flagName is FlagName, or the key of the path under the configured naming
+// strategy if there is one.
+func (c Config) flagName(path string) string {
+	if c.NamingStrategy == nil {
+		return FlagName(path)
+	}
+	return c.NamingStrategy.Key(path)
+}
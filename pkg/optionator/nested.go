@@ -1,38 +1,118 @@
 package optionator
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 )
 
 // setDefaultRecursively applies default values recursively for nested structs.
-func setDefaultRecursively(v reflect.Value, config Config) error {
+// It does nothing when config.SkipDefaults is set.
+func setDefaultRecursively(ctx context.Context, v reflect.Value, config Config) error {
+	if config.SkipDefaults {
+		return nil
+	}
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			// Allocate new value if pointer is nil.
 			v.Set(reflect.New(v.Type().Elem()))
 		}
-		return setDefaultRecursively(v.Elem(), config)
+		return setDefaultRecursively(ctx, v.Elem(), config)
 	}
 	if v.Kind() != reflect.Struct {
 		return nil
 	}
 	t := v.Type()
 	metadata := getTypeMetadata(t, config)
+	derived := false
 	for _, fm := range metadata {
 		field := v.FieldByIndex(fm.Index)
 		// If field is a struct or pointer to struct, apply defaults recursively.
-		if field.Kind() == reflect.Struct || (field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct) {
-			if err := setDefaultRecursively(field, config); err != nil {
+		if isNestedStruct(field.Type()) {
+			if err := setDefaultRecursively(ctx, field, config); err != nil {
 				return err
 			}
 		}
-		// Only set default if field is zero and a default tag is provided.
-		if isZeroValue(field) && fm.DefaultTag != "" {
-			if err := parseAndSetDefault(field, fm.DefaultTag, fm.Type); err != nil {
-				return fmt.Errorf("error setting default for field %s: %w", fm.Name, err)
+		if isDerived(fm.DefaultTag) {
+			derived = true
+			continue
+		}
+		// Only set default if field is zero, or forced, and a default tag is provided.
+		if (fm.ForceDefault || isZeroValue(field)) && fm.DefaultTag != "" {
+			tag, err := config.resolveDefault(fm.DefaultTag, fm.Type)
+			if err == nil {
+				err = parseAndSetDefault(field, tag, fm.Type)
+			}
+			if err != nil {
+				err = fmt.Errorf("error setting default for field %s: %w", fm.Name, err)
+				if err := tolerate(ctx, config, StrictnessStrict, fm.Name, err); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if derived && derivedLater(ctx) {
+		// Forced derived defaults replace the initial value; clear it so
+		// the later pass sees the field as unset.
+		for _, fm := range metadata {
+			if fm.ForceDefault && isDerived(fm.DefaultTag) {
+				field := v.FieldByIndex(fm.Index)
+				field.Set(reflect.Zero(field.Type()))
+			}
+		}
+	} else if derived {
+		if err := setDerivedDefaults(v, metadata, config, true); err != nil {
+			return err
+		}
+	}
+	return resolveKinds(ctx, v, metadata, config, true)
+}
+
+// isNestedStruct reports whether t is a struct or a pointer to a struct,
+// whose fields are configured recursively.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		if isTextType(t) {
+			return false
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isLeafStruct(t)
+}
+
+// isLeafStruct reports whether struct type t is configured as one value
+// rather than field by field: time.Time and types implementing
+// encoding.TextUnmarshaler.
+func isLeafStruct(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// fieldByIndexes walks a chain of field indexes from v, allocating nil
+// pointers to nested structs along the way.
+func fieldByIndexes(v reflect.Value, indexes [][]int) reflect.Value {
+	for _, index := range indexes {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByIndex(index)
+	}
+	return v
+}
+
+// lookupIndexes is the read-only counterpart of fieldByIndexes: it reports
+// false instead of allocating when a nil pointer is found along the way.
+func lookupIndexes(v reflect.Value, indexes [][]int) (reflect.Value, bool) {
+	for _, index := range indexes {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return v, false
 			}
+			v = v.Elem()
 		}
+		v = v.FieldByIndex(index)
 	}
-	return nil
+	return v, true
 }
@@ -0,0 +1,49 @@
+package optionator
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// groupedNumber matches numbers with thousands separated by commas, such as
+// 1,000,000 or 12,345.5.
+var groupedNumber = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d+)?([eE][+-]?\d+)?$`)
+
+// humanNumber rewrites text written for people, such as 1_000_000,
+// 1,000,000 or 1e6, into the plain form the parsers accept for a field of
+// type t. Text that is not such a number, or a field that is not numeric,
+// is returned unchanged.
+func humanNumber(text string, t reflect.Type) string {
+	if !isNumber(t.Kind()) || t == durationType {
+		return text
+	}
+	s := strings.TrimSpace(text)
+	if groupedNumber.MatchString(s) {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	if strings.Contains(s, "_") && digitSeparated(s) {
+		s = strings.ReplaceAll(s, "_", "")
+	}
+	if t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64 && strings.ContainsAny(s, ".eE") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return s
+}
+
+// digitSeparated reports whether every underscore in s sits between two
+// digits, as in Go number literals.
+func digitSeparated(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !isDigit(s[i-1]) || !isDigit(s[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,69 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+type reloadKey struct{}
+
+// withReload marks ctx as belonging to a Live reload, whose onset calls are
+// made against the previous value rather than around the options.
+func withReload(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reloadKey{}, true)
+}
+
+func isReload(ctx context.Context) bool {
+	reload, _ := ctx.Value(reloadKey{}).(bool)
+	return reload
+}
+
+// hasOnSet reports whether any field of struct type t has an onset tag.
+func hasOnSet(t reflect.Type, config Config) bool {
+	for _, fi := range describeType(t, config) {
+		if fi.OnSet != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// runOnSet calls the onset method of every field whose value differs between
+// old and new. The method is looked up on a pointer to the struct that
+// declares the field and is called with the old and new field values.
+func runOnSet(old, new reflect.Value, config Config) error {
+	for _, c := range diffFields(old, new, config) {
+		if c.info.OnSet == "" || !c.new.IsValid() {
+			continue
+		}
+		owner := new
+		if len(c.info.indexes) > 1 {
+			owner, _ = lookupIndexes(new, c.info.indexes[:len(c.info.indexes)-1])
+		}
+		for owner.Kind() == reflect.Ptr {
+			owner = owner.Elem()
+		}
+		method := owner.Addr().MethodByName(c.info.OnSet)
+		if !method.IsValid() {
+			return fmt.Errorf("onset method %s not found on *%v", c.info.OnSet, owner.Type())
+		}
+		mt := method.Type()
+		if mt.NumIn() != 2 || !c.info.Type.AssignableTo(mt.In(0)) || !c.info.Type.AssignableTo(mt.In(1)) {
+			return fmt.Errorf("onset method %s must accept (old, new %v)", c.info.OnSet, c.info.Type)
+		}
+		oldValue := c.old
+		if !oldValue.IsValid() {
+			oldValue = reflect.Zero(c.info.Type)
+		}
+		method.Call([]reflect.Value{oldValue, c.new})
+	}
+	return nil
+}
+
+// snapshot returns a deep copy of the struct v.
+func snapshot(v reflect.Value) reflect.Value {
+	cp := reflect.New(v.Type()).Elem()
+	copyValue(cp, v, copies{})
+	return cp
+}
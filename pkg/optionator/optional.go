@@ -0,0 +1,40 @@
+package optionator
+
+import (
+	"context"
+)
+
+// Optional wraps src so that a failure to load it is not fatal: the source
+// is skipped, and NewWithReport records a warning. Sources are required
+// unless wrapped.
+func Optional(src Source) Source {
+	return optionalSource{src}
+}
+
+type optionalSource struct{ Source }
+
+func (optionalSource) Optional() bool { return true }
+
+func (s optionalSource) WeaklyTyped() bool { return isWeaklyTyped(s.Source) }
+
+func (s optionalSource) Kind() SourceKind { return sourceKind(s.Source) }
+
+// isOptional reports whether a failure to load src may be skipped.
+func isOptional(src Source) bool {
+	o, ok := src.(interface{ Optional() bool })
+	return ok && o.Optional()
+}
+
+type reportKey struct{}
+
+// withReport makes warnings found while loading sources go to report.
+func withReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// warn records a warning on the report carried by ctx, if any.
+func warn(ctx context.Context, path, format string, args ...any) {
+	if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+		report.add(path, SeverityWarning, format, args...)
+	}
+}
@@ -1,10 +1,14 @@
 package optionator
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,23 +21,89 @@ func New[T any](target T, opts ...Option[T]) (T, error) {
 	return NewWithConfig(target, defaultConfig, opts...)
 }
 
-// With returns an Option that sets a specific field to a given value.
+// With returns an Option that sets a specific field to a given value. The
+// field is found by name, by name ignoring case or by one of its aliases.
+// When the target is built with Config.IgnoreZeroOptions, a zero value is
+// ignored.
 func With[T any](fieldName string, value interface{}) Option[T] {
+	return with[T](fieldName, value, false)
+}
+
+// WithNonZero is like With but only sets the field when value is non-zero,
+// for applying overrides from an optional partial struct.
+func WithNonZero[T any](fieldName string, value interface{}) Option[T] {
+	return with[T](fieldName, value, true)
+}
+
+// WithChecked is like With but resolves the field on T and checks that
+// value can be assigned to it right away, so a misspelled field or a value
+// of the wrong type fails where the option is built rather than inside New.
+// Decode hooks are not consulted, since they depend on the Config given at
+// construction; values that need one should use With.
+func WithChecked[T any](fieldName string, value interface{}) (Option[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("target must be a pointer to a struct")
+	}
+	sf, ok := optionStructField(t.Elem(), defaultConfig, fieldName)
+	if !ok {
+		return nil, fmt.Errorf("no such field: %s", fieldName)
+	}
+	if sf.PkgPath != "" {
+		return nil, fmt.Errorf("cannot set field: %s", fieldName)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("cannot set field %s to nil", fieldName)
+	}
+	vt := reflect.TypeOf(value)
+	fitsArray := sf.Type.Kind() == reflect.Array && vt.Kind() == reflect.Slice && vt.Elem() == sf.Type.Elem()
+	if !fitsArray && !vt.ConvertibleTo(sf.Type) {
+		return nil, fmt.Errorf("cannot convert %v to %v", vt, sf.Type)
+	}
+	if fitsArray {
+		if n := reflect.ValueOf(value).Len(); n != sf.Type.Len() {
+			return nil, fmt.Errorf("cannot set field %s: %v needs %d elements, got %d", fieldName, sf.Type, sf.Type.Len(), n)
+		}
+	}
+	return With[T](fieldName, value), nil
+}
+
+func with[T any](fieldName string, value interface{}, skipZero bool) Option[T] {
 	return func(target T) error {
 		v := reflect.ValueOf(target)
 		// Ensure target is a pointer to a struct.
 		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 			return errors.New("target must be a pointer to a struct")
 		}
-		elem := v.Elem()
-		field := elem.FieldByName(fieldName)
+		config := configFor(target)
+		field := optionField(v.Elem(), config, fieldName)
 		if !field.IsValid() {
 			return fmt.Errorf("no such field: %s", fieldName)
 		}
 		if !field.CanSet() {
 			return fmt.Errorf("cannot set field: %s", fieldName)
 		}
+		if len(config.DecodeHooks) > 0 {
+			converted, err := runDecodeHooks(config.DecodeHooks, field.Type(), value)
+			if err != nil {
+				return fmt.Errorf("cannot set field %s: %w", fieldName, err)
+			}
+			value = converted
+		}
 		val := reflect.ValueOf(value)
+		if (!val.IsValid() || isZeroValue(val)) && (skipZero || config.IgnoreZeroOptions) {
+			return nil
+		}
+		if !val.IsValid() {
+			return fmt.Errorf("cannot set field %s to nil", fieldName)
+		}
+		if field.Kind() == reflect.Array && val.Kind() == reflect.Slice && val.Type().Elem() == field.Type().Elem() {
+			if val.Len() != field.Len() {
+				return fmt.Errorf("cannot set field %s: %v needs %d elements, got %d", fieldName, field.Type(), field.Len(), val.Len())
+			}
+			reflect.Copy(field, val)
+			return nil
+		}
 		// Ensure the provided value is convertible to the field's type.
 		if !val.Type().ConvertibleTo(field.Type()) {
 			return fmt.Errorf("cannot convert %v to %v", val.Type(), field.Type())
@@ -43,10 +113,168 @@ func With[T any](fieldName string, value interface{}) Option[T] {
 	}
 }
 
+// optionField finds the field of struct v that With names, or returns the
+// zero Value.
+func optionField(v reflect.Value, config Config, name string) reflect.Value {
+	sf, ok := optionStructField(v.Type(), config, name)
+	if !ok {
+		return reflect.Value{}
+	}
+	return v.FieldByIndex(sf.Index)
+}
+
+// optionStructField finds the field of struct type t that With names:
+// through the field index by exact name, then by reflect's FieldByName,
+// which also finds promoted fields and fields metadata leaves out, then
+// through the index ignoring case or by alias.
+func optionStructField(t reflect.Type, config Config, name string) (reflect.StructField, bool) {
+	index := getFieldIndex(t, config)
+	if i, ok := index.names[name]; ok {
+		return t.FieldByIndex(index.fields[i].Index), true
+	}
+	if sf, ok := t.FieldByName(name); ok {
+		return sf, true
+	}
+	if fm, _, ok := index.match(name); ok {
+		return t.FieldByIndex(fm.Index), true
+	}
+	return reflect.StructField{}, false
+}
+
+// WithText returns an Option that sets the field at a dotted path, such as
+// "Nested.Port", by parsing text the same way a default tag is parsed, after
+// the decode hooks of the Config being constructed with.
+// Nil pointers to nested structs along the path are allocated.
+func WithText[T any](path, text string) Option[T] {
+	return func(target T) error {
+		v := reflect.ValueOf(target)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return errors.New("target must be a pointer to a struct")
+		}
+		config := configFor(target)
+		fi, ok := lookupPath(v.Elem().Type(), config, path)
+		if !ok {
+			return fmt.Errorf("no such field: %s", path)
+		}
+		field := fieldByIndexes(v.Elem(), fi.indexes)
+		if err := setFromText(contextFor(target), field, text, fi.Type, path, config); err != nil {
+			return fmt.Errorf("error setting field %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// setFromText sets field, of type t, from text as a source value would be:
+// the decode hooks of config run first, then the text is parsed like a
+// default tag, with human numbers if config allows them.
+func setFromText(ctx context.Context, field reflect.Value, text string, t reflect.Type, path string, config Config) error {
+	if len(config.DecodeHooks) > 0 {
+		converted, err := runDecodeHooks(config.DecodeHooks, t, text)
+		if err != nil {
+			return err
+		}
+		s, ok := converted.(string)
+		if !ok {
+			return binder{config: config, ctx: ctx}.assign(field, converted, path)
+		}
+		text = s
+	}
+	if config.HumanNumbers {
+		text = humanNumber(text, t)
+	}
+	return parseAndSetDefault(field, text, t)
+}
+
+// WithForceDefault returns an Option that sets the field at a dotted path to
+// its default, whatever it holds, for example to normalize a deprecated
+// value. Fields tagged forcedefault:"true" get this treatment before sources
+// are loaded.
+func WithForceDefault[T any](path string) Option[T] {
+	return func(target T) error {
+		v := reflect.ValueOf(target)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return errors.New("target must be a pointer to a struct")
+		}
+		fi, ok := lookupPath(v.Elem().Type(), configFor(target), path)
+		if !ok {
+			return fmt.Errorf("no such field: %s", path)
+		}
+		if fi.Default == "" || isDerived(fi.Default) {
+			return fmt.Errorf("field %s has no plain default", path)
+		}
+		field := fieldByIndexes(v.Elem(), fi.indexes)
+		if err := parseAndSetDefault(field, fi.Default, fi.Type); err != nil {
+			return fmt.Errorf("error setting default for field %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// WithReset returns an Option that sets the fields at the given dotted paths
+// to their defaults, or to their zero value if they have none. Without
+// paths, the whole target is reset. Defaults are applied even when the
+// target is built with Config.SkipDefaults.
+func WithReset[T any](paths ...string) Option[T] {
+	return func(target T) error {
+		v := reflect.ValueOf(target)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return errors.New("target must be a pointer to a struct")
+		}
+		config := configFor(target)
+		config.SkipDefaults = false
+		fresh := reflect.New(v.Elem().Type()).Elem()
+		if err := setDefaultRecursively(context.Background(), fresh, config); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			v.Elem().Set(fresh)
+			return nil
+		}
+		for _, path := range paths {
+			fi, ok := lookupPath(v.Elem().Type(), config, path)
+			if !ok {
+				return fmt.Errorf("no such field: %s", path)
+			}
+			def, ok := lookupIndexes(fresh, fi.indexes)
+			if !ok {
+				def = reflect.Zero(fi.Type)
+			}
+			fieldByIndexes(v.Elem(), fi.indexes).Set(def)
+		}
+		return nil
+	}
+}
+
+// lookupPath finds the leaf field of struct type t at a dotted path.
+func lookupPath(t reflect.Type, config Config, path string) (FieldInfo, bool) {
+	described := describeCached(t, config)
+	if i, ok := described.paths[path]; ok {
+		return described.fields[i], true
+	}
+	return FieldInfo{}, false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// isParsable reports whether parseAndSetDefault can set a field of type t.
+func isParsable(t reflect.Type) bool {
+	if t == durationType || isTextType(t) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Func:
+		return true
+	}
+	return false
+}
+
 // parseAndSetDefault sets the default value on the field based on its kind.
 // It now accepts fieldType from metadata for enhanced type handling.
 func parseAndSetDefault(field reflect.Value, defaultTag string, fieldType reflect.Type) error {
-	if fieldType == reflect.TypeOf(time.Duration(0)) {
+	if fieldType == durationType {
 		d, err := time.ParseDuration(defaultTag)
 		if err != nil {
 			return err
@@ -55,6 +283,12 @@ func parseAndSetDefault(field reflect.Value, defaultTag string, fieldType reflec
 		field.SetInt(int64(d))
 		return nil
 	}
+	if isTextType(fieldType) {
+		return parseTextType(field, defaultTag)
+	}
+	if ok, err := unmarshalDefault(field, defaultTag); ok {
+		return err
+	}
 
 	switch field.Kind() {
 	case reflect.String:
@@ -64,18 +298,27 @@ func parseAndSetDefault(field reflect.Value, defaultTag string, fieldType reflec
 		if err != nil {
 			return err
 		}
+		if field.OverflowInt(i) {
+			return &rangeError{text: defaultTag, value: reflect.ValueOf(i), t: fieldType}
+		}
 		field.SetInt(i)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		ui, err := strconv.ParseUint(defaultTag, 10, 64)
 		if err != nil {
 			return err
 		}
+		if field.OverflowUint(ui) {
+			return &rangeError{text: defaultTag, value: reflect.ValueOf(ui), t: fieldType}
+		}
 		field.SetUint(ui)
 	case reflect.Float32, reflect.Float64:
 		f, err := strconv.ParseFloat(defaultTag, 64)
 		if err != nil {
 			return err
 		}
+		if field.OverflowFloat(f) {
+			return &rangeError{text: defaultTag, value: reflect.ValueOf(f), t: fieldType}
+		}
 		field.SetFloat(f)
 	case reflect.Bool:
 		b, err := strconv.ParseBool(defaultTag)
@@ -83,14 +326,177 @@ func parseAndSetDefault(field reflect.Value, defaultTag string, fieldType reflec
 			return err
 		}
 		field.SetBool(b)
+	case reflect.Func:
+		return setFunc(field, defaultTag)
+	case reflect.Slice:
+		return parseSlice(field, defaultTag)
+	case reflect.Array:
+		return parseArray(field, defaultTag)
+	case reflect.Map:
+		return parseMap(field, defaultTag)
 	default:
 		return fmt.Errorf("unsupported field type: %v", fieldType)
 	}
 	return nil
 }
 
-// isZeroValue checks if a value is zero.
+// rangeError is a number that parses but does not fit the field type. value
+// holds it at 64 bits, for callers that tolerate the truncation.
+type rangeError struct {
+	text  string
+	value reflect.Value
+	t     reflect.Type
+}
+
+func (e *rangeError) Error() string { return fmt.Sprintf("%s overflows %v", e.text, e.t) }
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unmarshalDefault sets field through its own decoding methods, if it has
+// any: UnmarshalJSON when text is a JSON object or array, and otherwise
+// UnmarshalText. It reports false for fields that have neither.
+func unmarshalDefault(field reflect.Value, text string) (bool, error) {
+	if !field.CanAddr() {
+		return false, nil
+	}
+	pt := field.Addr().Type()
+	if pt.Implements(jsonUnmarshalerType) && (strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")) {
+		v := reflect.New(field.Type())
+		if err := v.Interface().(json.Unmarshaler).UnmarshalJSON([]byte(text)); err != nil {
+			return true, err
+		}
+		field.Set(v.Elem())
+		return true, nil
+	}
+	if pt.Implements(textUnmarshalerType) {
+		v := reflect.New(field.Type())
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return true, err
+		}
+		field.Set(v.Elem())
+		return true, nil
+	}
+	return false, nil
+}
+
+// parseSlice sets a slice from comma-separated elements; "[]" is an empty,
+// non-nil slice. A []byte takes the text as is.
+func parseSlice(field reflect.Value, text string) error {
+	t := field.Type()
+	if t.Elem().Kind() == reflect.Uint8 {
+		field.SetBytes([]byte(text))
+		return nil
+	}
+	if !isParsable(t.Elem()) {
+		return fmt.Errorf("unsupported field type: %v", t)
+	}
+	if text == "[]" {
+		field.Set(reflect.MakeSlice(t, 0, 0))
+		return nil
+	}
+	parts := strings.Split(text, ",")
+	s := reflect.MakeSlice(t, len(parts), len(parts))
+	for i, p := range parts {
+		if err := parseAndSetDefault(s.Index(i), strings.TrimSpace(p), t.Elem()); err != nil {
+			return err
+		}
+	}
+	field.Set(s)
+	return nil
+}
+
+// parseArray sets an array from comma-separated elements or a JSON array,
+// which must have exactly as many elements as the array.
+func parseArray(field reflect.Value, text string) error {
+	t := field.Type()
+	if !isParsable(t.Elem()) {
+		return fmt.Errorf("unsupported field type: %v", t)
+	}
+	var parts []string
+	if strings.HasPrefix(text, "[") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return err
+		}
+		for _, r := range raw {
+			var s string
+			if json.Unmarshal(r, &s) != nil {
+				s = string(r)
+			}
+			parts = append(parts, s)
+		}
+	} else if text != "" {
+		parts = strings.Split(text, ",")
+	}
+	if len(parts) != t.Len() {
+		return fmt.Errorf("%v needs %d elements, got %d", t, t.Len(), len(parts))
+	}
+	a := reflect.New(t).Elem()
+	for i, p := range parts {
+		if err := parseAndSetDefault(a.Index(i), strings.TrimSpace(p), t.Elem()); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	field.Set(a)
+	return nil
+}
+
+// parseMap sets a map from comma-separated key=value pairs; "{}" is an
+// empty, non-nil map.
+func parseMap(field reflect.Value, text string) error {
+	t := field.Type()
+	if !isParsable(t.Key()) || !isParsable(t.Elem()) {
+		return fmt.Errorf("unsupported field type: %v", t)
+	}
+	m := reflect.MakeMap(t)
+	if text != "{}" {
+		for _, pair := range strings.Split(text, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid map entry %q, want key=value", pair)
+			}
+			key, elem := reflect.New(t.Key()).Elem(), reflect.New(t.Elem()).Elem()
+			if err := parseAndSetDefault(key, strings.TrimSpace(k), t.Key()); err != nil {
+				return err
+			}
+			if err := parseAndSetDefault(elem, strings.TrimSpace(v), t.Elem()); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+	}
+	field.Set(m)
+	return nil
+}
+
+// IsZeroer is implemented by types that know when they are unset, such as
+// time.Time or optional wrappers. Such fields get defaults and fail required
+// validation according to IsZero rather than a structural comparison.
+type IsZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*IsZeroer)(nil)).Elem()
+
+// isZeroValue checks if a value is zero. Values are only boxed to call
+// IsZero, so the check does not allocate for plain types.
 func isZeroValue(v reflect.Value) bool {
-	zero := reflect.Zero(v.Type())
-	return reflect.DeepEqual(v.Interface(), zero.Interface())
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return true
+	}
+	if v.Type().Implements(isZeroerType) && v.CanInterface() {
+		return v.Interface().(IsZeroer).IsZero()
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(isZeroerType) && v.CanInterface() {
+		return v.Addr().Interface().(IsZeroer).IsZero()
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		// Negative zero counts as zero, as it compares equal to it.
+		return v.Float() == 0
+	}
+	return v.IsZero()
 }
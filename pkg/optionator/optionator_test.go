@@ -1,8 +1,19 @@
 package optionator
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/json"
+	"flag"
+	htmltemplate "html/template"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"text/template"
 	"time"
 )
 
@@ -76,3 +87,1121 @@ func TestRequiredValidationFailure(t *testing.T) {
 		t.Errorf("Expected error due to required field Field1, but got none")
 	}
 }
+
+func TestTagCompatibility(t *testing.T) {
+	type Legacy struct {
+		Host    string `default:"localhost" envDefault:"example.com" env:"HOST,required"`
+		Port    int    `default:"8080" envDefault:"9090"`
+		Skipped string `default:"x" ignored:"true" env:"-" envDefault:"y"`
+	}
+	s, err := NewWithConfig(&Legacy{}, Config{TagCompatibility: CompatEnvconfig})
+	if err != nil {
+		t.Fatalf("envconfig mode: %v", err)
+	}
+	if s.Host != "localhost" || s.Port != 8080 || s.Skipped != "" {
+		t.Errorf("envconfig mode: unexpected values %+v", s)
+	}
+	// The same type must not reuse metadata cached for another mode.
+	c, err := NewWithConfig(&Legacy{}, Config{TagCompatibility: CompatCaarlos0})
+	if err != nil {
+		t.Fatalf("caarlos0 mode: %v", err)
+	}
+	if c.Host != "example.com" || c.Port != 9090 || c.Skipped != "" {
+		t.Errorf("caarlos0 mode: unexpected values %+v", c)
+	}
+	type Strict struct {
+		Token string `env:"TOKEN,notEmpty"`
+	}
+	if _, err := NewWithConfig(&Strict{}, Config{TagCompatibility: CompatCaarlos0}); err == nil {
+		t.Errorf("Expected error for notEmpty field Token, but got none")
+	}
+	if _, err := NewWithConfig(&Legacy{}, Config{TagCompatibility: "viper"}); err == nil {
+		t.Errorf("Expected error for unknown compatibility mode, but got none")
+	}
+
+	// EnvSource reads the variables the tags name.
+	env := map[string]string{"APP_DB_URL": "postgres://db", "PORT": "7000", "APP_CACHE_TTL": "5m"}
+	lookup := func(name string) (string, bool) { v, ok := env[name]; return v, ok }
+	type Database struct {
+		URL string `envconfig:"url"`
+	}
+	type Envconfig struct {
+		DB   Database `envconfig:"db"`
+		Port int      `envconfig:"port"`
+	}
+	e, err := NewWithConfig(&Envconfig{}, Config{TagCompatibility: CompatEnvconfig, LookupEnv: lookup,
+		Sources: []Source{EnvSource{Prefix: "APP_"}}})
+	if err != nil || e.DB.URL != "postgres://db" || e.Port != 7000 {
+		t.Errorf("envconfig names: got %+v, %v", e, err)
+	}
+	type Cache struct {
+		TTL time.Duration `env:"TTL"`
+	}
+	type Caarlos0 struct {
+		Cache Cache `envPrefix:"CACHE_"`
+	}
+	k, err := NewWithConfig(&Caarlos0{}, Config{TagCompatibility: CompatCaarlos0, LookupEnv: lookup,
+		Sources: []Source{EnvSource{Prefix: "APP_"}}})
+	if err != nil || k.Cache.TTL != 5*time.Minute {
+		t.Errorf("caarlos0 names: got %+v, %v", k, err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	fields, err := Describe[*Server]()
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	byPath := map[string]FieldInfo{}
+	for _, f := range fields {
+		byPath[f.Path] = f
+	}
+	if f := byPath["Nested.Port"]; f.Default != "8080" || !f.Required {
+		t.Errorf("Expected Nested.Port with default 8080 and required, got %+v", f)
+	}
+	if _, ok := byPath["Nested"]; ok {
+		t.Errorf("Expected nested struct to be flattened into leaf fields")
+	}
+	if _, err := Describe[int](); err == nil {
+		t.Errorf("Expected error describing a non-struct type")
+	}
+	type Event struct {
+		Name  string
+		Start time.Time `default:"2024-01-02T00:00:00Z"`
+		Addr  net.IP
+	}
+	leaves, err := Describe[Event]()
+	if err != nil || len(leaves) != 3 || leaves[1].Path != "Start" || leaves[1].Type != reflect.TypeOf(time.Time{}) {
+		t.Errorf("Expected time.Time to be a leaf field, got %+v, %v", leaves, err)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	opt, err := BindFlags[*Server](fs)
+	if err != nil {
+		t.Fatalf("BindFlags: %v", err)
+	}
+	if f := fs.Lookup("max-conns"); f == nil || f.DefValue != "100" {
+		t.Fatalf("Expected max-conns flag with default 100, got %+v", f)
+	}
+	if err := fs.Parse([]string{"-address", "10.0.0.1", "-nested.port", "9090", "-timeout", "5s"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	s, err := New(&Server{}, opt)
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.Address != "10.0.0.1" || s.Nested.Port != 9090 || s.Timeout != 5*time.Second {
+		t.Errorf("Flags not applied: %+v %+v", s, s.Nested)
+	}
+	if s.MaxConns != 100 || s.Nested.Host != "localhost" {
+		t.Errorf("Unset flags must keep defaults: %+v %+v", s, s.Nested)
+	}
+	if _, err := New(&Server{}, WithFlags[*Server](defaultConfig, map[string]string{"max-conn": "5"})); err == nil || !strings.Contains(err.Error(), "no such field") {
+		t.Errorf("Expected an unknown flag to fail, got %v", err)
+	}
+
+	type Documented struct {
+		Region string `desc:"Deployment region" example:"eu-west-1" docs:"https://example.com/regions" required:"true"`
+	}
+	fs = flag.NewFlagSet("doc", flag.ContinueOnError)
+	if _, err := BindFlags[*Documented](fs); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fs.Lookup("region").Usage, "Deployment region (string), e.g. eu-west-1 [required]; see https://example.com/regions"; got != want {
+		t.Errorf("usage = %q, want %q", got, want)
+	}
+
+	type Listener struct {
+		Port int `default:"80"`
+	}
+	type Grouped struct {
+		Name    string
+		Timeout time.Duration `group:"Networking"`
+		HTTP    Listener
+		Admin   Listener `group:"Networking"`
+	}
+	fs = flag.NewFlagSet("grouped", flag.ContinueOnError)
+	if _, err := BindFlags[*Grouped](fs); err != nil {
+		t.Fatal(err)
+	}
+	fs.Bool("verbose", false, "log more")
+	var buf strings.Builder
+	fs.SetOutput(&buf)
+	GroupedUsage[*Grouped](fs)()
+	out := buf.String()
+	var order []int
+	for _, want := range []string{"-name", "\nNetworking:\n", "-timeout", "-admin.port", "\nHTTP:\n", "-http.port", "\nOther:\n", "-verbose"} {
+		order = append(order, strings.Index(out, want))
+	}
+	if !sort.IntsAreSorted(order) || order[0] < 0 {
+		t.Errorf("unexpected grouped usage:\n%s", out)
+	}
+	type Pool struct {
+		MaxConns int `default:"10"`
+	}
+	type Tuned struct {
+		ReadTimeout time.Duration `group:"Networking"`
+		DBPool      Pool
+	}
+	snake := defaultConfig
+	snake.NamingStrategy = SnakeCase
+	fs = flag.NewFlagSet("tuned", flag.ContinueOnError)
+	if _, err := BindFlagsWithConfig[*Tuned](fs, snake); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	fs.SetOutput(&buf)
+	GroupedUsageWithConfig[*Tuned](fs, snake)()
+	if out := buf.String(); !strings.Contains(out, "\nNetworking:\n  -read_timeout") || !strings.Contains(out, "\nDBPool:\n  -db_pool.max_conns") || strings.Contains(out, "Other:") {
+		t.Errorf("named flags must stay in their groups:\n%s", out)
+	}
+
+	type Knobs struct {
+		Public   int
+		Beta     int      `hidden:"true"`
+		Internal Listener `hidden:"true"`
+	}
+	fs = flag.NewFlagSet("knobs", flag.ContinueOnError)
+	if _, err := BindFlags[*Knobs](fs); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("public") == nil || fs.Lookup("beta") != nil || fs.Lookup("internal.port") != nil {
+		t.Errorf("hidden fields must not get flags")
+	}
+	k, err := New(&Knobs{}, WithText[*Knobs]("Beta", "7"))
+	if err != nil || k.Beta != 7 || k.Internal.Port != 80 {
+		t.Errorf("hidden fields must stay settable: %+v, %v", k, err)
+	}
+	config := defaultConfig
+	config.HumanNumbers = true
+	config.DecodeHooks = []DecodeHook{func(from, to reflect.Type, data any) (any, bool, error) {
+		if s, ok := data.(string); ok && to == durationType && s == "forever" {
+			return time.Duration(1 << 62), true, nil
+		}
+		return data, false, nil
+	}}
+	s, err = NewWithConfig(&Server{}, config, WithText[*Server]("MaxConns", "1_000"), WithText[*Server]("Timeout", "forever"))
+	if err != nil || s.MaxConns != 1000 || s.Timeout != 1<<62 {
+		t.Errorf("WithText must use the construction's Config: %+v, %v", s, err)
+	}
+}
+
+func TestRegisterSchema(t *testing.T) {
+	type Edge struct {
+		Name    string
+		Retries int
+		Backoff time.Duration
+	}
+	RegisterSchema(
+		Accessor("Name", "", true, func(e *Edge) *string { return &e.Name }, func(s string) (string, error) { return s, nil }),
+		Accessor("Retries", "3", false, func(e *Edge) *int { return &e.Retries }, strconv.Atoi),
+		Accessor("Backoff", "1s", false, func(e *Edge) *time.Duration { return &e.Backoff }, time.ParseDuration),
+	)
+	setName := func(e *Edge) error { e.Name = "edge-1"; return nil }
+	e, err := New(&Edge{}, setName)
+	if err != nil {
+		t.Fatalf("Error creating edge: %v", err)
+	}
+	if e.Name != "edge-1" || e.Retries != 3 || e.Backoff != time.Second {
+		t.Errorf("Unexpected values %+v", e)
+	}
+	if _, err := New(&Edge{}); err == nil {
+		t.Errorf("Expected error due to required field Name, but got none")
+	}
+}
+
+func TestStats(t *testing.T) {
+	ResetStats()
+	config := defaultConfig
+	config.CollectStats = true
+	for i := 0; i < 3; i++ {
+		opts := []Option[*Server]{}
+		if i == 0 {
+			opts = append(opts, With[*Server]("MaxConns", 5))
+		}
+		if _, err := NewWithConfig(&Server{}, config, opts...); err != nil {
+			t.Fatalf("Error creating server: %v", err)
+		}
+	}
+	got := Stats()["optionator.Server"]
+	if got["MaxConns"] != (FieldStats{Defaulted: 2, Overridden: 1}) {
+		t.Errorf("Unexpected MaxConns stats %+v", got["MaxConns"])
+	}
+	if got["Nested.Port"] != (FieldStats{Defaulted: 3}) {
+		t.Errorf("Unexpected Nested.Port stats %+v", got["Nested.Port"])
+	}
+	if _, ok := got["TLSConfig.ServerName"]; ok {
+		t.Errorf("Fields without defaults must not be counted")
+	}
+}
+
+func TestValuesAndFingerprint(t *testing.T) {
+	a, err := New(&Server{TLSConfig: &tls.Config{}})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	b, err := New(&Server{TLSConfig: &tls.Config{}})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	values, err := Values(a)
+	if err != nil {
+		t.Fatalf("Values: %v", err)
+	}
+	if values["Nested.Port"] != 8080 || values["Timeout"] != 30*time.Second {
+		t.Errorf("Unexpected values %v", values)
+	}
+	fa, _ := Fingerprint(a)
+	fb, _ := Fingerprint(b)
+	if fa != fb {
+		t.Errorf("Expected equal configs to share a fingerprint, got %s and %s", fa, fb)
+	}
+	b.MaxConns++
+	if fb, _ = Fingerprint(b); fa == fb {
+		t.Errorf("Expected fingerprint to change with MaxConns")
+	}
+	type Window struct{ Start time.Time }
+	f2024, _ := Fingerprint(Window{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	f2040, _ := Fingerprint(Window{time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if f2024 == f2040 {
+		t.Errorf("Expected fingerprint to change with a time.Time field")
+	}
+
+	type Creds struct {
+		User string
+		PIN  int `secret:"true"`
+	}
+	f1, _ := Fingerprint(Creds{"svc", 1234})
+	f2, _ := Fingerprint(Creds{"svc", 4321})
+	if f1 != f2 {
+		t.Errorf("Expected secrets to be left out of the fingerprint without a key")
+	}
+	SetSecretHashKey([]byte("fleet"))
+	defer SetSecretHashKey(nil)
+	f1, _ = Fingerprint(Creds{"svc", 1234})
+	f2, _ = Fingerprint(Creds{"svc", 4321})
+	if f1 == f2 {
+		t.Errorf("Expected keyed secrets to change the fingerprint")
+	}
+
+	type Ring struct {
+		Name string
+		Next *Ring
+	}
+	ring := &Ring{Name: "a", Next: &Ring{Name: "b"}}
+	ring.Next.Next = ring
+	fr, err := Fingerprint(ring)
+	if err != nil || fr == "" {
+		t.Errorf("Fingerprint of a cycle: %q, %v", fr, err)
+	}
+	ring.Next.Name = "c"
+	if fc, _ := Fingerprint(ring); fc == fr {
+		t.Errorf("Expected fingerprint to change within a cycle")
+	}
+}
+
+type recordingTracer struct{ spans []string }
+
+type recordingSpan struct {
+	t    *recordingTracer
+	name string
+}
+
+func (r *recordingTracer) Start(name string) Span { return &recordingSpan{r, name} }
+
+func (s *recordingSpan) SetAttribute(key string, value any) {}
+
+func (s *recordingSpan) End(err error) {
+	if err != nil {
+		s.t.spans = append(s.t.spans, s.name+"!")
+		return
+	}
+	s.t.spans = append(s.t.spans, s.name)
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	config := defaultConfig
+	config.Tracer = tracer
+	if _, err := NewWithConfig(&Server{}, config, With[*Server]("MaxConns", 1), With[*Server]("Missing", 1)); err == nil {
+		t.Fatalf("Expected error for missing field")
+	}
+	want := "defaults option option! optionator.New!"
+	if got := strings.Join(tracer.spans, " "); got != want {
+		t.Errorf("Expected spans %q, got %q", want, got)
+	}
+}
+
+func TestPhaseTimes(t *testing.T) {
+	var times []PhaseTimes
+	config := defaultConfig
+	config.ProfileLabels = true
+	config.OnPhaseTimes = func(pt PhaseTimes) { times = append(times, pt) }
+	slow := func(s *Server) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	if _, err := NewWithConfig(&Server{}, config, slow); err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 1 || times[0].Type != "optionator.Server" || times[0].Options < time.Millisecond || times[0].Total < times[0].Options || times[0].Err != nil {
+		t.Errorf("times = %+v", times)
+	}
+}
+
+func TestNewValue(t *testing.T) {
+	type Pool struct {
+		Size  int `default:"4"`
+		Hosts []string
+		Inner *NestedConfig
+	}
+	proto := Pool{Hosts: []string{"a"}}
+	p, err := NewValue(proto, With[*Pool]("Size", 8), func(p *Pool) error {
+		p.Hosts[0] = "changed"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error creating pool: %v", err)
+	}
+	if p.Size != 8 || p.Inner == nil || p.Inner.Port != 8080 || p.Hosts[0] != "changed" {
+		t.Errorf("Unexpected result %+v", p)
+	}
+	if proto.Size != 0 || proto.Inner != nil || proto.Hosts[0] != "a" {
+		t.Errorf("Expected prototype to be untouched, got %+v", proto)
+	}
+
+	// A pointer to a struct and one to its first field share an address.
+	type Aliased struct {
+		Inner *NestedConfig
+		Port  *int
+	}
+	inner := &NestedConfig{Port: 1}
+	a, err := NewValue(Aliased{Inner: inner, Port: &inner.Port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Inner == inner || a.Port == &inner.Port || *a.Port != 1 {
+		t.Errorf("Unexpected copy %+v", a)
+	}
+}
+
+func TestNewDynamic(t *testing.T) {
+	RegisterDynamicSchema("cache-plugin", DynamicSchema{Fields: []DynamicField{
+		{Name: "ttl", Type: reflect.TypeOf(time.Duration(0)), Default: "1m"},
+		{Name: "size", Type: reflect.TypeOf(0), Default: "128"},
+		{Name: "backend", Type: reflect.TypeOf(""), Required: true},
+	}})
+	cfg, err := NewDynamic("cache-plugin", map[string]any{"size": 256.0, "extra": true},
+		WithEntry("backend", "redis"))
+	if err != nil {
+		t.Fatalf("NewDynamic: %v", err)
+	}
+	want := map[string]any{"ttl": time.Minute, "size": 256, "backend": "redis", "extra": true}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Expected %v, got %v", want, cfg)
+	}
+	if _, err := NewDynamic("cache-plugin", nil); err == nil {
+		t.Errorf("Expected error due to required key backend, but got none")
+	}
+	if _, err := NewDynamic("cache-plugin", map[string]any{"backend": "x", "size": "big"}); err == nil {
+		t.Errorf("Expected error converting size")
+	}
+}
+
+func TestDerivedDefaults(t *testing.T) {
+	type Endpoint struct {
+		URL  string `default:"${Base}/v1"`
+		Base string `default:"http://${Host}:${Port}"`
+		Host string `default:"localhost"`
+		Port int    `default:"8080"`
+	}
+	cfg, err := New(&Endpoint{Host: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.URL != "http://example.com:8080/v1" {
+		t.Errorf("URL = %q", cfg.URL)
+	}
+
+	type E struct {
+		URL  string `default:"http://${Host}/v1"`
+		Host string `default:"localhost"`
+	}
+	e, err := New(&E{}, With[*E]("Host", "example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.URL != "http://example.com/v1" {
+		t.Errorf("URL = %q after option", e.URL)
+	}
+	e, err = New(&E{}, With[*E]("Host", "example.com"), With[*E]("URL", "https://api"))
+	if err != nil || e.URL != "https://api" {
+		t.Errorf("explicit URL = %q, %v", e.URL, err)
+	}
+
+	type Cycle struct {
+		A string `default:"${B}"`
+		B string `default:"${A}"`
+	}
+	_, err = New(&Cycle{})
+	if err == nil || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestWithChecked(t *testing.T) {
+	opt, err := WithChecked[*Server]("MaxConns", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := New(&Server{}, opt); err != nil || s.MaxConns != 7 {
+		t.Errorf("got %+v, %v", s, err)
+	}
+	for _, tc := range []struct {
+		field string
+		value any
+		want  string
+	}{
+		{"MaxConn", 7, "no such field: MaxConn"},
+		{"MaxConns", "seven", "cannot convert string to int"},
+		{"Timeout", nil, "cannot set field Timeout to nil"},
+	} {
+		if _, err := WithChecked[*Server](tc.field, tc.value); err == nil || err.Error() != tc.want {
+			t.Errorf("WithChecked(%q, %v) = %v, want %q", tc.field, tc.value, err, tc.want)
+		}
+	}
+	if _, err := WithChecked[Server]("MaxConns", 7); err == nil {
+		t.Error("expected an error for a non-pointer target type")
+	}
+}
+
+func TestZeroOptions(t *testing.T) {
+	cfg, err := New(&NestedConfig{}, WithNonZero[*NestedConfig]("Port", 0), WithNonZero[*NestedConfig]("Host", "example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.Host != "example.com" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	config := defaultConfig
+	config.IgnoreZeroOptions = true
+	cfg, err = NewWithConfig(&NestedConfig{}, config, With[*NestedConfig]("Host", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "localhost" {
+		t.Errorf("Host = %q, want the default", cfg.Host)
+	}
+	if _, err := New(&NestedConfig{}, With[*NestedConfig]("Host", "")); err == nil {
+		t.Error("expected the zero Host to fail validation")
+	}
+}
+
+func TestWithOverlay(t *testing.T) {
+	type Limits struct {
+		Burst int `default:"10"`
+		Rate  int `default:"100"`
+	}
+	type Service struct {
+		Name   string `default:"svc"`
+		Tags   []string
+		Limits Limits
+		Start  time.Time
+	}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	partial := &Service{Tags: []string{"a"}, Limits: Limits{Rate: 5}, Start: start}
+	cfg, err := New(&Service{}, WithOverlay(partial))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Service{Name: "svc", Tags: []string{"a"}, Limits: Limits{Burst: 10, Rate: 5}, Start: start}
+	if !reflect.DeepEqual(*cfg, want) {
+		t.Errorf("got %+v, want %+v", *cfg, want)
+	}
+	partial.Tags[0] = "b"
+	if cfg.Tags[0] != "a" {
+		t.Error("overlay shares the partial's slice")
+	}
+}
+
+func TestFuncFields(t *testing.T) {
+	type Backoff func(attempt int) time.Duration
+	RegisterFunc("constant", Backoff(func(int) time.Duration { return time.Second }))
+	RegisterFunc("exponential", Backoff(func(n int) time.Duration { return time.Second << n }))
+	type Retry struct {
+		Backoff Backoff `default:"exponential"`
+	}
+	cfg, err := New(&Retry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Backoff(3); got != 8*time.Second {
+		t.Errorf("Backoff(3) = %v", got)
+	}
+	cfg, err = New(&Retry{}, WithText[*Retry]("Backoff", "constant"))
+	if err != nil || cfg.Backoff(3) != time.Second {
+		t.Errorf("WithText: %v", err)
+	}
+	if _, err := New(&Retry{}, WithText[*Retry]("Backoff", "linear")); err == nil {
+		t.Error("expected an error for an unregistered name")
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	type CLI struct {
+		LogLevel string `oneof:"debug,info" desc:"log level"`
+		Config   string `complete:"file"`
+		Verbose  bool
+	}
+	comps, err := Completions[CLI]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Completion{
+		{Flag: "log-level", Description: "log level", Values: []string{"debug", "info"}},
+		{Flag: "config", Hint: HintFile},
+		{Flag: "verbose", Bool: true},
+	}
+	if !reflect.DeepEqual(comps, want) {
+		t.Errorf("got %+v", comps)
+	}
+	var fish strings.Builder
+	if err := WriteCompletion(&fish, "fish", "app", comps); err != nil {
+		t.Fatal(err)
+	}
+	wantFish := "complete -c app -o log-level -d 'log level' -x -a 'debug info'\ncomplete -c app -o config -r -F\ncomplete -c app -o verbose\n"
+	if fish.String() != wantFish {
+		t.Errorf("fish:\n%s", fish.String())
+	}
+	for _, shell := range []string{"bash", "zsh"} {
+		var b strings.Builder
+		if err := WriteCompletion(&b, shell, "app", comps); err != nil || !strings.Contains(b.String(), "log-level") {
+			t.Errorf("%s: %v\n%s", shell, err, b.String())
+		}
+	}
+}
+
+func TestForceDefault(t *testing.T) {
+	type Legacy struct {
+		Codec  string `default:"zstd" forcedefault:"true"`
+		Format string `default:"v2"`
+	}
+	cfg, err := New(&Legacy{Codec: "lz4", Format: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Codec != "zstd" || cfg.Format != "v1" {
+		t.Errorf("got %+v", cfg)
+	}
+	cfg, err = New(&Legacy{Format: "v1"}, WithForceDefault[*Legacy]("Format"))
+	if err != nil || cfg.Format != "v2" {
+		t.Errorf("got %+v, %v", cfg, err)
+	}
+}
+
+type StorageConfig interface{ storage() }
+
+type S3Storage struct {
+	Bucket string `required:"true"`
+	Region string `default:"us-east-1"`
+}
+
+func (*S3Storage) storage() {}
+
+type LocalStorage struct {
+	Dir string `default:"/var/lib/app"`
+}
+
+func (*LocalStorage) storage() {}
+
+func TestKinds(t *testing.T) {
+	RegisterKind("s3", func() StorageConfig { return &S3Storage{} })
+	RegisterKind("local", func() StorageConfig { return &LocalStorage{} })
+	type App struct {
+		StorageKind string        `default:"local"`
+		Storage     StorageConfig `kind:"s3|local"`
+	}
+	cfg, err := New(&App{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local, ok := cfg.Storage.(*LocalStorage); !ok || local.Dir != "/var/lib/app" {
+		t.Errorf("Storage = %#v", cfg.Storage)
+	}
+
+	src := MapSource{Values: map[string]any{"Storage": map[string]any{"kind": "s3", "Bucket": "logs"}}}
+	cfg, err = NewWithSources(&App{}, defaultConfig, []Source{src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s3, ok := cfg.Storage.(*S3Storage); !ok || s3.Bucket != "logs" || s3.Region != "us-east-1" || cfg.StorageKind != "s3" {
+		t.Errorf("Storage = %#v, kind %q", cfg.Storage, cfg.StorageKind)
+	}
+
+	_, err = New(&App{}, With[*App]("StorageKind", "s3"))
+	if err == nil || !strings.Contains(err.Error(), "Bucket is zero") {
+		t.Errorf("switching kind by option: got %v", err)
+	}
+	_, err = New(&App{StorageKind: "gcs"})
+	if err == nil || !strings.Contains(err.Error(), `unknown kind "gcs"`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	type LegacyTLS struct {
+		Cert string
+	}
+	type Legacy struct {
+		Addr     string
+		MaxConns int32
+		Tags     []string
+		TLS      LegacyTLS
+		Unused   bool
+	}
+	type TLS struct {
+		CertFile string
+	}
+	type Server struct {
+		Address  string `required:"true"`
+		MaxConns int    `default:"100"`
+		Timeout  string `default:"30s"`
+		Tags     []string
+		TLS      TLS
+	}
+	legacy := &Legacy{Addr: "10.0.0.1", MaxConns: 8, Tags: []string{"a"}, TLS: LegacyTLS{Cert: "c.pem"}}
+	cfg, err := New(&Server{}, CopyFrom[*Server](legacy,
+		FieldMap{From: "Addr", To: "Address"},
+		FieldMap{From: "TLS.Cert", To: "TLS.CertFile"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Address != "10.0.0.1" || cfg.MaxConns != 8 || cfg.Timeout != "30s" || cfg.TLS.CertFile != "c.pem" {
+		t.Errorf("got %+v", cfg)
+	}
+	legacy.Tags[0] = "b"
+	if cfg.Tags[0] != "a" {
+		t.Errorf("Tags shares storage with the source")
+	}
+
+	if _, err := New(&Server{}, CopyFrom[*Server](Legacy{})); err == nil || !strings.Contains(err.Error(), "required field Address") {
+		t.Errorf("expected validation to run after copying, got %v", err)
+	}
+	if _, err := New(&Server{}, CopyFrom[*Server](legacy, FieldMap{From: "Addr", To: "Missing"})); err == nil {
+		t.Errorf("expected error for a mapping to an unknown field")
+	}
+}
+
+type point struct{ X, Y int }
+
+func (p *point) UnmarshalJSON(data []byte) error {
+	var xy [2]int
+	if err := json.Unmarshal(data, &xy); err != nil {
+		return err
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+func TestUnmarshalerDefaults(t *testing.T) {
+	type Config struct {
+		Origin point     `default:"[3, 4]"`
+		IP     net.IP    `default:"10.0.0.1"`
+		Epoch  time.Time `default:"2024-01-02T00:00:00Z"`
+	}
+	cfg, err := New(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Origin != (point{3, 4}) || !cfg.IP.Equal(net.IPv4(10, 0, 0, 1)) || cfg.Epoch.Year() != 2024 {
+		t.Errorf("got %+v", cfg)
+	}
+	type Bad struct {
+		Origin point `default:"[3, \"x\"]"`
+	}
+	if _, err := New(&Bad{}); err == nil {
+		t.Errorf("expected error for an invalid JSON default")
+	}
+}
+
+func TestArrays(t *testing.T) {
+	type Config struct {
+		Mask  [4]byte   `default:"255,255,255,0"`
+		Pair  [2]string `default:"[\"a\", \"b\"]"`
+		Ports [2]int    `max:"65535"`
+	}
+	cfg, err := New(&Config{}, With[*Config]("Ports", []int{80, 443}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mask != [4]byte{255, 255, 255, 0} || cfg.Pair != [2]string{"a", "b"} || cfg.Ports != [2]int{80, 443} {
+		t.Errorf("got %+v", cfg)
+	}
+	if _, err := New(&Config{}, With[*Config]("Ports", []int{80})); err == nil {
+		t.Errorf("expected error for a short slice")
+	}
+	_, err = New(&Config{}, With[*Config]("Ports", [2]int{80, 70000}))
+	if err == nil || !strings.Contains(err.Error(), "invalid field Ports: element 1: 70000 is above the maximum") {
+		t.Errorf("got %v", err)
+	}
+	type Short struct {
+		Mask [4]byte `default:"255,0"`
+	}
+	if _, err := New(&Short{}); err == nil {
+		t.Errorf("expected error for a default with too few elements")
+	}
+	src := MapSource{Values: map[string]any{"Pair": []any{"x", "y"}}}
+	if cfg, err := NewWithSources(&Config{}, defaultConfig, []Source{src}); err != nil || cfg.Pair != [2]string{"x", "y"} {
+		t.Errorf("got %+v, %v", cfg, err)
+	}
+}
+
+func TestSkipDefaults(t *testing.T) {
+	config := defaultConfig
+	config.SkipDefaults = true
+	type Config struct {
+		Host string `default:"localhost"`
+		Port int    `default:"8080"`
+		Name string
+	}
+	cfg, err := NewWithConfig(&Config{}, config, With[*Config]("Name", "x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "" || cfg.Port != 0 {
+		t.Errorf("defaults applied: %+v", cfg)
+	}
+	fields, _ := DescribeWithConfig[Config](config)
+	if fields[0].Default != "localhost" {
+		t.Errorf("Describe lost the default: %+v", fields[0])
+	}
+
+	cfg, err = NewWithConfig(&Config{Port: 1}, config, WithReset[*Config]("Host", "Name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 1 || cfg.Name != "" {
+		t.Errorf("WithReset(paths): %+v", cfg)
+	}
+	cfg, _ = NewWithConfig(&Config{Port: 1, Name: "x"}, config, WithReset[*Config]())
+	if *cfg != (Config{Host: "localhost", Port: 8080}) {
+		t.Errorf("WithReset(): %+v", cfg)
+	}
+
+	_, err = NewWithConfig(&Server{}, config)
+	if err == nil || !strings.Contains(err.Error(), "required field Address") {
+		t.Errorf("expected required fields to need explicit values, got %v", err)
+	}
+}
+
+func TestNonDefaultFields(t *testing.T) {
+	type Config struct {
+		Host     string `default:"localhost"`
+		Port     int    `default:"8080"`
+		URL      string `default:"http://${Host}:${Port}"`
+		Password string `secret:"true"`
+		Nested   NestedConfig
+	}
+	cfg, err := New(&Config{}, With[*Config]("Port", 9090), With[*Config]("Password", "pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := NonDefaultFields(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FieldChange{
+		{Path: "Password", Value: Redacted, Default: Redacted},
+		{Path: "Port", Value: 9090, Default: 8080},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v", changes)
+	}
+}
+
+func TestSecretMasks(t *testing.T) {
+	type Keys struct {
+		APIKey  string `secret:"last4"`
+		Account string `secret:"hash"`
+		Pin     string `secret:"last4"`
+		Token   string `secret:"true"`
+	}
+	cfg := &Keys{APIKey: "sk-live-abcd1234", Account: "test", Pin: "1234", Token: "t"}
+	got, err := ToMap(cfg, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"APIKey":  "****1234",
+		"Account": "sha256:9f86d081884c",
+		"Pin":     Redacted,
+		"Token":   Redacted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToMap = %v", got)
+	}
+
+	SetSecretHashKey([]byte("support"))
+	defer SetSecretHashKey(nil)
+	got, err = ToMap(cfg, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["Account"] != "hmac:28e1559abcf2" {
+		t.Errorf("keyed hash = %v", got["Account"])
+	}
+}
+
+func TestRateAndPercent(t *testing.T) {
+	type Limits struct {
+		Requests Rate    `default:"5k/min" max:"100/s"`
+		Burst    Rate    `default:"10"`
+		Sample   Percent `default:"12.5%" max:"100%"`
+	}
+	cfg, err := New(&Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Requests.PerSecond() < 83.33 || cfg.Requests.PerSecond() > 83.34 || cfg.Burst != 10 || cfg.Sample.Fraction() != 0.125 {
+		t.Errorf("got %+v", cfg)
+	}
+	if cfg.Burst.Every() != 100*time.Millisecond || cfg.Sample.String() != "12.5%" {
+		t.Errorf("Every = %v, String = %v", cfg.Burst.Every(), cfg.Sample)
+	}
+	src := MapSource{Values: map[string]any{"Requests": "2/ms"}}
+	if _, err := NewWithSources(&Limits{}, defaultConfig, []Source{src}); err == nil || !strings.Contains(err.Error(), "above the maximum 100/s") {
+		t.Errorf("got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, cfg, ExportOptions{}); err != nil || !strings.Contains(buf.String(), `"Sample": "12.5%"`) {
+		t.Errorf("WriteJSON: %s, %v", buf.String(), err)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	type Schedule struct {
+		Zone  *time.Location `default:"America/New_York"`
+		Other *time.Location
+	}
+	cfg, err := New(&Schedule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Zone == nil || cfg.Zone.String() != "America/New_York" || cfg.Other != nil {
+		t.Fatalf("got %+v", cfg)
+	}
+	src := MapSource{Values: map[string]any{"Other": "Europe/Paris"}}
+	if cfg, err := NewWithSources(&Schedule{}, defaultConfig, []Source{src}); err != nil || cfg.Other.String() != "Europe/Paris" {
+		t.Errorf("got %+v, %v", cfg, err)
+	}
+	src = MapSource{Values: map[string]any{"Other": "Mars/Olympus"}}
+	if _, err := NewWithSources(&Schedule{}, defaultConfig, []Source{src}); err == nil {
+		t.Errorf("expected error for an unknown zone")
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, cfg, ExportOptions{}); err != nil || !strings.Contains(buf.String(), `"Zone": "America/New_York"`) {
+		t.Errorf("WriteJSON: %s, %v", buf.String(), err)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	type Filter struct {
+		Include *regexp.Regexp `default:"^api/.*$"`
+		Exclude *regexp.Regexp
+	}
+	cfg, err := New(&Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Include.MatchString("api/users") || cfg.Exclude != nil {
+		t.Errorf("got %+v", cfg)
+	}
+	src := MapSource{Values: map[string]any{"Exclude": "(unclosed"}}
+	_, err = NewWithSources(&Filter{}, defaultConfig, []Source{src})
+	if err == nil || !strings.Contains(err.Error(), "field Exclude: error parsing regexp") {
+		t.Errorf("got %v", err)
+	}
+	type Bad struct {
+		Nested struct {
+			Match *regexp.Regexp `default:"a(b"`
+		}
+	}
+	if _, err := New(&Bad{}); err == nil || !strings.Contains(err.Error(), "field Match") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	type Notify struct {
+		Subject *template.Template     `default:"Alert: {{.Name}}"`
+		Body    *htmltemplate.Template `default:"<p>{{.Body}}</p>"`
+	}
+	cfg, err := New(&Notify{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := cfg.Subject.Execute(&buf, map[string]string{"Name": "disk"}); err != nil || buf.String() != "Alert: disk" {
+		t.Errorf("Subject = %q, %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err := cfg.Body.Execute(&buf, map[string]string{"Body": "<b>"}); err != nil || buf.String() != "<p>&lt;b&gt;</p>" {
+		t.Errorf("Body = %q, %v", buf.String(), err)
+	}
+	src := MapSource{Values: map[string]any{"Subject": "{{.Name"}}
+	if _, err := NewWithSources(&Notify{}, defaultConfig, []Source{src}); err == nil || !strings.Contains(err.Error(), "field Subject") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	parent, err := New(&Server{}, With[*Server]("Address", "10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := Derive(parent, With[*Server]("MaxConns", 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child == parent || child.Address != "10.0.0.1" || child.MaxConns != 5 || parent.MaxConns != 100 {
+		t.Errorf("got child %+v of parent %+v", child, parent)
+	}
+	child.Nested.Port = 1
+	if parent.Nested.Port == 1 {
+		t.Error("child must not share nested values with its parent")
+	}
+	if p, ok := ParentOf(child); !ok || p != parent {
+		t.Errorf("ParentOf = %v, %v", p, ok)
+	}
+	if got := Provenance(child, "Address"); got != ProvenanceInherited {
+		t.Errorf("Address provenance = %q", got)
+	}
+	if got := Provenance(child, "MaxConns"); got != ProvenanceOverridden {
+		t.Errorf("MaxConns provenance = %q", got)
+	}
+	if got := Provenance(parent, "Address"); got != "" {
+		t.Errorf("parent provenance = %q", got)
+	}
+	if _, err := Derive(parent, With[*Server]("MaxConns", "x")); err == nil {
+		t.Error("expected an option error")
+	}
+
+	// The child keeps the Config it was derived with.
+	config := defaultConfig
+	config.HumanNumbers = true
+	human, err := DeriveWithConfig(parent, config, WithText[*Server]("MaxConns", "1_000"))
+	if err != nil || human.MaxConns != 1000 {
+		t.Fatalf("got %+v, %v", human, err)
+	}
+	grandchild, err := Derive(human, WithText[*Server]("MaxConns", "2_000"))
+	if err != nil || grandchild.MaxConns != 2000 {
+		t.Errorf("got %+v, %v", grandchild, err)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	type Generated struct {
+		Address string `json:"address" default:"127.0.0.1"`
+		Port    int
+		Mode    string
+	}
+	Define[*Generated]().
+		Field("Address").Default("0.0.0.0").Required().
+		Field("Port").Default("8080").Min(1).Max(65535).
+		Field("Mode").OneOf("fast", "safe").Desc("Processing mode")
+	g, err := New(&Generated{})
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+	if g.Address != "0.0.0.0" || g.Port != 8080 {
+		t.Errorf("Expected defined defaults to override tags, got %+v", g)
+	}
+	if _, err := New(&Generated{}, With[*Generated]("Port", 0)); err == nil {
+		t.Errorf("Expected defined min bound to be checked")
+	}
+	if _, err := New(&Generated{}, With[*Generated]("Mode", "slow")); err == nil {
+		t.Errorf("Expected defined oneof to be checked")
+	}
+	fields, err := Describe[*Generated]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fields[0].Required || fields[0].tag.Get("json") != "address" || fields[2].Description != "Processing mode" {
+		t.Errorf("Expected defined metadata merged with tags, got %+v", fields)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected Field to panic for an unknown field")
+		}
+	}()
+	Define[Generated]().Field("Missing")
+}
+
+// vendorPool stands in for a struct from another package.
+type vendorPool struct {
+	MaxIdle int `json:"max_idle"`
+	Name    string
+}
+
+func TestRegisterOverlay(t *testing.T) {
+	type App struct {
+		Pool *vendorPool
+	}
+	RegisterOverlay[vendorPool](map[string]string{
+		"MaxIdle": `default:"4" min:"1" desc:"Idle connections kept"`,
+		"Name":    `required:"true"`,
+	})
+	if _, err := New(&App{}); err == nil {
+		t.Errorf("Expected overlaid required field to be checked")
+	}
+	app, err := New(&App{}, func(a *App) error { a.Pool.Name = "db"; return nil })
+	if err != nil {
+		t.Fatalf("Error creating config: %v", err)
+	}
+	if app.Pool.MaxIdle != 4 {
+		t.Errorf("Expected overlaid default, got %d", app.Pool.MaxIdle)
+	}
+	fields, err := Describe[*App]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi := fields[0]
+	if fi.Path != "Pool.MaxIdle" || fi.Description != "Idle connections kept" || fi.tag.Get("json") != "max_idle" {
+		t.Errorf("Expected overlay merged with the type's tags, got %+v", fi)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a malformed overlay tag to panic")
+		}
+	}()
+	RegisterOverlay[vendorPool](map[string]string{"Name": `desc:"unterminated`})
+}
+
+func TestOptionSets(t *testing.T) {
+	RegisterOptionSet("high-throughput", "More connections, shorter timeouts",
+		With[*Server]("MaxConns", 1000),
+		With[*Server]("Timeout", 5*time.Second),
+	)
+	RegisterOptionSet("debug", "Single connection", With[*Server]("MaxConns", 1))
+	sets := ListOptionSets[*Server]()
+	if len(sets) != 2 || sets[0].Name != "debug" || sets[1].Description != "More connections, shorter timeouts" {
+		t.Errorf("Unexpected option sets %+v", sets)
+	}
+	s, report, err := NewWithReport(&Server{}, defaultConfig, WithOptionSet[*Server]("high-throughput"))
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.MaxConns != 1000 || s.Timeout != 5*time.Second {
+		t.Errorf("Option set not applied: %+v", s)
+	}
+	if !reflect.DeepEqual(report.OptionSets, []string{"high-throughput"}) {
+		t.Errorf("Expected applied option sets in report, got %v", report.OptionSets)
+	}
+	if _, err := New(&Server{}, WithOptionSet[*Server]("turbo")); err == nil || !strings.Contains(err.Error(), "debug, high-throughput") {
+		t.Errorf("Expected unknown option set error listing the sets, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package optionator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OptionSet is a named, documented group of options registered with
+// RegisterOptionSet, such as a "high-throughput" preset.
+type OptionSet struct {
+	Name        string
+	Description string
+}
+
+type optionSetKey struct {
+	t    reflect.Type
+	name string
+}
+
+type optionSet[T any] struct {
+	OptionSet
+	opts []Option[T]
+}
+
+// optionSets holds the registered option sets by target type and name.
+var optionSets sync.Map // map[optionSetKey]any, holding optionSet[T]
+
+// RegisterOptionSet registers opts under name for targets of type T, so a
+// CLI can offer them as --preset=name and apply them with WithOptionSet.
+// Registering a name again replaces the set.
+func RegisterOptionSet[T any](name, description string, opts ...Option[T]) {
+	key := optionSetKey{reflect.TypeOf((*T)(nil)).Elem(), name}
+	set := optionSet[T]{OptionSet{name, description}, append([]Option[T](nil), opts...)}
+	optionSets.Store(key, set)
+}
+
+// ListOptionSets returns the option sets registered for T, sorted by name.
+func ListOptionSets[T any]() []OptionSet {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	var sets []OptionSet
+	optionSets.Range(func(key, value any) bool {
+		if key.(optionSetKey).t == t {
+			sets = append(sets, value.(optionSet[T]).OptionSet)
+		}
+		return true
+	})
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return sets
+}
+
+// WithOptionSet returns an Option applying the options registered under
+// name, in order. Applied sets are listed in the Report of NewWithReport.
+func WithOptionSet[T any](name string) Option[T] {
+	return func(target T) error {
+		set, ok := optionSets.Load(optionSetKey{reflect.TypeOf((*T)(nil)).Elem(), name})
+		if !ok {
+			var names []string
+			for _, s := range ListOptionSets[T]() {
+				names = append(names, s.Name)
+			}
+			return fmt.Errorf("unknown option set %q; registered: %s", name, strings.Join(names, ", "))
+		}
+		for _, opt := range set.(optionSet[T]).opts {
+			if err := opt(target); err != nil {
+				return fmt.Errorf("option set %s: %w", name, err)
+			}
+		}
+		if report, ok := contextFor(target).Value(reportKey{}).(*Report); ok {
+			report.OptionSets = append(report.OptionSets, name)
+		}
+		return nil
+	}
+}
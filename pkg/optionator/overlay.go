@@ -0,0 +1,49 @@
+package optionator
+
+import (
+	"errors"
+	"reflect"
+)
+
+// WithOverlay returns an Option that copies every non-zero field of partial
+// onto the target. Nested structs are overlaid field by field rather than
+// replaced, and copied values share no pointers, slices or maps with
+// partial. Leaf structs such as time.Time are copied whole. A nil partial is
+// a no-op.
+func WithOverlay[T any](partial T) Option[T] {
+	return func(target T) error {
+		v, p := reflect.ValueOf(target), reflect.ValueOf(partial)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			return errors.New("target must be a pointer to a struct")
+		}
+		if p.IsNil() {
+			return nil
+		}
+		overlay(v.Elem(), p.Elem(), copies{})
+		return nil
+	}
+}
+
+// overlay copies the non-zero exported fields of struct src onto dst.
+func overlay(dst, src reflect.Value, seen copies) {
+	t := src.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
+		from, to := src.Field(i), dst.Field(i)
+		if isZeroValue(from) {
+			continue
+		}
+		switch {
+		case from.Kind() == reflect.Struct && isNestedStruct(from.Type()):
+			overlay(to, from, seen)
+		case from.Kind() == reflect.Ptr && isNestedStruct(from.Type()) && !to.IsNil():
+			overlay(to.Elem(), from.Elem(), seen)
+		default:
+			cp := reflect.New(from.Type()).Elem()
+			copyValue(cp, from, seen)
+			to.Set(cp)
+		}
+	}
+}
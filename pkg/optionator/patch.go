@@ -0,0 +1,110 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// PatchSource is a Source whose values are built up from JSON merge patches
+// (RFC 7386), typically pushed over a message bus. Add it to the Sources of
+// a Live and feed it with ApplyPatches.
+type PatchSource struct {
+	name   string
+	mu     sync.Mutex
+	values map[string]any
+}
+
+// NewPatchSource returns an empty PatchSource named name, e.g. the subject or
+// topic it is fed from.
+func NewPatchSource(name string) *PatchSource {
+	return &PatchSource{name: name, values: map[string]any{}}
+}
+
+func (s *PatchSource) Name() string { return "patch:" + s.name }
+
+func (*PatchSource) Kind() SourceKind { return FromPatch }
+
+func (s *PatchSource) Load(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return deepCopy(s.values), nil
+}
+
+// Message is a patch delivered by a message bus. Adapters for clients such
+// as NATS or Kafka implement Ack to confirm delivery and Nak to reject the
+// message, e.g. by not committing its offset. Nak receives the reason the
+// patch was rejected, an ErrorGroup if the patched config is invalid, for
+// the adapter to log or reply with.
+type Message interface {
+	Data() []byte
+	Ack() error
+	Nak(reason error) error
+}
+
+// ApplyPatches applies each message in msgs as a merge patch to src and
+// reloads live, whose sources must include src. A message whose patch does
+// not decode or whose result fails construction is rolled back and
+// rejected with Nak and the error; otherwise it is acknowledged with Ack. ApplyPatches
+// returns when msgs is closed or ctx is done.
+func ApplyPatches[T any](ctx context.Context, live *Live[T], src *PatchSource, msgs <-chan Message) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-msgs:
+			if !ok {
+				return nil
+			}
+			if err := applyPatch(ctx, live, src, msg.Data()); err != nil {
+				if nakErr := msg.Nak(err); nakErr != nil {
+					return fmt.Errorf("rejecting patch: %w", nakErr)
+				}
+				continue
+			}
+			if err := msg.Ack(); err != nil {
+				return fmt.Errorf("acknowledging patch: %w", err)
+			}
+		}
+	}
+}
+
+// applyPatch merges data into src and reloads live, restoring the previous
+// values of src if either step fails.
+func applyPatch[T any](ctx context.Context, live *Live[T], src *PatchSource, data []byte) error {
+	patch, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	src.mu.Lock()
+	prev := src.values
+	src.values = mergePatch(deepCopy(prev), patch)
+	src.mu.Unlock()
+	if err := live.Reload(ctx); err != nil {
+		src.mu.Lock()
+		src.values = prev
+		src.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// mergePatch applies an RFC 7386 merge patch to dst: null removes a key,
+// objects merge recursively and anything else replaces the value.
+func mergePatch(dst, patch map[string]any) map[string]any {
+	for k, v := range patch {
+		switch v := v.(type) {
+		case nil:
+			delete(dst, k)
+		case map[string]any:
+			sub, _ := dst[k].(map[string]any)
+			if sub == nil {
+				sub = map[string]any{}
+			}
+			dst[k] = mergePatch(sub, v)
+		default:
+			dst[k] = v
+		}
+	}
+	return dst
+}
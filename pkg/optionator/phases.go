@@ -0,0 +1,95 @@
+package optionator
+
+import (
+	"context"
+	"runtime/pprof"
+	"time"
+)
+
+// PhaseTimes is the time one construction spent in each of its phases,
+// passed to Config.OnPhaseTimes.
+type PhaseTimes struct {
+	// Type is the type of the struct constructed.
+	Type string
+	// Metadata is the time spent getting the struct's field metadata,
+	// nearly nothing once it is cached.
+	Metadata time.Duration
+	Defaults time.Duration
+	// Sources is the time spent loading sources and Binding the time spent
+	// assigning their values to fields.
+	Sources time.Duration
+	Binding time.Duration
+	Options time.Duration
+	// Validation covers required fields, validation tags and rules.
+	Validation time.Duration
+	Total      time.Duration
+	Err        error
+}
+
+type phaseKey struct{}
+
+// phaseProfile times the phases of a construction and labels the goroutine
+// running them.
+type phaseProfile struct {
+	config Config
+	// ctx holds the labels the goroutine had before construction.
+	ctx   context.Context
+	times PhaseTimes
+	start time.Time
+}
+
+// withPhaseProfile returns ctx carrying a profile of the construction of a
+// value of type typ, or ctx itself if config asks for no profiling.
+func withPhaseProfile(ctx context.Context, config Config, typ string) (context.Context, *phaseProfile) {
+	if config.OnPhaseTimes == nil && !config.ProfileLabels {
+		return ctx, nil
+	}
+	p := &phaseProfile{config: config, ctx: ctx, times: PhaseTimes{Type: typ}, start: time.Now()}
+	return context.WithValue(ctx, phaseKey{}, p), p
+}
+
+// phase starts timing the named phase of the construction ctx belongs to
+// and returns the function that stops it, labelling the goroutine with
+// optionator.type and optionator.phase in between if Config.ProfileLabels
+// is set.
+func phase(ctx context.Context, name string) func() {
+	p, _ := ctx.Value(phaseKey{}).(*phaseProfile)
+	if p == nil {
+		return func() {}
+	}
+	if p.config.ProfileLabels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(p.ctx, pprof.Labels("optionator.type", p.times.Type, "optionator.phase", name)))
+	}
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		switch name {
+		case "metadata":
+			p.times.Metadata += d
+		case "defaults":
+			p.times.Defaults += d
+		case "sources":
+			p.times.Sources += d
+		case "binding":
+			p.times.Binding += d
+		case "options":
+			p.times.Options += d
+		case "validate":
+			p.times.Validation += d
+		}
+		if p.config.ProfileLabels {
+			pprof.SetGoroutineLabels(p.ctx)
+		}
+	}
+}
+
+// done passes the times of the construction, which ended with err, to the
+// OnPhaseTimes hook.
+func (p *phaseProfile) done(err error) {
+	if p == nil || p.config.OnPhaseTimes == nil {
+		return
+	}
+	p.times.Total = time.Since(p.start)
+	p.times.Err = err
+	p.config.OnPhaseTimes(p.times)
+}
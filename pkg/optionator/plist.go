@@ -0,0 +1,133 @@
+package optionator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// decodePlist decodes an XML property list whose root is a dict, the format
+// written by `defaults export`. Integers become int64, reals float64, dates
+// strings and data the decoded bytes as a string.
+func decodePlist(data []byte) (map[string]any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("plist has no dict")
+			}
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "dict" {
+			v, err := plistValue(dec, se)
+			if err != nil {
+				return nil, err
+			}
+			return v.(map[string]any), nil
+		}
+	}
+}
+
+// plistValue decodes the element started by se.
+func plistValue(dec *xml.Decoder, se xml.StartElement) (any, error) {
+	switch se.Name.Local {
+	case "dict":
+		m := map[string]any{}
+		key := ""
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				if tok.Name.Local == "key" {
+					if err := dec.DecodeElement(&key, &tok); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := plistValue(dec, tok)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = v
+			case xml.EndElement:
+				return m, nil
+			}
+		}
+	case "array":
+		var a []any
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				v, err := plistValue(dec, tok)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			case xml.EndElement:
+				return a, nil
+			}
+		}
+	case "true", "false":
+		if err := dec.Skip(); err != nil {
+			return nil, err
+		}
+		return se.Name.Local == "true", nil
+	}
+	var text string
+	if err := dec.DecodeElement(&text, &se); err != nil {
+		return nil, err
+	}
+	switch se.Name.Local {
+	case "string", "date":
+		return text, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "data":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+		return string(b), err
+	}
+	return nil, fmt.Errorf("unsupported plist element <%s>", se.Name.Local)
+}
+
+// settingValues picks the fields of T tagged `setting:"key"` out of a flat
+// store of settings, nesting them by field path the way sources expect.
+// Integers fill bool fields as non-zero, since native stores often keep
+// flags as numbers.
+func settingValues[T any](config Config, lookup func(key string) (any, bool)) (map[string]any, error) {
+	fields, err := DescribeWithConfig[T](config)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	for _, fi := range fields {
+		key := fi.tag.Get("setting")
+		if key == "" {
+			continue
+		}
+		v, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		if n, isInt := v.(int64); isInt && fi.Type.Kind() == reflect.Bool {
+			v = n != 0
+		}
+		setPath(values, fi.Path, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,143 @@
+package optionator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Policy controls how a source, typically a remote one, is loaded.
+type Policy struct {
+	// Timeout bounds each attempt. Zero means no timeout.
+	Timeout time.Duration
+	// Retries is the number of additional attempts after a failed one.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles after each
+	// retry up to MaxBackoff, if set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// CacheFile, if set, receives every successful load as JSON and is read
+	// back, with a warning on the report, when all attempts fail, so a source
+	// outage doesn't block startup.
+	// Failing to write it is only a warning on the construction's report.
+	CacheFile string
+}
+
+// WithPolicy wraps src so that its loads follow p.
+func WithPolicy(src Source, p Policy) Source {
+	return policySource{src: src, policy: p}
+}
+
+type policySource struct {
+	src    Source
+	policy Policy
+}
+
+func (s policySource) Name() string { return s.src.Name() }
+
+func (s policySource) WeaklyTyped() bool { return isWeaklyTyped(s.src) }
+
+func (s policySource) Optional() bool { return isOptional(s.src) }
+
+func (s policySource) Kind() SourceKind { return sourceKind(s.src) }
+
+func (s policySource) Load(ctx context.Context) (map[string]any, error) {
+	backoff := s.policy.Backoff
+	var err error
+	for attempt := 0; attempt <= s.policy.Retries; attempt++ {
+		if attempt > 0 && backoff > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return s.fallback(ctx, ctx.Err())
+			}
+			backoff *= 2
+			if s.policy.MaxBackoff > 0 && backoff > s.policy.MaxBackoff {
+				backoff = s.policy.MaxBackoff
+			}
+		}
+		var values map[string]any
+		values, err = s.attempt(ctx)
+		if err == nil {
+			if s.policy.CacheFile != "" {
+				if cacheErr := writeCache(s.policy.CacheFile, values); cacheErr != nil {
+					warn(ctx, "", "source %s: writing cache: %v", s.Name(), cacheErr)
+				}
+			}
+			return values, nil
+		}
+	}
+	return s.fallback(ctx, err)
+}
+
+// attempt loads once, giving up when the timeout elapses even if the source
+// ignores its context.
+func (s policySource) attempt(ctx context.Context) (map[string]any, error) {
+	if s.policy.Timeout <= 0 {
+		return s.src.Load(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.policy.Timeout)
+	defer cancel()
+	type result struct {
+		values map[string]any
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		values, err := s.src.Load(ctx)
+		done <- result{values, err}
+	}()
+	select {
+	case r := <-done:
+		return r.values, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fallback returns the cached values, if any, with a warning naming err and
+// the age of the cache, or err.
+func (s policySource) fallback(ctx context.Context, err error) (map[string]any, error) {
+	if s.policy.CacheFile == "" {
+		return nil, err
+	}
+	info, statErr := os.Stat(s.policy.CacheFile)
+	if statErr != nil {
+		return nil, err
+	}
+	data, readErr := os.ReadFile(s.policy.CacheFile)
+	if readErr != nil {
+		return nil, err
+	}
+	values, decodeErr := decodeJSON(data)
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%v; cache unusable: %w", err, decodeErr)
+	}
+	age := time.Since(info.ModTime()).Round(time.Second)
+	warn(ctx, "", "source %s: %v; using cache from %v ago", s.Name(), err, age)
+	return values, nil
+}
+
+// writeCache atomically replaces path with values encoded as JSON.
+func writeCache(path string, values map[string]any) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,191 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Keys with special meaning at the top level of a file source.
+const (
+	// IncludeKey names one file, or a list of files, loaded and deep-merged
+	// before the including file's own values. Relative paths are resolved
+	// against the including file's directory.
+	IncludeKey = "$include"
+	// ExtendsKey names the parent of a file: the parent, with its own
+	// parents, is loaded and deep-merged first, then the includes, then the
+	// file's own values. Chains of any length are allowed; a file that ends
+	// up extending or including itself is an include cycle.
+	ExtendsKey = "$extends"
+	// ProfilesKey holds an object of named documents; the one named by
+	// Config.Profile is deep-merged over the rest of the file.
+	ProfilesKey = "$profiles"
+	// ProfileKey names the profile, or list of profiles, a document of a
+	// multi-document YAML file belongs to. Documents without it are merged
+	// first, in order, then those of Config.Profile; the others are skipped.
+	ProfileKey = "$profile"
+)
+
+type configKey struct{}
+
+// withConfig returns a context carrying config for sources to consult.
+func withConfig(ctx context.Context, config Config) context.Context {
+	return context.WithValue(ctx, configKey{}, config)
+}
+
+// ConfigFromContext returns the Config of the construction that is loading a
+// source, or the default config outside of one.
+func ConfigFromContext(ctx context.Context) Config {
+	if config, ok := ctx.Value(configKey{}).(Config); ok {
+		return config
+	}
+	return defaultConfig
+}
+
+// loadFile reads the file at path and resolves its includes and profiles.
+// Stack holds the files currently being loaded, to detect include cycles.
+func (s FileSource) loadFile(ctx context.Context, path string, stack []string) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("include cycle: %v", append(stack, abs))
+		}
+	}
+	stack = append(stack, abs)
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = s.unwrap(abs, data); err != nil {
+		return nil, err
+	}
+	if config := ConfigFromContext(ctx); config.TemplateFiles {
+		if err := config.dynamic("template " + abs); err != nil {
+			return nil, err
+		}
+		if data, err = renderTemplate(abs, data, config); err != nil {
+			return nil, err
+		}
+	}
+	docs, err := decodeDocuments(abs, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", abs, err)
+	}
+	profile := ConfigFromContext(ctx).Profile
+	var base, selected []map[string]any
+	for i, doc := range docs {
+		names, err := stringList(doc[ProfileKey])
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %s: %w", abs, i+1, ProfileKey, err)
+		}
+		delete(doc, ProfileKey)
+		switch {
+		case len(names) == 0:
+			base = append(base, doc)
+		case profile != "" && containsString(names, profile):
+			selected = append(selected, doc)
+		}
+	}
+	merged := map[string]any{}
+	for _, doc := range append(base, selected...) {
+		resolved, err := s.resolve(ctx, abs, doc, stack)
+		if err != nil {
+			return nil, err
+		}
+		mergeMaps(merged, resolved)
+	}
+	return merged, nil
+}
+
+// decodeDocuments decodes the documents of the file at path: every
+// document of a YAML file, or the one JSON object of any other.
+func decodeDocuments(path string, data []byte) ([]map[string]any, error) {
+	if isYAMLFile(path) {
+		return decodeYAML(data)
+	}
+	values, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]any{values}, nil
+}
+
+// resolve merges the parent and includes of a document of the file at abs
+// under its own values and the profile selected under ProfilesKey.
+func (s FileSource) resolve(ctx context.Context, abs string, values map[string]any, stack []string) (map[string]any, error) {
+	merged := map[string]any{}
+	parent, ok := values[ExtendsKey].(string)
+	if _, present := values[ExtendsKey]; present && !ok {
+		return nil, fmt.Errorf("%s: %s: expected a string, got %T", abs, ExtendsKey, values[ExtendsKey])
+	}
+	includes, err := stringList(values[IncludeKey])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", abs, IncludeKey, err)
+	}
+	if parent != "" {
+		includes = append([]string{parent}, includes...)
+	}
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(abs), inc)
+		}
+		included, err := s.loadFile(ctx, inc, stack)
+		if err != nil {
+			return nil, err
+		}
+		mergeMaps(merged, included)
+	}
+	profiles, _ := values[ProfilesKey].(map[string]any)
+	delete(values, IncludeKey)
+	delete(values, ExtendsKey)
+	delete(values, ProfilesKey)
+	mergeMaps(merged, values)
+	if profile := ConfigFromContext(ctx).Profile; profile != "" {
+		if doc, ok := profiles[profile].(map[string]any); ok {
+			mergeMaps(merged, doc)
+		}
+	}
+	return merged, nil
+}
+
+// stringList accepts a string or a list of strings.
+func stringList(value any) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		list := make([]string, len(v))
+		for i, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a string, got %T", elem)
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expected a string or a list of strings, got %T", value)
+}
+
+// mergeMaps deep-merges src into dst: nested objects are merged key by key,
+// anything else in src replaces the value in dst.
+func mergeMaps(dst, src map[string]any) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]any); ok {
+			if dstMap, ok := dst[key].(map[string]any); ok {
+				merged := make(map[string]any, len(dstMap))
+				mergeMaps(merged, dstMap)
+				mergeMaps(merged, srcMap)
+				dst[key] = merged
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
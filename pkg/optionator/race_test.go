@@ -0,0 +1,47 @@
+package optionator
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentConstruction exercises the shared caches and registries from
+// many goroutines; run it with -race.
+func TestConcurrentConstruction(t *testing.T) {
+	type Worker struct {
+		Name    string `default:"worker" format:"hostname"`
+		Port    int    `default:"8080" min:"1" max:"65535"`
+		Nested  NestedConfig
+		Derived string `default:"${Name}:${Port}"`
+	}
+	config := defaultConfig
+	config.CollectStats = true
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			RegisterFormat(fmt.Sprintf("format-%d", i%4), func(string) error { return nil })
+			cfg, err := NewWithConfig(&Worker{}, config,
+				With[*Worker]("Port", 9000+i),
+				WithText[*Worker]("Nested.Host", "example.com"),
+			)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if cfg.Port != 9000+i || cfg.Derived != fmt.Sprintf("worker:%d", 9000+i) {
+				errs <- fmt.Errorf("got %+v", cfg)
+			}
+			Describe[*Worker]()
+			Stats()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,21 @@
+package optionator
+
+// Raw holds a config section left undecoded, such as the block of each
+// plugin in a Plugins map[string]optionator.Raw field, until the code that
+// owns the section decodes it into its own struct with Decode.
+type Raw map[string]any
+
+// Decode populates target, a pointer to a struct, from r over its defaults
+// and validates the result, as NewWithConfig would with r as its only
+// source. A nil Raw leaves target with its defaults.
+func (r Raw) Decode(target any) error {
+	return r.DecodeWithConfig(target, defaultConfig)
+}
+
+// DecodeWithConfig is like Decode but reads tags and matches keys according
+// to config; its Sources are loaded before r.
+func (r Raw) DecodeWithConfig(target any, config Config) error {
+	config.Sources = append(append([]Source{}, config.Sources...), MapSource{Values: r})
+	_, err := NewWithConfig(target, config)
+	return err
+}
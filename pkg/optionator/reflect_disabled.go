@@ -0,0 +1,7 @@
+//go:build optionator_noreflect
+
+package optionator
+
+// reflectionEnabled is false when built with the optionator_noreflect tag, so
+// only types registered with RegisterSchema can be constructed.
+const reflectionEnabled = false
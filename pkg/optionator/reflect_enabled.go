@@ -0,0 +1,7 @@
+//go:build !optionator_noreflect
+
+package optionator
+
+// reflectionEnabled allows types without a registered schema to be
+// configured through struct tags.
+const reflectionEnabled = true
@@ -0,0 +1,114 @@
+//go:build windows
+
+package optionator
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// RegistrySource returns a Source that reads the values under the registry
+// key path of root, such as syscall.HKEY_CURRENT_USER, into the fields of T
+// tagged `setting:"name"`. A missing key yields no values. String, expandable
+// string, multi-string, DWORD and QWORD values are supported; DWORDs fill
+// bool fields as non-zero.
+func RegistrySource[T any](root syscall.Handle, path string) Source {
+	return registrySource[T]{root, path}
+}
+
+type registrySource[T any] struct {
+	root syscall.Handle
+	path string
+}
+
+func (s registrySource[T]) Name() string { return `registry:` + s.path }
+
+func (registrySource[T]) Kind() SourceKind { return FromRegistry }
+
+func (s registrySource[T]) Load(ctx context.Context) (map[string]any, error) {
+	path, err := syscall.UTF16PtrFromString(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(s.root, path, 0, syscall.KEY_READ, &key); err != nil {
+		if err == syscall.ERROR_FILE_NOT_FOUND {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+	defer syscall.RegCloseKey(key)
+	var readErr error
+	values, err := settingValues[T](ConfigFromContext(ctx), func(name string) (any, bool) {
+		v, ok, err := readRegistryValue(key, name)
+		if err != nil && readErr == nil {
+			readErr = fmt.Errorf("%s: %w", name, err)
+		}
+		return v, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, readErr
+}
+
+// readRegistryValue reads the value called name under key.
+func readRegistryValue(key syscall.Handle, name string) (any, bool, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, false, err
+	}
+	var typ, n uint32
+	if err := syscall.RegQueryValueEx(key, p, nil, &typ, nil, &n); err != nil {
+		if err == syscall.ERROR_FILE_NOT_FOUND {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	buf := make([]byte, n)
+	if n > 0 {
+		if err := syscall.RegQueryValueEx(key, p, nil, &typ, &buf[0], &n); err != nil {
+			return nil, false, err
+		}
+	}
+	buf = buf[:n]
+	switch typ {
+	case syscall.REG_SZ, syscall.REG_EXPAND_SZ:
+		return syscall.UTF16ToString(utf16s(buf)), true, nil
+	case syscall.REG_MULTI_SZ:
+		var list []any
+		u := utf16s(buf)
+		for start, i := 0, 0; i < len(u); i++ {
+			if u[i] == 0 {
+				if i == start {
+					break
+				}
+				list = append(list, syscall.UTF16ToString(u[start:i]))
+				start = i + 1
+			}
+		}
+		return list, true, nil
+	case syscall.REG_DWORD:
+		if len(buf) < 4 {
+			return nil, false, fmt.Errorf("short DWORD value")
+		}
+		return int64(binary.LittleEndian.Uint32(buf)), true, nil
+	case syscall.REG_QWORD:
+		if len(buf) < 8 {
+			return nil, false, fmt.Errorf("short QWORD value")
+		}
+		return int64(binary.LittleEndian.Uint64(buf)), true, nil
+	}
+	return nil, false, fmt.Errorf("unsupported registry value type %d", typ)
+}
+
+// utf16s reinterprets registry bytes as UTF-16 code units.
+func utf16s(b []byte) []uint16 {
+	if len(b) < 2 {
+		return nil
+	}
+	return unsafe.Slice((*uint16)(unsafe.Pointer(&b[0])), len(b)/2)
+}
@@ -0,0 +1,175 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Severity grades a Finding.
+type Severity int
+
+const (
+	// SeverityWarning marks findings that do not fail construction.
+	SeverityWarning Severity = iota
+	// SeverityError marks findings that fail construction.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is one issue found while constructing a configuration.
+type Finding struct {
+	// Path is the dotted path of the field concerned, if any.
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return f.Severity.String() + ": " + f.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Path, f.Message)
+}
+
+// Report collects the findings of a construction.
+type Report struct {
+	Findings []Finding
+	// Timings holds the duration of each source load and option
+	// application, in the order they ran.
+	Timings []Timing
+	// OptionSets names the option sets applied with WithOptionSet, in
+	// order.
+	OptionSets []string
+
+	raw   map[string]any
+	extra map[string]any
+}
+
+// Raw returns the values of all sources merged in load order, as they were
+// before binding: later sources override earlier ones key by key and nested
+// mappings are merged.
+func (r Report) Raw() map[string]any { return r.raw }
+
+// Extra returns the source values whose keys match no field, keyed by their
+// dotted path such as "plugins" or "DB.tuning" and merged across sources
+// like Raw, for extension sections the struct does not declare. Directive keys starting with $ are left out.
+func (r Report) Extra() map[string]any { return r.extra }
+
+// Warnings returns the findings with SeverityWarning.
+func (r Report) Warnings() []Finding { return r.filter(SeverityWarning) }
+
+// Errors returns the findings with SeverityError.
+func (r Report) Errors() []Finding { return r.filter(SeverityError) }
+
+func (r Report) filter(s Severity) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Severity == s {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (r *Report) add(path string, s Severity, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{Path: path, Severity: s, Message: fmt.Sprintf(format, args...)})
+}
+
+// NewWithReport is like NewWithConfig but also returns a Report. A failed
+// construction is reported as an error finding. After a successful one,
+// soft issues are reported as warnings:
+//
+//   - a field tagged deprecated:"<advice>" holds a non-zero value;
+//   - a field tagged insecure:"true" still holds its default;
+//   - a field cannot be configured, as reported by Verify.
+//
+// Registered rules of SeverityWarning add their violations too, and so do
+// optional sources that failed to load and sources and options slower than
+// Config.SlowThreshold.
+func NewWithReport[T any](target T, config Config, opts ...Option[T]) (T, Report, error) {
+	var report Report
+	target, err := newWithContext(withReport(context.Background(), &report), target, config, opts)
+	finishReport(&report, target, config, err)
+	return target, report, err
+}
+
+// recordRaw merges the values of a loaded source into the Raw view of the
+// report carried by ctx, if any.
+func recordRaw(ctx context.Context, values map[string]any) {
+	if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+		report.raw = mergeValues(report.raw, values)
+	}
+}
+
+// recordExtra records the value of an unknown key on the report carried by
+// ctx, if any, merged like Raw with what earlier sources gave for it.
+func recordExtra(ctx context.Context, path string, value any) {
+	if ctx == nil {
+		return
+	}
+	if report, ok := ctx.Value(reportKey{}).(*Report); ok {
+		report.extra = mergeValues(report.extra, map[string]any{path: value})
+	}
+}
+
+// mergeValues returns dst with the values of src merged over it, copying
+// nested mappings so the sources are left untouched.
+func mergeValues(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for k, v := range src {
+		if m, ok := v.(map[string]any); ok {
+			prev, _ := dst[k].(map[string]any)
+			dst[k] = mergeValues(prev, m)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// finishReport adds to report the outcome of constructing target: err as an
+// error finding, or the warnings found on the result.
+func finishReport(report *Report, target any, config Config, err error) {
+	if err != nil {
+		report.add("", SeverityError, "%v", err)
+		return
+	}
+	if v := reflect.ValueOf(target); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		checkSupported(report, v.Elem().Type(), config, "", map[reflect.Type]bool{})
+		checkFindings(report, v.Elem(), config)
+		report.Findings = append(report.Findings, evalRules(v.Elem(), config, SeverityWarning)...)
+	}
+}
+
+// checkFindings adds the warnings for the fields of struct v to report.
+func checkFindings(report *Report, v reflect.Value, config Config) {
+	for _, fi := range describeType(v.Type(), config) {
+		field, ok := lookupIndexes(v, fi.indexes)
+		if !ok {
+			continue
+		}
+		if advice, ok := fi.tag.Lookup("deprecated"); ok && !isZeroValue(field) {
+			msg := "deprecated field is set"
+			if advice != "" {
+				msg += "; " + strings.TrimSpace(advice)
+			}
+			report.add(fi.Path, SeverityWarning, "%s", msg)
+		}
+		if fi.tag.Get("insecure") == "true" && fi.Default != "" {
+			def := reflect.New(fi.Type).Elem()
+			if parseAndSetDefault(def, fi.Default, fi.Type) == nil && reflect.DeepEqual(field.Interface(), def.Interface()) {
+				report.add(fi.Path, SeverityWarning, "value is the insecure default")
+			}
+		}
+	}
+}
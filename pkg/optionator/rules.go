@@ -0,0 +1,80 @@
+package optionator
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Rule is an organisation-wide guardrail evaluated after validation on every
+// construction, whatever the struct. It applies to each leaf field whose name
+// or dotted path equals Field.
+type Rule struct {
+	Name  string
+	Field string
+	// Severity decides whether a violation fails construction or is only
+	// reported as a warning by NewWithReport.
+	Severity Severity
+	// Check returns an error describing the violation, if any. The config
+	// gives access to settings such as the profile.
+	Check func(value any, config Config) error
+}
+
+var rules struct {
+	sync.RWMutex
+	list []Rule
+}
+
+// RegisterRule adds r, replacing any rule registered under the same name.
+func RegisterRule(r Rule) {
+	defer resetValidationCache()
+	rules.Lock()
+	defer rules.Unlock()
+	for i := range rules.list {
+		if rules.list[i].Name == r.Name {
+			rules.list[i] = r
+			return
+		}
+	}
+	rules.list = append(rules.list, r)
+}
+
+// UnregisterRule removes the rule registered under name.
+func UnregisterRule(name string) {
+	defer resetValidationCache()
+	rules.Lock()
+	defer rules.Unlock()
+	for i := range rules.list {
+		if rules.list[i].Name == name {
+			rules.list = append(rules.list[:i:i], rules.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// evalRules checks the fields of struct v against the registered rules of
+// severity s.
+func evalRules(v reflect.Value, config Config, s Severity) []Finding {
+	rules.RLock()
+	list := rules.list
+	rules.RUnlock()
+	if len(list) == 0 {
+		return nil
+	}
+	var findings []Finding
+	for _, fi := range describeType(v.Type(), config) {
+		for _, r := range list {
+			if r.Severity != s || (r.Field != fi.Name && r.Field != fi.Path) {
+				continue
+			}
+			field, ok := lookupIndexes(v, fi.indexes)
+			if !ok {
+				continue
+			}
+			if err := r.Check(field.Interface(), config); err != nil {
+				findings = append(findings, Finding{Path: fi.Path, Severity: s, Message: fmt.Sprintf("%s: %v", r.Name, err)})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,66 @@
+package optionator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// WriteSample writes a commented sample configuration for T, a struct or a
+// pointer to one, in format "yaml" or "toml". Every field that is not hidden
+// appears with its default value, or its zero value when it has none, under
+// comments giving its desc tag, type, default and whether it is required.
+// Nested structs become nested mappings or tables.
+func WriteSample[T any](w io.Writer, format string) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.New("type must be a struct or a pointer to a struct")
+	}
+	v := reflect.New(t)
+	if err := setDefaultRecursively(context.Background(), v.Elem(), defaultConfig); err != nil {
+		return err
+	}
+	opts := ExportOptions{sample: true}
+	switch format {
+	case "yaml":
+		return WriteYAML(w, v.Interface(), opts)
+	case "toml":
+		return WriteTOML(w, v.Interface(), opts)
+	}
+	return fmt.Errorf("unknown sample format %q", format)
+}
+
+// sampleNotes describes fi in the comments of a sample file.
+func sampleNotes(fi FieldInfo) []string {
+	var notes []string
+	if fi.Description != "" {
+		notes = append(notes, strings.Split(fi.Description, "\n")...)
+	}
+	meta := []string{"type: " + fi.Type.String()}
+	if fi.Default != "" {
+		meta = append(meta, "default: "+fi.Default)
+	}
+	if fi.Example != "" {
+		meta = append(meta, "example: "+fi.Example)
+	}
+	if fi.Required {
+		meta = append(meta, "required")
+	}
+	if fi.Secret {
+		meta = append(meta, "secret")
+	}
+	if fi.Stability != "" && fi.Stability != StabilityStable {
+		meta = append(meta, fi.Stability)
+	}
+	notes = append(notes, strings.Join(meta, ", "))
+	if fi.Docs != "" {
+		notes = append(notes, "see "+fi.Docs)
+	}
+	return notes
+}
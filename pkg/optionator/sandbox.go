@@ -0,0 +1,70 @@
+package optionator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EvalLimits bounds the dynamic evaluation of templates, cel tags and
+// derived defaults, so a hostile config file or tag cannot exhaust memory
+// or read arbitrary files. They do not reach the network, though the
+// tcpaddr and resolve validation tags look host names up in DNS. Zero fields
+// take their value from DefaultEvalLimits.
+type EvalLimits struct {
+	// MaxDepth bounds the nesting of a cel expression and the length of a
+	// chain of derived defaults referencing each other.
+	MaxDepth int
+	// MaxExpansions bounds the function calls of a template and the field
+	// references substituted into the derived defaults of a struct.
+	MaxExpansions int
+	// MaxOutput bounds the size in bytes of a rendered template or of an
+	// expanded derived default.
+	MaxOutput int
+	// AllowFiles grants templates file access. Without it their file
+	// function fails, so a config file cannot read secrets off the disk.
+	AllowFiles bool
+}
+
+// DefaultEvalLimits are the limits in effect for zero EvalLimits fields.
+var DefaultEvalLimits = EvalLimits{MaxDepth: 32, MaxExpansions: 1000, MaxOutput: 1 << 20}
+
+// errDynamicDisabled is returned for dynamic features used while
+// Config.DisableDynamic is set.
+var errDynamicDisabled = errors.New("dynamic evaluation is disabled")
+
+// evalLimits returns the limits of c with defaults filled in.
+func (c Config) evalLimits() EvalLimits {
+	l := c.EvalLimits
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultEvalLimits.MaxDepth
+	}
+	if l.MaxExpansions <= 0 {
+		l.MaxExpansions = DefaultEvalLimits.MaxExpansions
+	}
+	if l.MaxOutput <= 0 {
+		l.MaxOutput = DefaultEvalLimits.MaxOutput
+	}
+	return l
+}
+
+// dynamic returns an error naming feature if c disables dynamic evaluation.
+func (c Config) dynamic(feature string) error {
+	if c.DisableDynamic {
+		return fmt.Errorf("%s: %w", feature, errDynamicDisabled)
+	}
+	return nil
+}
+
+// limitedBuffer collects output up to max bytes and fails beyond.
+type limitedBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if len(b.buf)+len(p) > b.max {
+		return 0, fmt.Errorf("output exceeds %d bytes", b.max)
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
@@ -0,0 +1,81 @@
+package optionator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FieldAccessor gives reflection-free access to one field of T.
+type FieldAccessor[T any] struct {
+	Name     string
+	Default  string
+	Required bool
+	// SetText parses text and assigns the result to the field.
+	SetText func(target T, text string) error
+	// IsZero reports whether the field holds its zero value.
+	IsZero func(target T) bool
+}
+
+// Accessor builds a FieldAccessor from a function returning a pointer to the
+// field and a parse function such as strconv.Atoi or time.ParseDuration.
+func Accessor[T any, F comparable](name, def string, required bool, field func(T) *F, parse func(string) (F, error)) FieldAccessor[T] {
+	return FieldAccessor[T]{
+		Name:     name,
+		Default:  def,
+		Required: required,
+		SetText: func(target T, text string) error {
+			v, err := parse(text)
+			if err != nil {
+				return err
+			}
+			*field(target) = v
+			return nil
+		},
+		IsZero: func(target T) bool {
+			var zero F
+			return *field(target) == zero
+		},
+	}
+}
+
+var schemas sync.Map // map[*T][]FieldAccessor[T], keyed by a typed nil pointer
+
+// RegisterSchema registers accessors for the fields of T. New and
+// NewWithConfig then use them for T instead of reflecting over struct tags:
+// zero fields are set from Default, options are applied, and Required fields
+// are checked with IsZero. Building with the optionator_noreflect tag limits
+// New to registered types, for targets such as TinyGo/WASM.
+func RegisterSchema[T any](fields ...FieldAccessor[T]) {
+	schemas.Store((*T)(nil), fields)
+}
+
+// lookupSchema returns the accessors registered for T, if any.
+func lookupSchema[T any]() ([]FieldAccessor[T], bool) {
+	s, ok := schemas.Load((*T)(nil))
+	if !ok {
+		return nil, false
+	}
+	return s.([]FieldAccessor[T]), true
+}
+
+// newFromSchema runs the construction pipeline using registered accessors.
+func newFromSchema[T any](target T, fields []FieldAccessor[T], opts []Option[T]) (T, error) {
+	for _, f := range fields {
+		if f.Default != "" && f.IsZero(target) {
+			if err := f.SetText(target, f.Default); err != nil {
+				return target, fmt.Errorf("error setting default for field %s: %w", f.Name, err)
+			}
+		}
+	}
+	for _, opt := range opts {
+		if err := opt(target); err != nil {
+			return target, err
+		}
+	}
+	for _, f := range fields {
+		if f.Required && f.IsZero(target) {
+			return target, fmt.Errorf("required field %s is zero", f.Name)
+		}
+	}
+	return target, nil
+}
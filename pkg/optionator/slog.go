@@ -0,0 +1,35 @@
+//go:build go1.21
+
+package optionator
+
+import "log/slog"
+
+// LogAttrs returns a "config" attribute group summarizing target for the
+// log lines of a service, as in logger.With(attr): the fingerprint of the
+// configuration, then every field that no longer holds its default, keyed
+// by its dotted path, so the lines show what was configured and everything
+// else is known to be a default. Secret fields are logged as Redacted or
+// masked as their secret tag asks.
+func LogAttrs(target any) (slog.Attr, error) {
+	fp, err := Fingerprint(target)
+	if err != nil {
+		return slog.Attr{}, err
+	}
+	v, err := structValue(target)
+	if err != nil {
+		return slog.Attr{}, err
+	}
+	attrs := []any{slog.String("fingerprint", fp)}
+	for _, fi := range describeType(v.Type(), defaultConfig) {
+		field, ok := lookupIndexes(v, fi.indexes)
+		if !ok || isDefault(fi, field) {
+			continue
+		}
+		if fi.Secret {
+			attrs = append(attrs, slog.String(fi.Path, maskSecret(fi.Mask, plainValue(field))))
+			continue
+		}
+		attrs = append(attrs, slog.Any(fi.Path, plainValue(field)))
+	}
+	return slog.Group("config", attrs...), nil
+}
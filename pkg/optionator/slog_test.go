@@ -0,0 +1,42 @@
+//go:build go1.21
+
+package optionator
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogAttrs(t *testing.T) {
+	type db struct {
+		Host     string `default:"localhost"`
+		Password string `secret:"true"`
+	}
+	type service struct {
+		Port int `default:"8080"`
+		Name string
+		DB   db
+	}
+	cfg, err := New(&service{}, With[*service]("Name", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DB.Password = "hunter2"
+	attr, err := LogAttrs(cfg)
+	if err != nil {
+		t.Fatalf("LogAttrs: %v", err)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("starting", attr)
+	out := buf.String()
+	for _, want := range []string{"config.fingerprint=", "config.Name=api", "config.DB.Password=[REDACTED]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line lacks %s: %s", want, out)
+		}
+	}
+	if strings.Contains(out, "Port") || strings.Contains(out, "hunter2") {
+		t.Errorf("log line shows defaults or secrets: %s", out)
+	}
+}
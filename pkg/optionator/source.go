@@ -0,0 +1,192 @@
+package optionator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// Source supplies configuration values as a tree of maps keyed by field name,
+// the shape produced by decoding a JSON object: nested structs are nested
+// maps. Keys match field names case-insensitively.
+type Source interface {
+	// Name identifies the source in errors, e.g. "file:/etc/app.json".
+	Name() string
+	Load(ctx context.Context) (map[string]any, error)
+}
+
+// SourceKind classifies a source for from tags, as in `from:"env,flag"`.
+// Sources declare it with a Kind method; a source without one has no kind
+// and cannot set fields restricted by a from tag.
+type SourceKind string
+
+// The kinds of the sources of this package. Values of command-line flags
+// bound with BindFlags have FromFlag.
+const (
+	FromMap         SourceKind = "map"
+	FromFile        SourceKind = "file"
+	FromEnv         SourceKind = "env"
+	FromFlag        SourceKind = "flag"
+	FromFeatureFlag SourceKind = "featureflag"
+	FromPatch       SourceKind = "patch"
+	FromStore       SourceKind = "store"
+	FromDefaults    SourceKind = "defaults"
+	FromRegistry    SourceKind = "registry"
+)
+
+// MapSource is a Source backed by an in-memory map.
+type MapSource struct {
+	Values map[string]any
+}
+
+func (s MapSource) Name() string { return "map" }
+
+func (MapSource) Kind() SourceKind { return FromMap }
+
+func (s MapSource) Load(ctx context.Context) (map[string]any, error) { return s.Values, nil }
+
+// FileSource is a Source that reads a JSON object from a file, or the
+// documents of a YAML file when Path ends in .yaml or .yml. A file may
+// extend a parent with ExtendsKey, pull in others with IncludeKey and carry
+// per-profile documents under ProfilesKey or, in YAML, as separate
+// documents marked with ProfileKey. Included files are verified and
+// decrypted like the main file, with their signatures read from the
+// included path with ".sig" appended.
+type FileSource struct {
+	Path string
+	// Verifier, if set, must accept the file's detached signature, read
+	// from SignaturePath or, by default, Path with ".sig" appended.
+	Verifier      Verifier
+	SignaturePath string
+	// Decrypter, if set, decrypts the whole file, after signature
+	// verification and before decoding.
+	Decrypter Decrypter
+}
+
+func (s FileSource) Name() string { return "file:" + s.Path }
+
+func (FileSource) Kind() SourceKind { return FromFile }
+
+func (s FileSource) Load(ctx context.Context) (map[string]any, error) {
+	return s.loadFile(ctx, s.Path, nil)
+}
+
+// unwrap verifies and decrypts the raw contents of the file at path.
+func (s FileSource) unwrap(path string, data []byte) ([]byte, error) {
+	if s.Verifier != nil {
+		sigPath := s.SignaturePath
+		if sigPath == "" || path != s.absPath() {
+			sigPath = path + ".sig"
+		}
+		sig, err := os.ReadFile(sigPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Verifier.Verify(data, sig); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if s.Decrypter != nil {
+		plain, err := s.Decrypter.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", path, err)
+		}
+		data = plain
+	}
+	return data, nil
+}
+
+func (s FileSource) absPath() string {
+	abs, err := filepath.Abs(s.Path)
+	if err != nil {
+		return s.Path
+	}
+	return abs
+}
+
+// decodeJSON decodes a JSON object, keeping numbers as json.Number so large
+// integers survive intact.
+func decodeJSON(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var values map[string]any
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// NewWithSources is like NewWithConfig but also loads the given sources, in
+// order, after defaults are set and before options are applied.
+func NewWithSources[T any](target T, config Config, sources []Source, opts ...Option[T]) (T, error) {
+	config.Sources = append(append([]Source{}, config.Sources...), sources...)
+	return NewWithConfig(target, config, opts...)
+}
+
+// loadedSource holds the values loaded from a source, ready to bind.
+type loadedSource struct {
+	src    Source
+	values map[string]any
+}
+
+// sourceLoader returns the source values for a construction.
+type sourceLoader func(ctx context.Context) ([]loadedSource, error)
+
+// fetchSources loads every configured source and decrypts its values. It
+// stops at the first required source that fails and skips optional ones.
+func fetchSources(ctx context.Context, config Config) ([]loadedSource, error) {
+	if len(config.Sources) == 0 {
+		return nil, nil
+	}
+	ctx = withConfig(ctx, config)
+	loaded := make([]loadedSource, 0, len(config.Sources))
+	for _, src := range config.Sources {
+		span := startSpan(config, "source")
+		span.SetAttribute("source", src.Name())
+		start := time.Now()
+		values, err := src.Load(ctx)
+		timeSince(ctx, config, "source", src.Name(), start)
+		if err == nil && config.ValueDecrypter != nil {
+			values, err = decryptValues(values, config.ValueDecrypter)
+		}
+		span.End(err)
+		if err != nil && isOptional(src) {
+			warn(ctx, "", "skipped optional source %s: %v", src.Name(), err)
+			continue
+		}
+		if err != nil {
+			if err := salvage(ctx, "", fmt.Errorf("source %s: %w", src.Name(), err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		loaded = append(loaded, loadedSource{src, values})
+	}
+	return loaded, nil
+}
+
+// bindSources binds loaded source values onto v, in order.
+func bindSources(ctx context.Context, v reflect.Value, loaded []loadedSource, config Config) error {
+	for _, ls := range loaded {
+		recordRaw(ctx, ls.values)
+		b := binder{config: config, weak: isWeaklyTyped(ls.src), ctx: ctx, source: sourceKind(ls.src)}
+		if err := b.bindMap(v, ls.values, ""); err != nil {
+			return fmt.Errorf("source %s: %w", ls.src.Name(), err)
+		}
+	}
+	return nil
+}
+
+// sourceKind returns the kind src declares, matched against from tags, or
+// "" if it declares none.
+func sourceKind(src Source) SourceKind {
+	if k, ok := src.(interface{ Kind() SourceKind }); ok {
+		return k.Kind()
+	}
+	return ""
+}
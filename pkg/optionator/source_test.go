@@ -0,0 +1,1165 @@
+package optionator
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewWithSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	if err := os.WriteFile(path, []byte(`{"address": "10.0.0.2", "timeout": "5s", "nested": {"port": 9443}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewWithSources(&Server{}, defaultConfig,
+		[]Source{FileSource{Path: path}, MapSource{Values: map[string]any{"MaxConns": 50}}},
+		With[*Server]("MaxConns", 60),
+	)
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.Address != "10.0.0.2" || s.Timeout != 5*time.Second || s.Nested.Port != 9443 {
+		t.Errorf("File values not applied: %+v %+v", s, s.Nested)
+	}
+	if s.Nested.Host != "localhost" {
+		t.Errorf("Expected Nested.Host default to survive, got %q", s.Nested.Host)
+	}
+	if s.MaxConns != 60 {
+		t.Errorf("Expected options to win over sources, got %d", s.MaxConns)
+	}
+
+	_, err = NewWithSources(&Server{}, defaultConfig, []Source{MapSource{Values: map[string]any{"MaxConns": 1.5}}})
+	if err == nil {
+		t.Errorf("Expected error for lossy float to int conversion")
+	}
+}
+
+type flakySource struct {
+	failures int
+	calls    int
+	delay    time.Duration
+}
+
+func (s *flakySource) Name() string { return "flaky" }
+
+func (s *flakySource) Load(ctx context.Context) (map[string]any, error) {
+	s.calls++
+	time.Sleep(s.delay)
+	if s.calls <= s.failures {
+		return nil, errors.New("unavailable")
+	}
+	return map[string]any{"MaxConns": 7}, nil
+}
+
+func TestPolicy(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "cache.json")
+	src := &flakySource{failures: 2}
+	s, err := NewWithSources(&Server{}, defaultConfig, []Source{
+		WithPolicy(src, Policy{Retries: 2, Backoff: time.Millisecond, CacheFile: cache}),
+	})
+	if err != nil || s.MaxConns != 7 || src.calls != 3 {
+		t.Fatalf("Expected success on third attempt, got %v (calls %d)", err, src.calls)
+	}
+
+	// The source is now down for good; the cached copy is used instead.
+	down := &flakySource{failures: 100}
+	config := defaultConfig
+	config.Sources = []Source{WithPolicy(down, Policy{Retries: 1, CacheFile: cache})}
+	s, report, err := NewWithReport(&Server{}, config)
+	if err != nil || s.MaxConns != 7 {
+		t.Fatalf("Expected cached value, got %v", err)
+	}
+	if w := report.Warnings(); len(w) != 1 || !strings.HasPrefix(w[0].Message, "source flaky: unavailable; using cache from 0s ago") {
+		t.Errorf("warnings = %v", w)
+	}
+
+	// An unwritable cache does not fail a successful load.
+	unwritable := filepath.Join(t.TempDir(), "missing", "cache.json")
+	config.Sources = []Source{WithPolicy(&flakySource{}, Policy{CacheFile: unwritable})}
+	s, report, err = NewWithReport(&Server{}, config)
+	if err != nil || s.MaxConns != 7 {
+		t.Fatalf("Expected loaded value despite cache failure, got %v", err)
+	}
+	if w := report.Warnings(); len(w) != 1 || !strings.Contains(w[0].String(), "writing cache") {
+		t.Errorf("warnings = %v", w)
+	}
+
+	slow := &flakySource{delay: 50 * time.Millisecond}
+	if _, err := WithPolicy(slow, Policy{Timeout: time.Millisecond}).Load(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestSignedFileSource(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	payload := []byte(`{"MaxConns": 9}`)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sig", []byte(sig+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	src := FileSource{Path: path, Verifier: Ed25519Verifier{Keys: StaticKeys{pub}}}
+	s, err := NewWithSources(&Server{}, defaultConfig, []Source{src})
+	if err != nil || s.MaxConns != 9 {
+		t.Fatalf("Expected signed payload to load, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"MaxConns": 10}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWithSources(&Server{}, defaultConfig, []Source{src}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered payload, got %v", err)
+	}
+}
+
+func TestEncryptedFileSource(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	seal := func(plain string) []byte { return gcm.Seal(append([]byte{}, nonce...), nonce, []byte(plain), nil) }
+	open := DecrypterFunc(func(ciphertext []byte) ([]byte, error) {
+		return gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	})
+
+	secret := EncryptedValuePrefix + base64.StdEncoding.EncodeToString(seal("10.1.1.1"))
+	path := filepath.Join(t.TempDir(), "server.json.enc")
+	if err := os.WriteFile(path, seal(`{"MaxConns": 11, "Address": "`+secret+`"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig
+	config.ValueDecrypter = open
+	s, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path, Decrypter: open}})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.MaxConns != 11 || s.Address != "10.1.1.1" {
+		t.Errorf("Unexpected values %+v", s)
+	}
+}
+
+func TestFileSourceProfilesAndIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("base.json", `{"MaxConns": 10, "Nested": {"Port": 1000, "Host": "base"}}`)
+	path := write("app.json", `{
+		"$include": "base.json",
+		"Nested": {"Port": 2000},
+		"$profiles": {"prod": {"MaxConns": 500, "Nested": {"Host": "prod.internal"}}}
+	}`)
+
+	config := defaultConfig
+	config.Profile = "prod"
+	s, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.MaxConns != 500 || s.Nested.Port != 2000 || s.Nested.Host != "prod.internal" {
+		t.Errorf("Unexpected merge result %+v %+v", s, s.Nested)
+	}
+	s, err = NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: path}})
+	if err != nil || s.MaxConns != 10 || s.Nested.Host != "base" {
+		t.Errorf("Expected base values without a profile, got %+v (%v)", s, err)
+	}
+
+	write("a.json", `{"$include": "b.json"}`)
+	write("b.json", `{"$include": ["a.json"]}`)
+	if _, err := NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: filepath.Join(dir, "a.json")}}); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+}
+
+func TestFileSourceExtends(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("base.json", `{"Address": "base", "MaxConns": 1, "Nested": {"Port": 1, "Host": "base"}}`)
+	write("prod.json", `{"$extends": "base.json", "MaxConns": 2, "Nested": {"Port": 2}}`)
+	write("prod-eu.json", `{"$extends": "prod.json", "Nested": {"Port": 3}}`)
+	s, err := NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: filepath.Join(dir, "prod-eu.json")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Address != "base" || s.MaxConns != 2 || s.Nested.Port != 3 || s.Nested.Host != "base" {
+		t.Errorf("got %+v, nested %+v", s, s.Nested)
+	}
+
+	write("base.json", `{"$extends": "prod-eu.json"}`)
+	if _, err := NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: filepath.Join(dir, "prod.json")}}); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("expected a cycle error, got %v", err)
+	}
+}
+
+func TestFileSourceYAMLProfiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("base.yaml", "MaxConns: 10\nNested:\n  Port: 1000\n  Host: base\n")
+	write("prod-secrets.json", `{"Address": "10.0.0.1"}`)
+	path := write("app.yaml", `# shared by every profile
+$include: base.yaml
+Nested:
+  Port: 2000
+---
+$profile: prod
+$include: prod-secrets.json
+MaxConns: 500
+Nested: {Host: "prod.internal"}
+---
+$profile: [dev, test]
+Timeout: 1s
+`)
+	config := defaultConfig
+	config.Profile = "prod"
+	s, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MaxConns != 500 || s.Address != "10.0.0.1" || s.Timeout != 30*time.Second || s.Nested.Port != 2000 || s.Nested.Host != "prod.internal" {
+		t.Errorf("prod: got %+v, nested %+v", s, s.Nested)
+	}
+	config.Profile = "test"
+	s, err = NewWithSources(&Server{}, config, []Source{FileSource{Path: path}})
+	if err != nil || s.MaxConns != 10 || s.Address != "0.0.0.0" || s.Timeout != time.Second || s.Nested.Host != "base" {
+		t.Errorf("test: got %+v (%v)", s, err)
+	}
+
+	write("a.yaml", "$include: b.yaml\n")
+	write("b.yaml", "---\n$include: [a.yaml]\n")
+	if _, err := NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: filepath.Join(dir, "a.yaml")}}); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	docs, err := decodeYAML([]byte(`%YAML 1.2
+---
+name: 'it''s'   # comment
+url: http://example.com/#frag
+quoted: "a: b # c"
+count: 12
+ratio: -1.5
+on: true
+empty: ~
+tags: [a, "b, c", 3]
+limits: {cpu: 2, mem: 1Gi}
+hosts:
+- one
+- name: two
+  port: 2
+-
+  - nested
+script: |
+  echo hi
+    indented
+folded: >-
+  a
+  b
+
+  c
+...
+---
+# empty document
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{{
+		"name":   "it's",
+		"url":    "http://example.com/#frag",
+		"quoted": "a: b # c",
+		"count":  json.Number("12"),
+		"ratio":  json.Number("-1.5"),
+		"on":     true,
+		"empty":  nil,
+		"tags":   []any{"a", "b, c", json.Number("3")},
+		"limits": map[string]any{"cpu": json.Number("2"), "mem": "1Gi"},
+		"hosts":  []any{"one", map[string]any{"name": "two", "port": json.Number("2")}, []any{"nested"}},
+		"script": "echo hi\n  indented\n",
+		"folded": "a b\nc",
+	}, {}}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("got %#v", docs)
+	}
+
+	for _, bad := range []string{"a: 1\n  b: 2\n", "a: 1\na: 2\n", "a: &x 1\n", "- a\n", "a: [1, 2\n", "a:\n\tb: 1\n"} {
+		if _, err := decodeYAML([]byte(bad)); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OPTIONATOR_TEST_REGION", "eu-west-1")
+	if err := os.WriteFile(filepath.Join(dir, "host"), []byte("db.internal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "app.json")
+	content := `{"Address": "{{ env "OPTIONATOR_TEST_REGION" }}", "Nested": {"Host": "{{ file "host" }}"}, "MaxConns": {{ env "OPTIONATOR_TEST_UNSET" | default "42" }}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig
+	config.TemplateFiles = true
+	config.EvalLimits.AllowFiles = true
+	s, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.Address != "eu-west-1" || s.Nested.Host != "db.internal" || s.MaxConns != 42 {
+		t.Errorf("Unexpected values %+v %+v", s, s.Nested)
+	}
+	if _, err := NewWithSources(&Server{}, defaultConfig, []Source{FileSource{Path: path}}); err == nil {
+		t.Errorf("Expected templates to be left alone unless TemplateFiles is set")
+	}
+}
+
+func TestWeakTyping(t *testing.T) {
+	type Loose struct {
+		Name    string
+		Enabled bool
+		Count   int
+		Ratio   float64
+		Hosts   []string
+		Ports   []int
+	}
+	values := map[string]any{
+		"Name":    12,
+		"Enabled": json.Number("1"),
+		"Count":   "3.0",
+		"Ratio":   "",
+		"Hosts":   "a, b",
+		"Ports":   json.Number("80"),
+	}
+	if _, err := NewWithSources(&Loose{}, defaultConfig, []Source{MapSource{Values: values}}); err == nil {
+		t.Fatalf("Expected strict binding to reject loosely typed values")
+	}
+	l, err := NewWithSources(&Loose{Ratio: 2}, defaultConfig, []Source{WithWeakTyping(MapSource{Values: values})})
+	if err != nil {
+		t.Fatalf("Error binding weakly typed values: %v", err)
+	}
+	want := Loose{Name: "12", Enabled: true, Count: 3, Hosts: []string{"a", "b"}, Ports: []int{80}}
+	if !reflect.DeepEqual(*l, want) {
+		t.Errorf("Expected %+v, got %+v", want, *l)
+	}
+	if _, err := NewWithSources(&Loose{}, defaultConfig, []Source{WithWeakTyping(MapSource{Values: map[string]any{"Count": "3.5"}})}); err == nil {
+		t.Errorf("Expected error for non-integral count")
+	}
+}
+
+type countingSource struct {
+	MapSource
+	loads int
+}
+
+func (s *countingSource) Load(ctx context.Context) (map[string]any, error) {
+	s.loads++
+	return s.MapSource.Load(ctx)
+}
+
+func TestNewBatch(t *testing.T) {
+	src := &countingSource{MapSource: MapSource{Values: map[string]any{"Host": "shared.example.com"}}}
+	config := defaultConfig
+	config.Sources = []Source{src}
+	protos := []*NestedConfig{{}, {}, {}}
+	per := [][]Option[*NestedConfig]{nil, {With[*NestedConfig]("Port", 9001)}, {With[*NestedConfig]("Port", 9002)}}
+	got, err := NewBatchWithConfig(protos, config, nil, per)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.loads != 1 {
+		t.Errorf("source loaded %d times, want once", src.loads)
+	}
+	for i, want := range []int{8080, 9001, 9002} {
+		if got[i].Port != want || got[i].Host != "shared.example.com" {
+			t.Errorf("item %d = %+v", i, got[i])
+		}
+	}
+	if _, err := NewBatch(protos, nil, per[:1]); err == nil {
+		t.Error("expected an error for mismatched option lists")
+	}
+}
+
+func TestMemo(t *testing.T) {
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{"Host": "example.com"}}}
+	memo := NewMemo(func() *NestedConfig { return &NestedConfig{} }, config)
+	ctx := context.Background()
+	a, err := memo.Get(ctx, map[string]any{"Port": 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := memo.Get(ctx, map[string]any{"Port": 9000})
+	c, _ := memo.Get(ctx, map[string]any{"Port": 9001})
+	if a != b {
+		t.Error("identical overrides built a new instance")
+	}
+	if a == c || c.Port != 9001 || c.Host != "example.com" {
+		t.Errorf("different overrides gave %+v", c)
+	}
+	memo.Reset()
+	if d, _ := memo.Get(ctx, map[string]any{"Port": 9000}); d == a {
+		t.Error("Reset kept the cached instance")
+	}
+
+	memo.SetSize(2)
+	for port := 1; port <= 10; port++ {
+		memo.Get(ctx, map[string]any{"Port": port})
+	}
+	if n := memo.order.Len(); n != 2 {
+		t.Errorf("memo keeps %d instances, want 2", n)
+	}
+	e, _ := memo.Get(ctx, map[string]any{"Port": 10})
+	if f, _ := memo.Get(ctx, map[string]any{"Port": 10}); e != f {
+		t.Error("recent instance was evicted")
+	}
+}
+
+func TestOptionalSources(t *testing.T) {
+	config := defaultConfig
+	config.Sources = []Source{
+		MapSource{Values: map[string]any{"Host": "example.com"}},
+		Optional(WithWeakTyping(&flakySource{failures: 1})),
+		MapSource{Values: map[string]any{"Port": 9000}},
+	}
+	cfg, report, err := NewWithReport(&NestedConfig{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "example.com" || cfg.Port != 9000 {
+		t.Errorf("got %+v", cfg)
+	}
+	if w := report.Warnings(); len(w) != 1 || w[0].Message != "skipped optional source flaky: unavailable" {
+		t.Errorf("warnings = %v", w)
+	}
+
+	config.Sources = []Source{&flakySource{failures: 1}}
+	if _, err := NewWithConfig(&NestedConfig{}, config); err == nil {
+		t.Error("expected a required source failure to be fatal")
+	}
+}
+
+func TestPlistSettings(t *testing.T) {
+	const plist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>ServerHost</key>
+	<string>example.com</string>
+	<key>ServerPort</key>
+	<integer>9000</integer>
+	<key>Verbose</key>
+	<integer>1</integer>
+	<key>Recent</key>
+	<array><string>a</string><real>1.5</real><true/></array>
+</dict>
+</plist>`
+	settings, err := decodePlist([]byte(plist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := settings["Recent"]; !reflect.DeepEqual(got, []any{"a", 1.5, true}) {
+		t.Errorf("Recent = %#v", got)
+	}
+	type Desktop struct {
+		Verbose bool `setting:"Verbose"`
+		Server  struct {
+			Host string `setting:"ServerHost"`
+			Port int    `setting:"ServerPort"`
+		}
+	}
+	values, err := settingValues[Desktop](defaultConfig, func(key string) (any, bool) {
+		v, ok := settings[key]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewWithSources(&Desktop{}, defaultConfig, []Source{MapSource{Values: values}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Verbose || cfg.Server.Host != "example.com" || cfg.Server.Port != 9000 {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	m := &Migrations{Current: 2}
+	m.Register(0, func(v map[string]any) (map[string]any, error) {
+		if addr, ok := v["Address"]; ok {
+			v["Host"] = addr
+			delete(v, "Address")
+		}
+		return v, nil
+	})
+	m.Register(1, func(v map[string]any) (map[string]any, error) {
+		v["Nested"] = map[string]any{"Host": v["Host"]}
+		delete(v, "Host")
+		return v, nil
+	})
+	type Root struct {
+		Nested NestedConfig
+	}
+	old := MapSource{Values: map[string]any{"Address": "old.example.com"}}
+	cfg, err := NewWithSources(&Root{}, defaultConfig, []Source{WithMigrations(old, m)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Nested.Host != "old.example.com" {
+		t.Errorf("Host = %q", cfg.Nested.Host)
+	}
+	if _, ok := old.Values["Address"]; !ok {
+		t.Error("migration modified the source's values")
+	}
+	values, err := WithMigrations(old, m).Load(context.Background())
+	if err != nil || values[VersionKey] != 2 {
+		t.Errorf("got %v, %v", values, err)
+	}
+	newer := MapSource{Values: map[string]any{VersionKey: 3}}
+	if _, err := NewWithSources(&Root{}, defaultConfig, []Source{WithMigrations(newer, m)}); err == nil {
+		t.Error("expected an error for a newer schema version")
+	}
+}
+
+func TestAliasKeys(t *testing.T) {
+	type Server struct {
+		ListenAddr string `alias:"Addr,Address"`
+	}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{"address": "old:80"}}}
+	cfg, report, err := NewWithReport(&Server{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ListenAddr != "old:80" {
+		t.Errorf("ListenAddr = %q", cfg.ListenAddr)
+	}
+	if w := report.Warnings(); len(w) != 1 || w[0].String() != "warning: ListenAddr: old key address used; rename it to ListenAddr" {
+		t.Errorf("warnings = %v", w)
+	}
+
+	config.Sources = []Source{MapSource{Values: map[string]any{"Addr": "old:80", "ListenAddr": "new:80"}}}
+	if cfg, _ := NewWithConfig(&Server{}, config); cfg.ListenAddr != "new:80" {
+		t.Errorf("ListenAddr = %q, want the new key to win", cfg.ListenAddr)
+	}
+
+	for _, name := range []string{"ListenAddr", "listenaddr", "Address"} {
+		if cfg, err := New(&Server{}, With[*Server](name, "opt:80")); err != nil || cfg.ListenAddr != "opt:80" {
+			t.Errorf("With(%q): got %+v, %v", name, cfg, err)
+		}
+	}
+}
+
+type envSource map[string]any
+
+func (envSource) Name() string { return "env" }
+
+func (envSource) Kind() SourceKind { return FromEnv }
+
+func (s envSource) Load(ctx context.Context) (map[string]any, error) { return s, nil }
+
+func TestFromTag(t *testing.T) {
+	type DB struct {
+		Host     string
+		Password string `from:"env,flag"`
+	}
+	path := filepath.Join(t.TempDir(), "db.json")
+	if err := os.WriteFile(path, []byte(`{"Host": "db", "Password": "hunter2"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewWithSources(&DB{}, defaultConfig, []Source{FileSource{Path: path}})
+	if err == nil || !strings.Contains(err.Error(), "field Password may only be set from env, flag") {
+		t.Errorf("got %v", err)
+	}
+
+	cfg, err := NewWithSources(&DB{}, defaultConfig, []Source{envSource{"Password": "hunter2"}},
+		With[*DB]("Host", "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "hunter2" || cfg.Host != "db" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	// The kind comes from the Kind method, not the name.
+	unkinded := struct{ Source }{envSource{"Password": "hunter2"}}
+	if _, err := NewWithSources(&DB{}, defaultConfig, []Source{unkinded}); err == nil {
+		t.Error("expected a source without a kind to be refused")
+	}
+
+	type Token struct {
+		Value string `from:"env"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opt, err := BindFlags[*Token](fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-value", "leaked"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(&Token{}, opt); err == nil || !strings.Contains(err.Error(), "field Value may only be set from env") {
+		t.Errorf("expected flag to be refused, got %v", err)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	type DB struct {
+		Password string        `secret:"true"`
+		Timeout  time.Duration `default:"5s"`
+	}
+	type App struct {
+		Name  string `default:"app"`
+		Tags  []string
+		DB    DB
+		Debug bool
+	}
+	cfg, err := New(&App{Tags: []string{"a", "b"}, DB: DB{Password: "hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var js, yaml, toml bytes.Buffer
+	if err := WriteJSON(&js, cfg, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := "{\n  \"Name\": \"app\",\n  \"Tags\": [\"a\",\"b\"],\n  \"DB\": {\n    \"Password\": \"[REDACTED]\",\n    \"Timeout\": \"5s\"\n  },\n  \"Debug\": false\n}\n"
+	if js.String() != wantJSON {
+		t.Errorf("JSON:\n%s", js.String())
+	}
+	ref := func(path string) string { return "vault:" + path }
+	if err := WriteYAML(&yaml, cfg, ExportOptions{NonDefault: true, SecretRef: ref}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Tags: [\"a\",\"b\"]\nDB:\n  Password: \"vault:DB.Password\"\n"; yaml.String() != want {
+		t.Errorf("YAML:\n%s", yaml.String())
+	}
+	if err := WriteTOML(&toml, cfg, ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Name = \"app\"\nTags = [\"a\", \"b\"]\nDebug = false\n\n[DB]\nPassword = \"[REDACTED]\"\nTimeout = \"5s\"\n"; toml.String() != want {
+		t.Errorf("TOML:\n%s", toml.String())
+	}
+
+	// The JSON export loads back.
+	path := filepath.Join(t.TempDir(), "app.json")
+	js.Reset()
+	WriteJSON(&js, cfg, ExportOptions{NonDefault: true})
+	os.WriteFile(path, js.Bytes(), 0o600)
+	back, err := NewWithSources(&App{}, defaultConfig, []Source{FileSource{Path: path}})
+	if err != nil || back.DB.Password != Redacted || len(back.Tags) != 2 {
+		t.Errorf("reloaded %+v, %v", back, err)
+	}
+}
+
+func TestWriteSample(t *testing.T) {
+	type Listener struct {
+		Addr string `default:":8080" desc:"Address to listen on."`
+	}
+	type Service struct {
+		Name     string `required:"true" desc:"Service name,\nshown in logs."`
+		Listener Listener
+	}
+	var yaml, toml bytes.Buffer
+	if err := WriteSample[Service](&yaml, "yaml"); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Service name,
+# shown in logs.
+# type: string, required
+Name: ""
+Listener:
+  # Address to listen on.
+  # type: string, default: :8080
+  Addr: ":8080"
+`
+	if yaml.String() != want {
+		t.Errorf("YAML:\n%s", yaml.String())
+	}
+	if err := WriteSample[*Service](&toml, "toml"); err != nil {
+		t.Fatal(err)
+	}
+	want = `# Service name,
+# shown in logs.
+# type: string, required
+Name = ""
+
+[Listener]
+# Address to listen on.
+# type: string, default: :8080
+Addr = ":8080"
+`
+	if toml.String() != want {
+		t.Errorf("TOML:\n%s", toml.String())
+	}
+}
+
+func TestTimings(t *testing.T) {
+	config := defaultConfig
+	config.SlowThreshold = 5 * time.Millisecond
+	config.Sources = []Source{&flakySource{delay: 10 * time.Millisecond}}
+	_, report, err := NewWithReport(&Server{}, config, With[*Server]("Address", "localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Timings) != 2 || report.Timings[0].Name != "flaky" || report.Timings[1].Name != "option 0" {
+		t.Fatalf("timings = %+v", report.Timings)
+	}
+	if report.Timings[0].Duration < 10*time.Millisecond {
+		t.Errorf("source took %v", report.Timings[0].Duration)
+	}
+	if w := report.Warnings(); len(w) != 1 || !strings.HasPrefix(w[0].Message, "slow source: flaky took") {
+		t.Errorf("warnings = %v", w)
+	}
+}
+
+func TestStrictness(t *testing.T) {
+	type Config struct {
+		Port int    `default:"80"`
+		Mode string `default:"x"`
+		Size int8   `default:"big"`
+	}
+	src := MapSource{Values: map[string]any{"Port": json.Number("80.5"), "Extra": true}}
+	config := defaultConfig
+	config.Sources = []Source{src}
+
+	if _, err := NewWithConfig(&Config{}, config); err == nil {
+		t.Errorf("expected the bad default to fail by default")
+	}
+
+	config.Strictness = StrictnessWarn
+	cfg, report, err := NewWithReport(&Config{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 80 || cfg.Size != 0 || len(report.Warnings()) != 3 {
+		t.Errorf("got %+v, warnings %v", cfg, report.Warnings())
+	}
+
+	config.Strictness = StrictnessLenient
+	if _, report, err := NewWithReport(&Config{}, config); err != nil || len(report.Findings) != 0 {
+		t.Errorf("lenient: %v, %v", report.Findings, err)
+	}
+
+	SetStrictness(StrictnessStrict)
+	defer SetStrictness(StrictnessDefault)
+	config.Strictness = StrictnessDefault
+	config.Sources = []Source{MapSource{Values: map[string]any{"Extra": true}}}
+	_, err = NewWithConfig(&struct{ Port int }{}, config)
+	if err == nil || !strings.Contains(err.Error(), "unknown key Extra") {
+		t.Errorf("strict: got %v", err)
+	}
+}
+
+func TestBindRangeAndNull(t *testing.T) {
+	type Config struct {
+		Hosts  []string
+		Labels map[string]string
+		Small  int8
+		Level  uint8
+	}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{
+		"Hosts":  []any{"a", nil},
+		"Labels": map[string]any{"a": nil, "b": "x"},
+	}}}
+	cfg, err := NewWithConfig(&Config{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Hosts, []string{"a", ""}) || !reflect.DeepEqual(cfg.Labels, map[string]string{"a": "", "b": "x"}) {
+		t.Errorf("got %q %q", cfg.Hosts, cfg.Labels)
+	}
+
+	for _, tt := range []struct {
+		values map[string]any
+		want   Config
+	}{
+		{map[string]any{"Small": json.Number("300")}, Config{Small: 44}},
+		{map[string]any{"Small": "300"}, Config{Small: 44}},
+		{map[string]any{"Level": "257"}, Config{Level: 1}},
+	} {
+		config.Sources = []Source{MapSource{Values: tt.values}}
+		config.Strictness = StrictnessDefault
+		if _, err := NewWithConfig(&Config{}, config); err == nil || !strings.Contains(err.Error(), "overflows") {
+			t.Errorf("%v: expected an overflow error, got %v", tt.values, err)
+		}
+		config.Strictness = StrictnessWarn
+		cfg, report, err := NewWithReport(&Config{}, config)
+		if err != nil || len(report.Warnings()) != 1 || cfg.Small != tt.want.Small || cfg.Level != tt.want.Level {
+			t.Errorf("%v: got %+v, warnings %v, %v", tt.values, cfg, report.Warnings(), err)
+		}
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{
+		"maxconn": 5,
+		"Nested":  map[string]any{"Prot": 1, "Host": "h"},
+		"Zzz":     1,
+	}}}
+	_, report, err := NewWithReport(&Server{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range report.Warnings() {
+		got = append(got, f.Message)
+	}
+	sort.Strings(got)
+	want := []string{
+		"unknown key Nested.Prot; did you mean Port?",
+		"unknown key Zzz",
+		"unknown key maxconn; did you mean MaxConns?",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %q", got)
+	}
+}
+
+func TestReportExtra(t *testing.T) {
+	config := defaultConfig
+	config.Sources = []Source{
+		MapSource{Values: map[string]any{"Address": "a", "plugins": map[string]any{"auth": map[string]any{"ttl": 1}}}},
+		MapSource{Values: map[string]any{"Address": "b", "plugins": map[string]any{"cache": true}, "$schema": "x"}},
+	}
+	_, report, err := NewWithReport(&Server{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRaw := map[string]any{
+		"Address": "b",
+		"plugins": map[string]any{"auth": map[string]any{"ttl": 1}, "cache": true},
+		"$schema": "x",
+	}
+	if !reflect.DeepEqual(report.Raw(), wantRaw) {
+		t.Errorf("Raw() = %v", report.Raw())
+	}
+	if extra := report.Extra(); !reflect.DeepEqual(extra, map[string]any{"plugins": wantRaw["plugins"]}) {
+		t.Errorf("Extra() = %v", extra)
+	}
+}
+
+func TestRawDecode(t *testing.T) {
+	type Host struct {
+		Plugins map[string]Raw
+	}
+	type Auth struct {
+		TTL    int    `default:"60"`
+		Issuer string `required:"true"`
+	}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{"Plugins": map[string]any{
+		"auth":  map[string]any{"Issuer": "me"},
+		"other": map[string]any{"TTL": json.Number("5")},
+	}}}}
+	host, err := NewWithConfig(&Host{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var auth Auth
+	if err := host.Plugins["auth"].Decode(&auth); err != nil || auth != (Auth{TTL: 60, Issuer: "me"}) {
+		t.Errorf("auth = %+v, %v", auth, err)
+	}
+	if err := host.Plugins["other"].Decode(&Auth{}); err == nil || !strings.Contains(err.Error(), "Issuer") {
+		t.Errorf("expected the missing Issuer to fail, got %v", err)
+	}
+}
+
+func TestDebugBundle(t *testing.T) {
+	type DB struct {
+		User     string
+		Password string `secret:"true"`
+	}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{"User": "app", "Password": "hunter2", "Typo": 1}}}
+	var buf bytes.Buffer
+	if err := DebugBundle(&buf, &DB{}, config); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for name, want := range map[string]string{
+		"version.txt":         "go ",
+		"config.json":         `"User": "app"`,
+		"sources/00-map.json": `"Password": "[REDACTED]"`,
+		"report.txt":          "warning: unknown key Typo",
+		"fields.json":         `"Tag": "secret:\"true\""`,
+	} {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("%s = %q, want it to contain %q", name, files[name], want)
+		}
+	}
+	for name, content := range files {
+		if strings.Contains(content, "hunter2") {
+			t.Errorf("%s leaks the secret", name)
+		}
+	}
+}
+
+func TestHumanNumbers(t *testing.T) {
+	type Limits struct {
+		Max   int64   `default:"1_000_000"`
+		Ratio float64 `default:"1,234.5"`
+		Big   uint32
+		Odd   int
+	}
+	config := defaultConfig
+	config.HumanNumbers = true
+	config.Sources = []Source{MapSource{Values: map[string]any{"Big": json.Number("2e9"), "Odd": "1,000,000"}}}
+	cfg, err := NewWithConfig(&Limits{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Max != 1000000 || cfg.Ratio != 1234.5 || cfg.Big != 2000000000 || cfg.Odd != 1000000 {
+		t.Errorf("got %+v", cfg)
+	}
+	for _, bad := range []string{"1,5", "1__0", "2.5e0"} {
+		config.Sources = []Source{MapSource{Values: map[string]any{"Odd": bad}}}
+		if _, err := NewWithConfig(&Limits{}, config); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+	if _, err := New(&Limits{}); err == nil {
+		t.Errorf("expected human numbers to need the option")
+	}
+}
+
+type celsius float64
+
+func TestDecodeHooks(t *testing.T) {
+	type Config struct {
+		Temp  celsius
+		Hosts []string
+	}
+	fahrenheit := func(from, to reflect.Type, data any) (any, bool, error) {
+		s, ok := data.(string)
+		if !ok || to != reflect.TypeOf(celsius(0)) || !strings.HasSuffix(s, "F") {
+			return nil, false, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSuffix(s, "F"), 64)
+		return celsius((f - 32) * 5 / 9), true, err
+	}
+	upper := func(from, to reflect.Type, data any) (any, bool, error) {
+		if s, ok := data.(string); ok && to.Kind() == reflect.String {
+			return strings.ToUpper(s), true, nil
+		}
+		return nil, false, nil
+	}
+	config := defaultConfig
+	config.DecodeHooks = []DecodeHook{fahrenheit, upper}
+	config.Sources = []Source{MapSource{Values: map[string]any{"Temp": "212F", "Hosts": []any{"a", "b"}}}}
+	cfg, err := NewWithConfig(&Config{}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Temp != 100 || !reflect.DeepEqual(cfg.Hosts, []string{"A", "B"}) {
+		t.Errorf("got %+v", cfg)
+	}
+	cfg, err = NewWithConfig(&Config{}, config, With[*Config]("Temp", "50F"))
+	if err != nil || cfg.Temp != 10 {
+		t.Errorf("With: %+v, %v", cfg, err)
+	}
+	config.Sources = []Source{MapSource{Values: map[string]any{"Temp": "xF"}}}
+	if _, err := NewWithConfig(&Config{}, config); err == nil {
+		t.Errorf("expected the hook's error")
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	for name, want := range map[string][3]string{
+		"MaxConns":  {"max_conns", "max-conns", "maxConns"},
+		"TLSConfig": {"tls_config", "tls-config", "tlsConfig"},
+	} {
+		if got := [3]string{SnakeCase(name), KebabCase(name), CamelCase(name)}; got != want {
+			t.Errorf("Expected %s to be named %v, got %v", name, want, got)
+		}
+	}
+
+	config := defaultConfig
+	config.NamingStrategy = SnakeCase
+	config.Strictness = StrictnessStrict
+	t.Setenv("APP_MAX_CONNS", "7")
+	t.Setenv("APP_NESTED__PORT", "8443")
+	s, err := NewWithSources(&Server{}, config, []Source{
+		MapSource{Values: map[string]any{"address": "10.0.0.3", "timeout": "2s"}},
+		EnvSource{Prefix: "APP_"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating server: %v", err)
+	}
+	if s.Address != "10.0.0.3" || s.MaxConns != 7 || s.Nested.Port != 8443 {
+		t.Errorf("Snake case keys not applied: %+v %+v", s, s.Nested)
+	}
+
+	m, err := ToMap(s, ExportOptions{Naming: SnakeCase})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["max_conns"] != 7 {
+		t.Errorf("Expected max_conns in ToMap output, got %v", m)
+	}
+	back, err := NewWithSources(&Server{}, config, []Source{MapSource{Values: m}})
+	if err != nil || back.MaxConns != 7 || back.Nested.Port != 8443 {
+		t.Errorf("Expected ToMap output to load back, got %+v, %v", back, err)
+	}
+	if got := SnakeCase.EnvName("APP_", "Nested.Port"); got != "APP_NESTED__PORT" {
+		t.Errorf("Expected APP_NESTED__PORT, got %s", got)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := BindFlagsWithConfig[*Server](fs, config); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("max_conns") == nil || fs.Lookup("nested.port") == nil {
+		t.Errorf("Expected flags named by the naming strategy")
+	}
+}
+
+func TestClockAndLookupEnv(t *testing.T) {
+	type Lease struct {
+		Issued  time.Time `default:"$now"`
+		Expires time.Time `default:"$now+24h" after:"now"`
+		Region  string
+		Limits  struct{ Burst int }
+	}
+	start := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	env := map[string]string{"LEASE_REGION": "eu-west-1", "LEASE_LIMITS__BURST": "9"}
+	config := defaultConfig
+	config.Clock = func() time.Time { return start }
+	config.LookupEnv = func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	config.Sources = []Source{EnvSource{Prefix: "LEASE_"}}
+	l, err := NewWithConfig(&Lease{}, config)
+	if err != nil {
+		t.Fatalf("Error creating lease: %v", err)
+	}
+	if !l.Issued.Equal(start) || !l.Expires.Equal(start.Add(24*time.Hour)) {
+		t.Errorf("Expected times from the clock, got %v and %v", l.Issued, l.Expires)
+	}
+	if l.Region != "eu-west-1" || l.Limits.Burst != 9 {
+		t.Errorf("Expected values from LookupEnv, got %+v", l)
+	}
+
+	config.Clock = func() time.Time { return start.Add(48 * time.Hour) }
+	if _, err := NewWithConfig(&Lease{}, config, With[*Lease]("Expires", start)); err == nil {
+		t.Errorf("Expected after:\"now\" to use the clock")
+	}
+}
+
+func TestEvalLimits(t *testing.T) {
+	type Checked struct {
+		Port int `default:"80" cel:"self > 0 && (self < 65536 || (self < 70000 && (self != 1 || (self != 2 && (self != 3)))))"`
+	}
+	type Derived struct {
+		Host string `default:"localhost"`
+		URL  string `default:"http://${Host}"`
+	}
+	config := defaultConfig
+	config.DisableDynamic = true
+	if _, err := NewWithConfig(&Checked{}, config); !errors.Is(err, errDynamicDisabled) {
+		t.Errorf("Expected cel tags to be disabled, got %v", err)
+	}
+	if _, err := NewWithConfig(&Derived{}, config); !errors.Is(err, errDynamicDisabled) {
+		t.Errorf("Expected derived defaults to be disabled, got %v", err)
+	}
+
+	config = defaultConfig
+	config.EvalLimits.MaxDepth = 4
+	if _, err := NewWithConfig(&Checked{}, config); err == nil || !strings.Contains(err.Error(), "limit of 4") {
+		t.Errorf("Expected cel nesting limit, got %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(filepath.Join(dir, "host"), []byte("db.internal"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"Nested": {"Host": "{{ file "host" }}"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config = defaultConfig
+	config.TemplateFiles = true
+	if _, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}}); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected file access to be denied, got %v", err)
+	}
+	config.EvalLimits = EvalLimits{MaxOutput: 8, AllowFiles: true}
+	if _, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}}); err == nil || !strings.Contains(err.Error(), "8 bytes") {
+		t.Errorf("Expected template output limit, got %v", err)
+	}
+	for name, content := range map[string]string{
+		"range":     `{"Address": "{{ range 2000000000 }}{{ end }}"}`,
+		"nested":    `{"Address": "{{ if true }}{{ else }}{{ range 10 }}{{ end }}{{ end }}"}`,
+		"recursion": `{{ define "a" }}{{ template "a" }}{{ template "a" }}{{ end }}{{ template "a" }}`,
+	} {
+		loop := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(loop, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: loop}}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("%s: expected the template to be rejected, got %v", name, err)
+		}
+	}
+	config.DisableDynamic = true
+	if _, err := NewWithSources(&Server{}, config, []Source{FileSource{Path: path}}); !errors.Is(err, errDynamicDisabled) {
+		t.Errorf("Expected templates to be disabled, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package optionator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Stability levels of the stability tag, as in `stability:"experimental"`.
+// A nested struct's level applies to the fields under it.
+const (
+	StabilityStable       = "stable"
+	StabilityBeta         = "beta"
+	StabilityExperimental = "experimental"
+)
+
+// checkExperimental rejects experimental fields of struct v holding
+// anything but their default, unless config.AllowExperimental is set.
+func checkExperimental(ctx context.Context, v reflect.Value, config Config) error {
+	if config.AllowExperimental {
+		return nil
+	}
+	for _, fi := range describeType(v.Type(), config) {
+		if fi.Stability != StabilityExperimental {
+			continue
+		}
+		field, ok := lookupIndexes(v, fi.indexes)
+		if !ok || isDefault(fi, field) {
+			continue
+		}
+		err := fmt.Errorf("experimental field %s is set without Config.AllowExperimental", fi.Path)
+		if err := tolerate(ctx, config, StrictnessStrict, fi.Path, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package optionator
+
+import (
+	"expvar"
+	"reflect"
+	"sync"
+)
+
+// FieldStats counts, for a field with a default tag, how often constructions
+// ended with the default value and how often with something else.
+type FieldStats struct {
+	Defaulted  uint64
+	Overridden uint64
+}
+
+var usage = struct {
+	sync.Mutex
+	byType map[string]map[string]*FieldStats
+}{byType: map[string]map[string]*FieldStats{}}
+
+// Stats returns a snapshot of the usage counters collected by constructions
+// with Config.CollectStats set, keyed by type name and then field path.
+func Stats() map[string]map[string]FieldStats {
+	usage.Lock()
+	defer usage.Unlock()
+	out := make(map[string]map[string]FieldStats, len(usage.byType))
+	for typ, fields := range usage.byType {
+		m := make(map[string]FieldStats, len(fields))
+		for path, fs := range fields {
+			m[path] = *fs
+		}
+		out[typ] = m
+	}
+	return out
+}
+
+// ResetStats clears all usage counters.
+func ResetStats() {
+	usage.Lock()
+	defer usage.Unlock()
+	usage.byType = map[string]map[string]*FieldStats{}
+}
+
+// PublishStats exposes Stats as an expvar variable with the given name.
+func PublishStats(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return Stats() }))
+}
+
+// recordStats compares every field that has a default tag against its parsed
+// default and updates the counters for the type of v.
+func recordStats(v reflect.Value, config Config) {
+	fields := describeType(v.Type(), config)
+	usage.Lock()
+	defer usage.Unlock()
+	byPath := usage.byType[v.Type().String()]
+	if byPath == nil {
+		byPath = map[string]*FieldStats{}
+		usage.byType[v.Type().String()] = byPath
+	}
+	for _, fi := range fields {
+		if fi.Default == "" {
+			continue
+		}
+		def := reflect.New(fi.Type).Elem()
+		if err := parseAndSetDefault(def, fi.Default, fi.Type); err != nil {
+			continue
+		}
+		fs := byPath[fi.Path]
+		if fs == nil {
+			fs = &FieldStats{}
+			byPath[fi.Path] = fs
+		}
+		if reflect.DeepEqual(fieldByIndexes(v, fi.indexes).Interface(), def.Interface()) {
+			fs.Defaulted++
+		} else {
+			fs.Overridden++
+		}
+	}
+}
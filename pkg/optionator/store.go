@@ -0,0 +1,112 @@
+package optionator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OverrideStore persists runtime overrides in a local JSON file and replays
+// them as a Source, so changes made at runtime survive restarts. Put it last
+// in the Sources of a Live and change it through ApplyOverride.
+//
+// The file records the schema Version it was written with. A file written
+// with an older version is upgraded through Migrations, if set; any other
+// mismatch fails rather than binding values whose meaning may have changed.
+type OverrideStore struct {
+	Path       string
+	Version    int
+	Migrations *Migrations
+
+	mu sync.Mutex
+}
+
+// storeFile is the on-disk layout of an OverrideStore.
+type storeFile struct {
+	Version int            `json:"version"`
+	Values  map[string]any `json:"values"`
+}
+
+func (s *OverrideStore) Name() string { return "store:" + s.Path }
+
+func (*OverrideStore) Kind() SourceKind { return FromStore }
+
+func (s *OverrideStore) Load(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// read returns the stored overrides; a missing file holds none.
+func (s *OverrideStore) read() (map[string]any, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := values["version"].(json.Number)
+	version, err := n.Int64()
+	migrate := err == nil && int(version) < s.Version && s.Migrations != nil && s.Migrations.Current == s.Version
+	if err != nil || (int(version) != s.Version && !migrate) {
+		return nil, fmt.Errorf("%s has schema version %q, want %d", s.Path, n, s.Version)
+	}
+	stored, _ := values["values"].(map[string]any)
+	if stored == nil {
+		stored = map[string]any{}
+	}
+	if migrate {
+		if stored, err = s.Migrations.Migrate(stored, int(version)); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Path, err)
+		}
+		delete(stored, VersionKey)
+	}
+	return stored, nil
+}
+
+func (s *OverrideStore) write(values map[string]any) error {
+	return writeCache(s.Path, map[string]any{"version": s.Version, "values": values})
+}
+
+// Reset removes every stored override.
+func (s *OverrideStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// ApplyOverride merges patch, a JSON merge patch in the shape of source
+// values, into store and reloads live, whose sources must include store.
+// If the reload fails the store is restored and the error returned.
+func ApplyOverride[T any](ctx context.Context, live *Live[T], store *OverrideStore, patch map[string]any) error {
+	store.mu.Lock()
+	prev, err := store.read()
+	if err == nil {
+		err = store.write(mergePatch(deepCopy(prev), patch))
+	}
+	store.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := live.Reload(ctx); err != nil {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if restoreErr := store.write(prev); restoreErr != nil {
+			return fmt.Errorf("%v; restoring %s: %w", err, store.Path, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
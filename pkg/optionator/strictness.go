@@ -0,0 +1,59 @@
+package optionator
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Strictness decides whether recoverable problems fail a construction:
+// source keys that match no field, numbers that do not fit their field
+// without loss, default tags that do not parse, and experimental fields set
+// without Config.AllowExperimental.
+type Strictness int
+
+const (
+	// StrictnessDefault defers to SetStrictness, and failing that keeps each
+	// problem's own default: unknown keys are warned about, while the other
+	// problems fail.
+	StrictnessDefault Strictness = iota
+	// StrictnessLenient ignores the problems. Lossy numbers are converted
+	// anyway, fields with bad default tags are left alone and experimental
+	// fields keep their values.
+	StrictnessLenient
+	// StrictnessWarn is like StrictnessLenient but records each problem as
+	// a warning on the report of NewWithReport.
+	StrictnessWarn
+	// StrictnessStrict fails construction on any of the problems.
+	StrictnessStrict
+)
+
+var globalStrictness int32
+
+// SetStrictness sets the level of constructions whose Config leaves
+// Strictness at StrictnessDefault.
+func SetStrictness(s Strictness) {
+	atomic.StoreInt32(&globalStrictness, int32(s))
+}
+
+// tolerate returns err, a problem found at path, if the strictness in
+// effect makes it fatal, and nil otherwise. fallback is the level used when
+// neither config nor SetStrictness choose one.
+func tolerate(ctx context.Context, config Config, fallback Strictness, path string, err error) error {
+	level := config.Strictness
+	if level == StrictnessDefault {
+		level = Strictness(atomic.LoadInt32(&globalStrictness))
+	}
+	if level == StrictnessDefault {
+		level = fallback
+	}
+	switch level {
+	case StrictnessLenient:
+		return nil
+	case StrictnessWarn:
+		if ctx != nil {
+			warn(ctx, path, "%v", err)
+		}
+		return nil
+	}
+	return salvage(ctx, path, err)
+}
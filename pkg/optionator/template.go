@@ -0,0 +1,117 @@
+package optionator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// renderTemplate runs a file's contents through text/template with a
+// restricted set of functions:
+//
+//	env "NAME"            the value of an environment variable
+//	file "path"           the contents of a file, relative to the config file;
+//	                      only with EvalLimits.AllowFiles
+//	default "x" VALUE     VALUE, or "x" if VALUE is empty
+//	required "msg" VALUE  VALUE, or an error with msg if VALUE is empty
+//
+// The function calls, the output size and file access are bounded by the
+// EvalLimits of config. Loops and template calls are rejected, since range
+// over an integer or a recursive template could run for ever between two
+// function calls.
+func renderTemplate(path string, data []byte, config Config) ([]byte, error) {
+	limits := config.evalLimits()
+	calls := 0
+	spend := func() error {
+		if calls++; calls > limits.MaxExpansions {
+			return fmt.Errorf("template exceeds the limit of %d function calls", limits.MaxExpansions)
+		}
+		return nil
+	}
+	funcs := template.FuncMap{
+		"env": func(name string) (string, error) {
+			value, _ := config.lookupEnv(name)
+			return value, spend()
+		},
+		"file": func(name string) (string, error) {
+			if err := spend(); err != nil {
+				return "", err
+			}
+			if !limits.AllowFiles {
+				return "", fmt.Errorf("file %s: file access is denied", name)
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(path), name)
+			}
+			b, err := os.ReadFile(name)
+			return strings.TrimRight(string(b), "\r\n"), err
+		},
+		"default": func(def, value string) (string, error) {
+			if value == "" {
+				return def, spend()
+			}
+			return value, spend()
+		},
+		"required": func(msg, value string) (string, error) {
+			if value == "" {
+				return "", errors.New(msg)
+			}
+			return value, spend()
+		},
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(funcs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Name() != tmpl.Name() {
+			return nil, fmt.Errorf("template %s: defining templates is not allowed", t.Name())
+		}
+	}
+	if tmpl.Tree != nil {
+		if err := checkTemplateNode(tmpl.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	out := &limitedBuffer{max: limits.MaxOutput}
+	if err := tmpl.Execute(out, nil); err != nil {
+		return nil, err
+	}
+	return out.buf, nil
+}
+
+// checkTemplateNode rejects the range and template actions in the tree
+// under n.
+func checkTemplateNode(n parse.Node) error {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkTemplateNode(c); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return fmt.Errorf("line %d: range is not allowed in config templates", n.Line)
+	case *parse.TemplateNode:
+		return fmt.Errorf("line %d: template calls are not allowed in config templates", n.Line)
+	}
+	return nil
+}
+
+func checkTemplateBranch(b *parse.BranchNode) error {
+	if err := checkTemplateNode(b.List); err != nil {
+		return err
+	}
+	return checkTemplateNode(b.ElseList)
+}
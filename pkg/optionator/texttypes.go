@@ -0,0 +1,70 @@
+package optionator
+
+import (
+	htmltemplate "html/template"
+	"reflect"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+// textType configures a pointer type from text that is neither a basic
+// kind nor an encoding.TextUnmarshaler. Values are treated as immutable:
+// copies share them. Templates are parsed without custom functions and
+// format as their parse tree, which is equivalent to but not always the
+// same as their source.
+type textType struct {
+	parse  func(text string) (any, error)
+	format func(v any) string
+}
+
+var textTypes = map[reflect.Type]textType{
+	reflect.TypeOf((*time.Location)(nil)): {
+		parse:  func(text string) (any, error) { return time.LoadLocation(text) },
+		format: func(v any) string { return v.(*time.Location).String() },
+	},
+	reflect.TypeOf((*regexp.Regexp)(nil)): {
+		parse:  func(text string) (any, error) { return regexp.Compile(text) },
+		format: func(v any) string { return v.(*regexp.Regexp).String() },
+	},
+	reflect.TypeOf((*template.Template)(nil)): {
+		parse: func(text string) (any, error) { return template.New("").Parse(text) },
+		format: func(v any) string {
+			if t := v.(*template.Template); t.Tree != nil {
+				return t.Tree.Root.String()
+			}
+			return ""
+		},
+	},
+	reflect.TypeOf((*htmltemplate.Template)(nil)): {
+		parse: func(text string) (any, error) { return htmltemplate.New("").Parse(text) },
+		format: func(v any) string {
+			if t := v.(*htmltemplate.Template); t.Tree != nil {
+				return t.Tree.Root.String()
+			}
+			return ""
+		},
+	},
+}
+
+// isTextType reports whether t is configured through textTypes.
+func isTextType(t reflect.Type) bool {
+	_, ok := textTypes[t]
+	return ok
+}
+
+// parseTextType sets field, of a type in textTypes, from text.
+func parseTextType(field reflect.Value, text string) error {
+	v, err := textTypes[field.Type()].parse(text)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(v))
+	return nil
+}
+
+// formatTextType returns the text of v, a non-nil value of a type in
+// textTypes.
+func formatTextType(v reflect.Value) string {
+	return textTypes[v.Type()].format(v.Interface())
+}
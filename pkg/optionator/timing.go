@@ -0,0 +1,30 @@
+package optionator
+
+import (
+	"context"
+	"time"
+)
+
+// Timing is how long one source load or option application took.
+type Timing struct {
+	// Phase is "source" or "option".
+	Phase string
+	// Name is the source's name, or "option <index>" for options.
+	Name     string
+	Duration time.Duration
+}
+
+// timeSince records on the report carried by ctx, if any, how long a
+// phase has taken since start, warning when it exceeds the configured
+// SlowThreshold.
+func timeSince(ctx context.Context, config Config, phase, name string, start time.Time) {
+	report, ok := ctx.Value(reportKey{}).(*Report)
+	if !ok {
+		return
+	}
+	d := time.Since(start)
+	report.Timings = append(report.Timings, Timing{Phase: phase, Name: name, Duration: d})
+	if config.SlowThreshold > 0 && d > config.SlowThreshold {
+		report.add("", SeverityWarning, "slow %s: %s took %v, over the %v threshold", phase, name, d, config.SlowThreshold)
+	}
+}
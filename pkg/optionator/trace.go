@@ -0,0 +1,30 @@
+package optionator
+
+// Tracer receives a span for each phase of a construction. It mirrors the
+// shape of an OpenTelemetry tracer so that a small bridge can forward spans
+// to one. Spans started while another is open belong to that span: every
+// construction opens an "optionator.New" span first, and the "defaults",
+// "option" and "validate" phases are started and ended inside it.
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Span is a phase of a construction started by a Tracer.
+type Span interface {
+	SetAttribute(key string, value any)
+	// End finishes the span, recording err if the phase failed.
+	End(err error)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+func (noopSpan) End(error)                {}
+
+// startSpan starts a span on the configured tracer, if any.
+func startSpan(config Config, name string) Span {
+	if config.Tracer == nil {
+		return noopSpan{}
+	}
+	return config.Tracer.Start(name)
+}
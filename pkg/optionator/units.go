@@ -0,0 +1,101 @@
+package optionator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is a frequency in events per second, parsed from text such as
+// "100/s", "5k/min" or "2/h". The count may carry a k (thousand) or M
+// (million) suffix; the units are ms, s, min and h. A bare number is per
+// second.
+type Rate float64
+
+// PerSecond returns the rate as events per second.
+func (r Rate) PerSecond() float64 { return float64(r) }
+
+// Every returns the interval between events, or 0 for a zero rate.
+func (r Rate) Every() time.Duration {
+	if r <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(r))
+}
+
+func (r Rate) String() string {
+	return strconv.FormatFloat(float64(r), 'g', -1, 64) + "/s"
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	count, unit, hasUnit := strings.Cut(s, "/")
+	n, err := parseCount(strings.TrimSpace(count))
+	if err != nil {
+		return fmt.Errorf("invalid rate %q: %w", s, err)
+	}
+	per := time.Second
+	if hasUnit {
+		switch strings.TrimSpace(unit) {
+		case "ms":
+			per = time.Millisecond
+		case "s", "sec":
+			per = time.Second
+		case "m", "min":
+			per = time.Minute
+		case "h", "hour":
+			per = time.Hour
+		default:
+			return fmt.Errorf("invalid rate %q: unknown unit %q", s, unit)
+		}
+	}
+	*r = Rate(n * float64(time.Second) / float64(per))
+	return nil
+}
+
+func (r Rate) MarshalText() ([]byte, error) { return []byte(r.String()), nil }
+
+// parseCount parses a number with an optional k or M suffix.
+func parseCount(s string) (float64, error) {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n * mult, err
+}
+
+// Percent is a fraction parsed from text such as "75%", held as 0.75. A
+// bare number is taken as the fraction itself.
+type Percent float64
+
+// Fraction returns the percentage as a fraction, 0.75 for 75%.
+func (p Percent) Fraction() float64 { return float64(p) }
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p)*100, 'g', -1, 64) + "%"
+}
+
+func (p *Percent) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if n := strings.TrimSuffix(s, "%"); n != s {
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return fmt.Errorf("invalid percentage %q: %w", s, err)
+		}
+		*p = Percent(f / 100)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q: %w", s, err)
+	}
+	*p = Percent(f)
+	return nil
+}
+
+func (p Percent) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
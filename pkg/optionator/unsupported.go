@@ -0,0 +1,83 @@
+package optionator
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Verify checks the fields of T, a struct or a pointer to one, without
+// constructing it, and reports as warnings those optionator cannot
+// configure: channels, unsafe pointers, func fields without a registered
+// default and sync primitives. Such fields should be tagged optionator:"-",
+// which leaves them out of defaults, sources, validation and reports.
+// Nested structs are checked when declared in the same package as T; those
+// of other packages, such as tls.Config, cannot be tagged.
+//
+// Verify also runs each field's default through the field's own tag
+// checks and reports contradictions, such as default:"0" with min:"1", as
+// errors: they only surface once something relies on the default.
+func Verify[T any]() (Report, error) {
+	return VerifyWithConfig[T](defaultConfig)
+}
+
+// VerifyWithConfig is like Verify but reads tags according to config.
+func VerifyWithConfig[T any](config Config) (Report, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return Report{}, errors.New("type must be a struct or a pointer to a struct")
+	}
+	var report Report
+	checkSupported(&report, t, config, "", map[reflect.Type]bool{})
+	checkDefaults(&report, t, config)
+	return report, nil
+}
+
+// checkSupported adds a warning to report for every field of struct t, and
+// of its nested structs from the same package, that is not configurable.
+func checkSupported(report *Report, t reflect.Type, config Config, prefix string, visiting map[reflect.Type]bool) {
+	visiting[t] = true
+	defer delete(visiting, t)
+	for _, fm := range getTypeMetadata(t, config) {
+		path := prefix + fm.Name
+		ft := fm.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.PkgPath() == "sync" || ft.PkgPath() == "sync/atomic":
+			report.add(path, SeverityWarning, "%v holds synchronization state, not configuration; tag it optionator:\"-\"", fm.Type)
+		case ft.Kind() == reflect.Chan || ft.Kind() == reflect.UnsafePointer:
+			report.add(path, SeverityWarning, "%v fields cannot be configured; tag it optionator:\"-\"", ft.Kind())
+		case ft.Kind() == reflect.Func && fm.DefaultTag == "":
+			report.add(path, SeverityWarning, "func field has no registered default; tag it optionator:\"-\" if options never set it")
+		case ft.Kind() == reflect.Struct && ft.PkgPath() == t.PkgPath() && !visiting[ft]:
+			checkSupported(report, ft, config, path+".", visiting)
+		}
+	}
+}
+
+// checkDefaults adds an error to report for every default of struct t, or
+// of its nested structs, that does not parse or fails its field's tag
+// checks. Derived defaults depend on other fields and are skipped.
+func checkDefaults(report *Report, t reflect.Type, config Config) {
+	for _, fi := range describeType(t, config) {
+		if fi.Default == "" || isDerived(fi.Default) {
+			continue
+		}
+		def := reflect.New(fi.Type).Elem()
+		tag, err := config.resolveDefault(fi.Default, fi.Type)
+		if err == nil {
+			err = parseAndSetDefault(def, tag, fi.Type)
+		}
+		if err != nil {
+			report.add(fi.Path, SeverityError, "default %q does not parse: %v", fi.Default, err)
+			continue
+		}
+		if err := checkTags(def, fi.tag, config); err != nil {
+			report.add(fi.Path, SeverityError, "default %q fails its own validation: %v", fi.Default, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package optionator
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// checkURL validates a string field tagged url:"<scheme>,...". The value must
+// parse as a URL whose scheme is one of those listed; an empty list allows
+// any scheme. Two options may appear in the list: absolute requires a scheme
+// and host, and nouserinfo rejects credentials embedded in the URL.
+func checkURL(field reflect.Value, arg string) error {
+	if field.Kind() != reflect.String {
+		return fmt.Errorf("url tag on non-string type %v", field.Type())
+	}
+	u, err := url.Parse(field.String())
+	if err != nil {
+		return err
+	}
+	var schemes []string
+	absolute, noUserinfo := false, false
+	for _, opt := range strings.Split(arg, ",") {
+		switch opt {
+		case "":
+		case "absolute":
+			absolute = true
+		case "nouserinfo":
+			noUserinfo = true
+		default:
+			schemes = append(schemes, opt)
+		}
+	}
+	if absolute && (!u.IsAbs() || u.Host == "") {
+		return fmt.Errorf("url %s is not absolute", u.Redacted())
+	}
+	if noUserinfo && u.User != nil {
+		return fmt.Errorf("url %s must not contain userinfo", u.Redacted())
+	}
+	if len(schemes) == 0 {
+		return nil
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("url scheme %q not allowed, want one of %s", u.Scheme, strings.Join(schemes, ", "))
+}
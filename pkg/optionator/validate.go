@@ -4,31 +4,155 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 )
 
-// validateRequiredFields checks if required fields are non-zero.
-func validateRequiredFields(v reflect.Value, config Config) error {
+var errNilValidation = errors.New("nil pointer encountered in validation")
+
+// validateRequiredFields collects every required and tag failure of v.
+func validateRequiredFields(v reflect.Value, config Config) ErrorGroup {
+	var group ErrorGroup
+	validateFields(v, config, "", func(err *FieldError) bool {
+		group = append(group, err)
+		return true
+	})
+	return group
+}
+
+// validateFields checks the required and tag constraints of v and the
+// structs nested in it, whose paths start with prefix, passing each failure
+// to found until it returns false. It reports whether it went through every
+// field.
+func validateFields(v reflect.Value, config Config, prefix string, found func(*FieldError) bool) bool {
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
-			return errors.New("nil pointer encountered in validation")
+			return found(&FieldError{Path: strings.TrimSuffix(prefix, "."), Code: "nil", Message: errNilValidation.Error(), err: errNilValidation})
 		}
-		return validateRequiredFields(v.Elem(), config)
+		return validateFields(v.Elem(), config, prefix, found)
 	}
 	if v.Kind() != reflect.Struct {
-		return nil
+		return true
 	}
 	t := v.Type()
 	metadata := getTypeMetadata(t, config)
 	for _, fm := range metadata {
 		field := v.FieldByIndex(fm.Index)
+		path := prefix + fm.Name
 		// For nested structs, validate recursively.
-		if field.Kind() == reflect.Struct || (field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct) {
-			if err := validateRequiredFields(field, config); err != nil {
-				return err
+		if isNestedStruct(field.Type()) {
+			if !validateFields(field, config, path+".", found) {
+				return false
+			}
+		}
+		if isKindField(fm) && !field.IsNil() {
+			if !validateFields(field.Elem(), config, path+".", found) {
+				return false
 			}
 		}
 		if fm.Required && isZeroValue(field) {
-			return fmt.Errorf("required field %s is zero", fm.Name)
+			if !found(fieldError(path, "required", fmt.Errorf("required field %s is zero", fm.Name))) {
+				return false
+			}
+			continue
+		}
+		if fm.NonEmpty && (field.Kind() == reflect.Slice || field.Kind() == reflect.Map) && field.Len() == 0 {
+			if !found(fieldError(path, "nonempty", fmt.Errorf("required field %s is empty", fm.Name))) {
+				return false
+			}
+			continue
+		}
+		if !fm.Checked {
+			continue
+		}
+		if err := checkTags(field, fm.Tag, config); err != nil {
+			code := "invalid"
+			var te *tagError
+			if errors.As(err, &te) {
+				code = te.tag
+			}
+			if !found(fieldError(path, code, fmt.Errorf("invalid field %s: %w", fm.Name, err))) {
+				return false
+			}
+			continue
+		}
+		if expr, ok := fm.Tag.Lookup("cel"); ok {
+			if err := checkCEL(field, v, expr, config); err != nil {
+				if !found(fieldError(path, "cel", fmt.Errorf("invalid field %s: %w", fm.Name, err))) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// tagCheck validates a field value against the argument of its tag. Unless
+// zero is set, the check is skipped for zero values, which mean "unset".
+// With clock set, arguments such as "now" or "now+24h" are first resolved
+// against Config.Clock.
+type tagCheck struct {
+	tag   string
+	check func(field reflect.Value, arg string) error
+	zero  bool
+	clock bool
+}
+
+// tagChecks run, in order, on every field carrying their tag.
+var tagChecks = []tagCheck{
+	{"addr", checkAddr, false, false},
+	{"url", checkURL, false, false},
+	{"format", checkFormat, false, false},
+	{"oneof", checkOneOf, false, false},
+	{"kind", checkKind, false, false},
+	{"min", checkMin, true, false},
+	{"max", checkMax, true, false},
+	{"before", checkBefore, false, true},
+	{"after", checkAfter, false, true},
+}
+
+// hasCheckTags reports whether tag carries any tag checked by
+// validateRequiredFields.
+func hasCheckTags(tag reflect.StructTag) bool {
+	if _, ok := tag.Lookup("cel"); ok {
+		return true
+	}
+	for _, tc := range tagChecks {
+		if _, ok := tag.Lookup(tc.tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTags runs the tag checks that apply to field, or to each element
+// of an array field, with the clock of config.
+func checkTags(field reflect.Value, tag reflect.StructTag, config Config) error {
+	if field.Kind() == reflect.Array {
+		for i := 0; i < field.Len(); i++ {
+			if err := checkTags(field.Index(i), tag, config); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	zero := isZeroValue(field)
+	for _, tc := range tagChecks {
+		if zero && !tc.zero {
+			continue
+		}
+		if arg, ok := tag.Lookup(tc.tag); ok {
+			if tc.clock {
+				if now, ok, err := config.relativeTime(arg, "now"); ok {
+					if err != nil {
+						return fmt.Errorf("invalid time bound %q: %w", arg, err)
+					}
+					arg = now.Format(time.RFC3339Nano)
+				}
+			}
+			if err := tc.check(field, arg); err != nil {
+				return &tagError{tc.tag, err}
+			}
 		}
 	}
 	return nil
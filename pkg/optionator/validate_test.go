@@ -0,0 +1,671 @@
+package optionator
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddrValidation(t *testing.T) {
+	type Listener struct {
+		Listen string `addr:"hostport"`
+		Dial   string `addr:"tcpaddr"`
+		Socket string `addr:"unixsocket"`
+	}
+	tests := []struct {
+		name string
+		in   Listener
+		err  string
+	}{
+		{"valid", Listener{Listen: ":8080", Dial: "127.0.0.1:53", Socket: "/tmp/app.sock"}, ""},
+		{"any port", Listener{Listen: "127.0.0.1:0"}, ""},
+		{"dial any port", Listener{Dial: "127.0.0.1:0"}, "out of range 1-65535"},
+		{"missing port", Listener{Listen: "localhost"}, "invalid field Listen"},
+		{"port range", Listener{Listen: "localhost:70000"}, "out of range"},
+		{"bad port", Listener{Dial: "127.0.0.1:http2x"}, "invalid field Dial"},
+		{"socket dir", Listener{Socket: "/nonexistent/dir/app.sock"}, "socket directory"},
+		{"socket length", Listener{Socket: "/tmp/" + strings.Repeat("s", 120)}, "longer than"},
+	}
+	for _, tt := range tests {
+		in := tt.in
+		_, err := New(&in)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestURLValidation(t *testing.T) {
+	type Upstream struct {
+		Endpoint string `url:"https,grpc,absolute,nouserinfo"`
+	}
+	for in, want := range map[string]string{
+		"https://api.example.com":      "",
+		"grpc://10.0.0.1:443":          "",
+		"http://api.example.com":       `scheme "http" not allowed`,
+		"/relative/path":               "not absolute",
+		"https://user:pw@example.com/": "userinfo",
+	} {
+		_, err := New(&Upstream{Endpoint: in})
+		if want == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", in, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("%s: got error %v, want %q", in, err, want)
+		}
+	}
+}
+
+func TestFormatValidation(t *testing.T) {
+	type Release struct {
+		Owner   string `format:"email"`
+		Host    string `format:"hostname"`
+		Version string `format:"semver"`
+		Region  string `format:"region"`
+	}
+	RegisterFormat("region", func(s string) error {
+		if !strings.HasPrefix(s, "eu-") {
+			return errors.New("not an EU region")
+		}
+		return nil
+	})
+	if _, err := New(&Release{Owner: "ops@example.com", Host: "db-1.internal", Version: "v1.2.3-rc.1", Region: "eu-west"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []Release{
+		{Owner: "Ops <ops@example.com>"},
+		{Host: "-bad.example.com"},
+		{Version: "1.2"},
+		{Region: "us-east"},
+	} {
+		in := in
+		if _, err := New(&in); err == nil {
+			t.Errorf("%+v: expected an error", in)
+		}
+	}
+}
+
+func TestBoundsValidation(t *testing.T) {
+	type Window struct {
+		Timeout time.Duration `default:"5s" min:"(0s" max:"10m"`
+		Workers int           `default:"4" min:"1" max:"64)"`
+		Ratio   float64       `max:"1"`
+		Start   time.Time     `after:"2020-01-01" before:"2100-01-01T00:00:00Z"`
+	}
+	if _, err := New(&Window{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		opt  Option[*Window]
+		want string
+	}{
+		{With[*Window]("Timeout", time.Duration(0)), "below the minimum (0s"},
+		{With[*Window]("Timeout", time.Hour), "above the maximum 10m"},
+		{With[*Window]("Workers", 64), "above the maximum 64)"},
+		{With[*Window]("Ratio", 1.5), "above the maximum 1"},
+		{With[*Window]("Start", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)), "not after 2020-01-01"},
+	} {
+		_, err := New(&Window{}, tt.opt)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got error %v, want %q", err, tt.want)
+		}
+	}
+}
+
+// Decimal is zero when its units are, whatever its precision.
+type Decimal struct {
+	Units     int64
+	Precision int
+}
+
+func (d Decimal) IsZero() bool { return d.Units == 0 }
+
+func TestIsZeroer(t *testing.T) {
+	type Price struct {
+		Amount Decimal `required:"true"`
+	}
+	if _, err := New(&Price{Amount: Decimal{Precision: 2}}); err == nil {
+		t.Error("expected a zero Decimal to fail required validation")
+	}
+	if _, err := New(&Price{Amount: Decimal{Units: 150, Precision: 2}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewWithReport(t *testing.T) {
+	type Admin struct {
+		Password string `default:"changeme" insecure:"true"`
+		Legacy   bool   `deprecated:"use Mode instead"`
+		Mode     string `default:"strict" required:"true"`
+	}
+	_, report, err := NewWithReport(&Admin{}, defaultConfig, With[*Admin]("Legacy", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range report.Warnings() {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"warning: Password: value is the insecure default",
+		"warning: Legacy: deprecated field is set; use Mode instead",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	_, report, err = NewWithReport(&Admin{}, defaultConfig, With[*Admin]("Mode", ""))
+	if err == nil || len(report.Errors()) != 1 {
+		t.Errorf("got error %v and errors %v", err, report.Errors())
+	}
+}
+
+func TestExperimental(t *testing.T) {
+	type Cache struct {
+		Size int `default:"64"`
+	}
+	type Service struct {
+		Workers int   `default:"4" stability:"experimental"`
+		Cache   Cache `stability:"experimental"`
+	}
+	if _, err := New(&Service{}); err != nil {
+		t.Errorf("defaults of experimental fields must be accepted: %v", err)
+	}
+	_, err := New(&Service{}, func(s *Service) error { s.Cache.Size = 128; return nil })
+	if err == nil || !strings.Contains(err.Error(), "experimental field Cache.Size") {
+		t.Errorf("expected experimental error, got %v", err)
+	}
+	s, err := NewWithConfig(&Service{}, Config{DefaultTag: "default", RequiredTag: "required", AllowExperimental: true}, With[*Service]("Workers", 8))
+	if err != nil || s.Workers != 8 {
+		t.Errorf("AllowExperimental: got %+v, %v", s, err)
+	}
+	config := defaultConfig
+	config.Strictness = StrictnessWarn
+	_, report, err := NewWithReport(&Service{}, config, With[*Service]("Workers", 8))
+	if err != nil || len(report.Warnings()) != 1 {
+		t.Errorf("StrictnessWarn: got %v, %v", report.Findings, err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	RegisterRule(Rule{
+		Name:     "tls-min-version",
+		Field:    "TLSMinVersion",
+		Severity: SeverityError,
+		Check: func(value any, config Config) error {
+			if value.(string) < "1.2" {
+				return errors.New("must be at least 1.2")
+			}
+			return nil
+		},
+	})
+	RegisterRule(Rule{
+		Name:     "no-debug-in-prod",
+		Field:    "Debug",
+		Severity: SeverityWarning,
+		Check: func(value any, config Config) error {
+			if config.Profile == "prod" && value.(bool) {
+				return errors.New("debug is on")
+			}
+			return nil
+		},
+	})
+	defer UnregisterRule("tls-min-version")
+	defer UnregisterRule("no-debug-in-prod")
+
+	type Frontend struct {
+		TLSMinVersion string `default:"1.2"`
+		Debug         bool
+	}
+	config := defaultConfig
+	config.Profile = "prod"
+	_, report, err := NewWithReport(&Frontend{Debug: true}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := report.Warnings(); len(w) != 1 || w[0].String() != "warning: Debug: no-debug-in-prod: debug is on" {
+		t.Errorf("warnings = %v", w)
+	}
+	_, err = New(&Frontend{TLSMinVersion: "1.0"})
+	if err == nil || !strings.Contains(err.Error(), "policy violation: TLSMinVersion: tls-min-version") {
+		t.Errorf("got %v", err)
+	}
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "TLSMinVersion" || fe.Code != "policy" {
+		t.Errorf("Expected a policy FieldError, got %#v", err)
+	}
+}
+
+func TestWizard(t *testing.T) {
+	type Init struct {
+		Name  string `required:"true" desc:"service name"`
+		Level string `default:"info" oneof:"debug,info,warn"`
+		Port  int    `min:"1"`
+		Mode  string `oneof:"dev,prod"`
+	}
+	in := strings.NewReader("\nbilling\nabc\n9000\nstaging\nprod\n")
+	var out strings.Builder
+	cfg, err := Wizard(in, &out, &Init{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Init{Name: "billing", Level: "info", Port: 9000, Mode: "prod"}
+	if *cfg != want {
+		t.Errorf("got %+v", *cfg)
+	}
+	for _, s := range []string{"Name (service name): a value is required", "invalid int", `"staging" is not one of dev, prod`, "Mode {dev|prod}: "} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("output lacks %q:\n%s", s, out.String())
+		}
+	}
+	if _, err := Wizard(strings.NewReader(""), io.Discard, &Init{}); err == nil {
+		t.Error("expected an error at end of input")
+	}
+}
+
+func TestNilVersusEmpty(t *testing.T) {
+	type Cluster struct {
+		Hosts  []string          `default:"a,b"`
+		Peers  []string          `required:"nonnil"`
+		Zones  []string          `required:"nonempty"`
+		Labels map[string]int    `default:"x=1, y=2"`
+		Extra  map[string]string `default:"{}"`
+	}
+	cfg, err := New(&Cluster{Hosts: []string{}, Peers: []string{}, Zones: []string{"z1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Hosts == nil || len(cfg.Hosts) != 0 {
+		t.Errorf("explicitly empty Hosts = %#v", cfg.Hosts)
+	}
+	if cfg.Labels["y"] != 2 || cfg.Extra == nil {
+		t.Errorf("Labels = %v, Extra = %#v", cfg.Labels, cfg.Extra)
+	}
+	cfg, _ = New(&Cluster{Peers: []string{}, Zones: []string{"z1"}})
+	if len(cfg.Hosts) != 2 {
+		t.Errorf("nil Hosts = %v, want the default", cfg.Hosts)
+	}
+	if _, err := New(&Cluster{Zones: []string{"z1"}}); err == nil || !strings.Contains(err.Error(), "Peers is zero") {
+		t.Errorf("nil Peers: got %v", err)
+	}
+	if _, err := New(&Cluster{Peers: []string{}, Zones: []string{}}); err == nil || !strings.Contains(err.Error(), "Zones is empty") {
+		t.Errorf("empty Zones: got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	type Inner struct {
+		Events chan string
+	}
+	type Config struct {
+		mu      sync.Mutex
+		Lock    sync.RWMutex
+		Hook    func()
+		Skipped chan int `optionator:"-"`
+		Inner   Inner
+		TLS     *tls.Config
+		Name    string `default:"x"`
+	}
+	report, err := Verify[*Config]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range report.Warnings() {
+		got = append(got, f.Path)
+	}
+	if want := []string{"Lock", "Hook", "Inner.Events"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warned about %v, want %v", got, want)
+	}
+	if !strings.Contains(report.Findings[0].Message, `tag it optionator:"-"`) {
+		t.Errorf("message = %q", report.Findings[0].Message)
+	}
+
+	cfg, report, err := NewWithReport(&Config{}, defaultConfig)
+	if err != nil || cfg.Name != "x" || len(report.Warnings()) != 3 {
+		t.Errorf("NewWithReport: %v, %v", report.Findings, err)
+	}
+}
+
+func TestVerifyDefaults(t *testing.T) {
+	type Limits struct {
+		Burst int `default:"500" max:"100"`
+	}
+	type Config struct {
+		Workers int    `default:"0" min:"1"`
+		Mode    string `default:"turbo" oneof:"fast,safe"`
+		Retries int    `default:"3" min:"1"`
+		Wait    int    `default:"soon"`
+		Limits  Limits
+	}
+	report, err := Verify[*Config]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range report.Errors() {
+		got = append(got, f.Path)
+	}
+	if want := []string{"Workers", "Mode", "Wait", "Limits.Burst"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reported defaults %v, want %v", got, want)
+	}
+}
+
+func TestCompareSchemas(t *testing.T) {
+	type V1 struct {
+		Addr    string `default:"localhost"`
+		Port    int    `default:"80"`
+		Timeout int
+		Debug   bool
+	}
+	type V2 struct {
+		Address string        `default:"localhost" alias:"Addr"`
+		Port    int           `default:"8080"`
+		Timeout time.Duration `required:"true"`
+		Region  string        `required:"true"`
+		Verbose bool
+	}
+	old, _ := Describe[V1]()
+	cur, _ := Describe[V2]()
+	var got []string
+	for _, c := range CompareSchemas(old, cur) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"Address: field renamed (Addr -> Address)",
+		"Debug: field removed [breaking]",
+		"Port: default changed (80 -> 8080)",
+		"Region: new required field [breaking]",
+		"Timeout: type changed (int -> time.Duration) [breaking]",
+		"Timeout: new required field [breaking]",
+		"Verbose: field added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestCEL(t *testing.T) {
+	type Pool struct {
+		MaxConns  int           `default:"100"`
+		IdleConns int           `default:"10" cel:"self > 0 && self < this.MaxConns"`
+		Timeout   time.Duration `default:"5s" cel:"self <= duration(\"1m\")"`
+		Hosts     []string      `cel:"size(self) % 2 == 0"`
+		Name      string        `default:"pool" cel:"size(self) <= 8 && self != \"default\""`
+	}
+	if _, err := New(&Pool{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		opt  Option[*Pool]
+		want string
+	}{
+		{With[*Pool]("IdleConns", 100), "invalid field IdleConns: 100 does not satisfy self > 0 && self < this.MaxConns"},
+		{With[*Pool]("Timeout", 2*time.Minute), "invalid field Timeout"},
+		{With[*Pool]("Hosts", []string{"a"}), "invalid field Hosts"},
+		{With[*Pool]("Name", "default"), "invalid field Name"},
+	} {
+		if _, err := New(&Pool{}, tc.opt); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("got %v, want %q", err, tc.want)
+		}
+	}
+
+	type Bad struct {
+		N int `cel:"self + \"x\" > 0"`
+	}
+	if _, err := New(&Bad{}); err == nil || !strings.Contains(err.Error(), "no such overload") {
+		t.Errorf("expected overload error, got %v", err)
+	}
+
+	type Service struct {
+		Mode    string   `default:"fast" cel:"self in ['fast', 'safe']"`
+		Workers int      `default:"4" cel:"this.Mode == 'safe' ? self == 1 : self > 1"`
+		Zones   []string `default:"a,b" cel:"self.size() >= 2 && 'a' in self && self[0].startsWith('a')"`
+		Scale   int64    `default:"1" cel:"self * 9223372036854775807 > 0"`
+	}
+	if _, err := New(&Service{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		svc  *Service
+		opt  Option[*Service]
+		want string
+	}{
+		{&Service{}, With[*Service]("Mode", "slow"), "invalid field Mode"},
+		{&Service{}, With[*Service]("Mode", "safe"), "invalid field Workers"},
+		{&Service{}, With[*Service]("Zones", []string{"b", "a"}), "invalid field Zones"},
+		{&Service{}, With[*Service]("Scale", int64(2)), "integer overflow"},
+	} {
+		if _, err := New(tc.svc, tc.opt); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("got %v, want %q", err, tc.want)
+		}
+	}
+
+	type Private struct {
+		Count uint64 `cel:"self >= 0"`
+		Name  string `default:"x" cel:"this.secret == 'x'"`
+
+		secret string
+	}
+	if _, err := New(&Private{}); err == nil || !strings.Contains(err.Error(), "no such field secret") {
+		t.Errorf("expected unexported field error, got %v", err)
+	}
+	if _, err := New(&Private{Count: 1 << 63}); err == nil || !strings.Contains(err.Error(), "overflows int") {
+		t.Errorf("expected uint overflow error, got %v", err)
+	}
+	if _, err := parseCEL(`self.foo(`); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestValidationCache(t *testing.T) {
+	SetValidationCacheSize(2)
+	defer SetValidationCacheSize(0)
+	calls := 0
+	RegisterFormat("cached-limit", func(s string) error {
+		calls++
+		if len(s) > 3 {
+			return errors.New("too long")
+		}
+		return nil
+	})
+	type Limits struct {
+		Code string `default:"abc" format:"cached-limit"`
+	}
+	build := func(code string) error {
+		_, err := New(&Limits{}, With[*Limits]("Code", code))
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := build("abc"); err != nil {
+			t.Fatal(err)
+		}
+		if err := build("abcdef"); err == nil || !strings.Contains(err.Error(), "too long") {
+			t.Fatalf("expected cached failure, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("format ran %d times, want 2", calls)
+	}
+	var group ErrorGroup
+	if err := build("abcdef"); !errors.As(err, &group) {
+		t.Fatalf("expected an ErrorGroup, got %v", err)
+	}
+	group[0].Message = "changed by the caller"
+	if err := build("abcdef"); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("cached errors must not be shared with callers, got %v", err)
+	}
+	build("ab") // evicts the outcome for abc
+	build("abc")
+	if calls != 4 {
+		t.Errorf("format ran %d times after eviction, want 4", calls)
+	}
+
+	// Rules see the Config, so they run on every construction.
+	SetValidationCacheSize(10)
+	RegisterRule(Rule{
+		Name:     "cached-prod",
+		Field:    "Debug",
+		Severity: SeverityError,
+		Check: func(value any, config Config) error {
+			if config.Profile == "prod" && value.(bool) {
+				return errors.New("debug is on")
+			}
+			return nil
+		},
+	})
+	defer UnregisterRule("cached-prod")
+	type Frontend struct {
+		Debug bool
+	}
+	prod := defaultConfig
+	prod.Profile = "prod"
+	if _, err := New(&Frontend{Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWithConfig(&Frontend{Debug: true}, prod); err == nil {
+		t.Error("expected policy violation under the prod profile")
+	}
+
+	// Values differing only in a time must not share an outcome.
+	type Window struct {
+		Start time.Time `before:"2030-01-01"`
+	}
+	if _, err := New(&Window{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(&Window{Start: time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)}); err == nil {
+		t.Error("expected 2040 to fail the before bound")
+	}
+
+	// Bounds relative to now depend on the clock.
+	type Expiry struct {
+		At time.Time `after:"now"`
+	}
+	at := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	early, late := defaultConfig, defaultConfig
+	early.Clock = func() time.Time { return at.Add(-time.Hour) }
+	late.Clock = func() time.Time { return at.Add(time.Hour) }
+	if _, err := NewWithConfig(&Expiry{At: at}, early); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWithConfig(&Expiry{At: at}, late); err == nil {
+		t.Error("expected the later clock to fail the after bound")
+	}
+
+	// cel checks are turned off by DisableDynamic, not cached past it.
+	type Pool struct {
+		Size int `default:"5" cel:"self > 0"`
+	}
+	if _, err := New(&Pool{}); err != nil {
+		t.Fatal(err)
+	}
+	static := defaultConfig
+	static.DisableDynamic = true
+	if _, err := NewWithConfig(&Pool{}, static); !errors.Is(err, errDynamicDisabled) {
+		t.Errorf("expected dynamic evaluation error, got %v", err)
+	}
+}
+
+func TestNewLenient(t *testing.T) {
+	type Limits struct {
+		Burst int `default:"10" min:"1"`
+	}
+	type Service struct {
+		Name    string `required:"true"`
+		Port    int    `default:"8080" max:"9000"`
+		Timeout time.Duration
+		Limits  Limits
+	}
+	config := defaultConfig
+	config.Sources = []Source{MapSource{Values: map[string]any{
+		"Port":    9500,
+		"Timeout": "soon",
+		"Limits":  map[string]any{"Burst": 0},
+		"Extra":   true,
+	}}}
+	fail := func(*Service) error { return errors.New("option failed") }
+	s, findings := NewLenient(&Service{}, config, fail, With[*Service]("Limits.Burst", 5))
+	if s.Port != 9500 || s.Timeout != 0 {
+		t.Errorf("expected best-effort values, got %+v", s)
+	}
+	var errs, warnings []string
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, f.Message)
+		} else {
+			warnings = append(warnings, f.Message)
+		}
+	}
+	for _, want := range []string{
+		`field Timeout: time: invalid duration "soon"`,
+		"option failed",
+		"no such field: Limits.Burst",
+		"required field Name is zero",
+		"invalid field Port",
+		"invalid field Burst",
+	} {
+		found := false
+		for _, e := range errs {
+			found = found || strings.Contains(e, want)
+		}
+		if !found {
+			t.Errorf("missing error %q in %q", want, errs)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "unknown key Extra") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestErrorGroup(t *testing.T) {
+	type DB struct {
+		Host string `required:"true"`
+		Pool int    `min:"1"`
+	}
+	type App struct {
+		Name string `oneof:"api,worker"`
+		DB   DB
+	}
+	err := Validate(&App{Name: "cron"})
+	var group ErrorGroup
+	if !errors.As(err, &group) || len(group) != 3 {
+		t.Fatalf("Expected an ErrorGroup of 3 errors, got %v", err)
+	}
+	var got []string
+	for _, e := range group.Unwrap() {
+		var fe *FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("Expected FieldErrors, got %T", e)
+		}
+		got = append(got, fe.Path+" "+fe.Code)
+	}
+	if want := []string{"Name oneof", "DB.Host required", "DB.Pool min"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got errors %v, want %v", got, want)
+	}
+	data, err := json.Marshal(group)
+	if err != nil || !strings.HasPrefix(string(data), `[{"path":"Name","code":"oneof","message":"invalid field Name: `) {
+		t.Errorf("Unexpected JSON %s, %v", data, err)
+	}
+	if err := Validate(&App{Name: "api", DB: DB{Host: "db", Pool: 2}}); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+	_, err = New(&App{})
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Path != "DB.Host" || fe.Code != "required" {
+		t.Errorf("Expected construction to fail with a FieldError, got %#v", err)
+	}
+	_, err = New(&App{Name: "cron"})
+	if !errors.As(err, &group) || len(group) != 3 {
+		t.Errorf("Expected construction to report every failure, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package optionator
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// validationCache remembers validation outcomes when enabled with
+// SetValidationCacheSize.
+var validationCache = newValidationLRU()
+
+// validationKey identifies a validated configuration by its type, the
+// Config settings that can change the outcome and its fingerprint.
+type validationKey struct {
+	metadataKey
+	Profile           string
+	DisableDynamic    bool
+	AllowExperimental bool
+	Fingerprint       string
+}
+
+type validationEntry struct {
+	key   validationKey
+	group ErrorGroup
+}
+
+// validationLRU maps keys to outcomes, evicting the least recently used
+// beyond size. It stores nothing while size is zero.
+type validationLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *validationEntry, most recent first
+	entries map[validationKey]*list.Element
+}
+
+func newValidationLRU() *validationLRU {
+	return &validationLRU{order: list.New(), entries: map[validationKey]*list.Element{}}
+}
+
+// SetValidationCacheSize makes constructions remember the outcome of
+// validating up to size distinct configurations, so rebuilding one whose
+// values were already validated, as in bursts of Live reloads, skips the
+// required and tag checks. Zero, the default, disables the cache.
+// Registering formats or kinds clears it. Checks that depend on more than
+// the field values are never cached: types with cel tags, before or after
+// bounds relative to now, or polymorphic sections are always validated, and
+// rules, which see the Config, always run.
+func SetValidationCacheSize(size int) {
+	c := validationCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	for c.order.Len() > size {
+		c.remove(c.order.Back())
+	}
+}
+
+// resetValidationCache forgets every outcome, for when the checks change.
+func resetValidationCache() {
+	c := validationCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = map[validationKey]*list.Element{}
+}
+
+func (c *validationLRU) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size > 0
+}
+
+func (c *validationLRU) get(key validationKey) (group ErrorGroup, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*validationEntry).group, true
+}
+
+func (c *validationLRU) put(key validationKey, group ErrorGroup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*validationEntry).group = group
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&validationEntry{key, group})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *validationLRU) remove(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*validationEntry).key)
+}
+
+// validate runs the required, tag and error rule checks on struct v. The
+// required and tag checks go through the cache when it is enabled.
+func validate(v reflect.Value, config Config) error {
+	group := validateFieldsCached(v, config)
+	for _, f := range evalRules(v, config, SeverityError) {
+		group = append(group, policyError(f))
+	}
+	if len(group) == 0 {
+		return nil
+	}
+	return group
+}
+
+func validateFieldsCached(v reflect.Value, config Config) ErrorGroup {
+	if !validationCache.enabled() || configDependent(v.Type(), config) {
+		return validateRequiredFields(v, config)
+	}
+	key := validationKey{
+		metadataKey: metadataKey{
+			Type:             v.Type(),
+			DefaultTag:       config.DefaultTag,
+			RequiredTag:      config.RequiredTag,
+			TagCompatibility: config.TagCompatibility,
+		},
+		Profile:           config.Profile,
+		DisableDynamic:    config.DisableDynamic,
+		AllowExperimental: config.AllowExperimental,
+		Fingerprint:       canonicalHash(v),
+	}
+	// Callers own the errors they get back, so the cache keeps its own.
+	if group, ok := validationCache.get(key); ok {
+		return group.clone()
+	}
+	group := validateRequiredFields(v, config)
+	validationCache.put(key, group.clone())
+	return group
+}
+
+// dependents memoizes configDependent by metadata key.
+var dependents sync.Map // map[metadataKey]bool
+
+// configDependent reports whether validating struct type t depends on more
+// than its field values: on the clock, through before or after bounds
+// relative to now, on Config.DisableDynamic and EvalLimits, through cel
+// tags, or on the registered kinds, through polymorphic sections.
+func configDependent(t reflect.Type, config Config) bool {
+	key := metadataKey{t, config.DefaultTag, config.RequiredTag, config.TagCompatibility}
+	if dep, ok := dependents.Load(key); ok {
+		return dep.(bool)
+	}
+	dep := typeDependent(t, config, map[reflect.Type]bool{})
+	dependents.Store(key, dep)
+	return dep
+}
+
+func typeDependent(t reflect.Type, config Config, seen map[reflect.Type]bool) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for _, fm := range getTypeMetadata(t, config) {
+		if isKindField(fm) {
+			return true
+		}
+		if _, ok := fm.Tag.Lookup("cel"); ok {
+			return true
+		}
+		for _, tag := range []string{"before", "after"} {
+			if strings.HasPrefix(fm.Tag.Get(tag), "now") {
+				return true
+			}
+		}
+		if isNestedStruct(fm.Type) && typeDependent(fm.Type, config, seen) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package optionator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Values returns the current value of every leaf field of target, a struct or
+// a pointer to one, keyed by field path. Fields behind nil pointers are omitted.
+func Values(target any) (map[string]any, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	for _, fi := range describeType(v.Type(), defaultConfig) {
+		if field, ok := lookupIndexes(v, fi.indexes); ok {
+			values[fi.Path] = field.Interface()
+		}
+	}
+	return values, nil
+}
+
+// FieldChange is a field whose value differs from its default.
+type FieldChange struct {
+	Path string
+	// Value and Default are the current and default values; both are
+	// Redacted, or masked as their secret tag asks, for secret fields.
+	Value   any
+	Default any
+}
+
+// NonDefaultFields returns the leaf fields of target, a struct or a pointer
+// to one, whose values differ from what their default tags give, or from
+// the zero value when they have none, ordered by path. Derived defaults are
+// evaluated against the defaults of the fields they reference.
+func NonDefaultFields[T any](target T) ([]FieldChange, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return nil, err
+	}
+	defaults := reflect.New(v.Type()).Elem()
+	if err := setDefaultRecursively(withDerivedLater(context.Background()), defaults, defaultConfig); err != nil {
+		return nil, err
+	}
+	// Derived defaults follow the fields they reference, so they are
+	// expanded from the target's own values.
+	derived := reflect.New(v.Type()).Elem()
+	copyValue(derived, v, copies{})
+	clearDerived(derived, defaultConfig)
+	if err := setDerivedRecursively(derived, defaultConfig); err != nil {
+		return nil, err
+	}
+	var changes []FieldChange
+	for _, fi := range describeType(v.Type(), defaultConfig) {
+		field, ok := lookupIndexes(v, fi.indexes)
+		if !ok {
+			continue
+		}
+		def, _ := lookupIndexes(defaults, fi.indexes)
+		if isDerived(fi.Default) {
+			def, _ = lookupIndexes(derived, fi.indexes)
+		}
+		if field.Kind() == reflect.Func && field.Pointer() == def.Pointer() {
+			continue
+		}
+		if field.Kind() != reflect.Func && reflect.DeepEqual(field.Interface(), def.Interface()) {
+			continue
+		}
+		change := FieldChange{Path: fi.Path, Value: field.Interface(), Default: def.Interface()}
+		if fi.Secret {
+			change.Value, change.Default = maskSecret(fi.Mask, plainValue(field)), maskSecret(fi.Mask, plainValue(def))
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Fingerprint returns a stable hex-encoded SHA-256 hash of the values of
+// target. Pointers are hashed by what they point to and funcs and channels by
+// type only, so equal configurations hash equally across processes. Secret
+// fields are left out, since a published fingerprint would otherwise let a
+// low-entropy secret be guessed offline, unless SetSecretHashKey set a key:
+// they are then mixed in as their HMAC under it, so a secret change shows.
+func Fingerprint(target any) (string, error) {
+	v, err := structValue(target)
+	if err != nil {
+		return "", err
+	}
+	key, _ := secretHashKey.Load().([]byte)
+	return hashCanonical(v, &canonicalizer{key: key, omitSecrets: len(key) == 0}), nil
+}
+
+// canonicalHash is the fingerprint of struct v, secrets included as they
+// are, for internal use only.
+func canonicalHash(v reflect.Value) string {
+	return hashCanonical(v, &canonicalizer{})
+}
+
+func hashCanonical(v reflect.Value, c *canonicalizer) string {
+	h := sha256.New()
+	h.Write([]byte(c.canonical(v)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// structValue dereferences target down to a struct value.
+func structValue(target any) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return v, errors.New("target must be a struct or a pointer to a struct")
+	}
+	return v, nil
+}
+
+// canonicalizer renders values deterministically for hashing.
+type canonicalizer struct {
+	// key, if set, replaces the rendering of secret fields by its HMAC.
+	key []byte
+	// omitSecrets leaves secret fields out when there is no key.
+	omitSecrets bool
+	// visiting holds the pointers and maps being rendered, by the depth at
+	// which they were entered, so cycles render as a reference.
+	visiting map[copyKey]int
+}
+
+// enter marks the pointer or map v as being rendered. It returns a
+// reference to v and false if v is already on the way down.
+func (c *canonicalizer) enter(v reflect.Value) (ref string, ok bool) {
+	if c.visiting == nil {
+		c.visiting = map[copyKey]int{}
+	}
+	key := copyKey{v.Pointer(), v.Type()}
+	if depth, ok := c.visiting[key]; ok {
+		return fmt.Sprintf("cycle:%d", depth), false
+	}
+	c.visiting[key] = len(c.visiting)
+	return "", true
+}
+
+func (c *canonicalizer) leave(v reflect.Value) {
+	delete(c.visiting, copyKey{v.Pointer(), v.Type()})
+}
+
+// canonical renders v.
+func (c *canonicalizer) canonical(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "nil"
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		if isTextType(v.Type()) {
+			return fmt.Sprintf("%q", formatTextType(v))
+		}
+		if v.Kind() == reflect.Ptr {
+			ref, ok := c.enter(v)
+			if !ok {
+				return ref
+			}
+			defer c.leave(v)
+		}
+		return c.canonical(v.Elem())
+	case reflect.Struct:
+		if text, ok := leafText(v); ok {
+			return fmt.Sprintf("%q", text)
+		}
+		var b strings.Builder
+		b.WriteString("{")
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			text := c.canonical(v.Field(i))
+			if (c.key != nil || c.omitSecrets) && isSecret(sf.Tag.Get("secret")) {
+				if c.omitSecrets {
+					continue
+				}
+				mac := hmac.New(sha256.New, c.key)
+				mac.Write([]byte(text))
+				text = "hmac:" + hex.EncodeToString(mac.Sum(nil))
+			}
+			fmt.Fprintf(&b, "%s:%s;", sf.Name, text)
+		}
+		b.WriteString("}")
+		return b.String()
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = c.canonical(v.Index(i))
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case reflect.Map:
+		if v.IsNil() {
+			return "map[]"
+		}
+		ref, ok := c.enter(v)
+		if !ok {
+			return ref
+		}
+		defer c.leave(v)
+		parts := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			parts = append(parts, c.canonical(iter.Key())+"="+c.canonical(iter.Value()))
+		}
+		sort.Strings(parts)
+		return "map[" + strings.Join(parts, ",") + "]"
+	case reflect.Func:
+		if name, ok := funcName(v); ok {
+			return v.Type().String() + "=" + name
+		}
+		return v.Type().String()
+	case reflect.Chan, reflect.UnsafePointer:
+		return v.Type().String()
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(v.Interface()))
+}
+
+// leafText returns the text of struct v if it is a single value, such as a
+// time.Time, rather than a set of fields: its MarshalText, or its String
+// when it has no exported fields.
+func leafText(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	if m, ok := p.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).PkgPath == "" {
+			return "", false
+		}
+	}
+	if s, ok := p.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
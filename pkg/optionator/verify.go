@@ -0,0 +1,52 @@
+package optionator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+// Verifier checks a detached signature over a raw configuration payload.
+type Verifier interface {
+	Verify(payload, signature []byte) error
+}
+
+// KeyProvider supplies the public keys trusted to sign configuration.
+type KeyProvider interface {
+	PublicKeys() ([]ed25519.PublicKey, error)
+}
+
+// StaticKeys is a KeyProvider with a fixed set of keys.
+type StaticKeys []ed25519.PublicKey
+
+func (k StaticKeys) PublicKeys() ([]ed25519.PublicKey, error) { return k, nil }
+
+// Ed25519Verifier accepts payloads signed by any key from Keys. Signatures
+// may be raw 64-byte values or base64-encoded, as cosign writes them.
+type Ed25519Verifier struct {
+	Keys KeyProvider
+}
+
+// ErrInvalidSignature is returned when no trusted key verifies a payload.
+var ErrInvalidSignature = errors.New("invalid configuration signature")
+
+func (v Ed25519Verifier) Verify(payload, signature []byte) error {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		signature = decoded
+	}
+	keys, err := v.Keys.PublicKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, payload, signature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
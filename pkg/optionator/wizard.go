@@ -0,0 +1,95 @@
+package optionator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Wizard interactively fills target, a pointer to a struct, for commands
+// such as `myapp init`. It prompts on out for every field that is required
+// or has no default, showing the desc tag, the default and the oneof
+// choices, and reads answers from in, one per line. An empty answer keeps
+// the default. Invalid answers are explained and asked again. The answers
+// are then applied as options to a normal construction, whose result is
+// returned.
+func Wizard[T any](in io.Reader, out io.Writer, target T) (T, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return target, errors.New("target must be a pointer to a struct")
+	}
+	r := bufio.NewReader(in)
+	var opts []Option[T]
+	for _, fi := range describeType(v.Elem().Type(), defaultConfig) {
+		if (!fi.Required && (fi.Default != "" || fi.Hidden)) || !isParsable(fi.Type) {
+			continue
+		}
+		answer, err := ask(r, out, fi)
+		if err != nil {
+			return target, err
+		}
+		if answer != "" {
+			opts = append(opts, WithText[T](fi.Path, answer))
+		}
+	}
+	return New(target, opts...)
+}
+
+// ask prompts for fi until it gets an acceptable answer.
+func ask(r *bufio.Reader, out io.Writer, fi FieldInfo) (string, error) {
+	choices := tagList(fi.tag.Get("oneof"))
+	for {
+		prompt := fi.Path
+		if fi.Description != "" {
+			prompt += " (" + fi.Description + ")"
+		}
+		if len(choices) > 0 {
+			prompt += " {" + strings.Join(choices, "|") + "}"
+		}
+		if fi.Default != "" {
+			prompt += " [" + fi.Default + "]"
+		}
+		fmt.Fprint(out, prompt+": ")
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			if fi.Required && fi.Default == "" {
+				fmt.Fprintln(out, "a value is required")
+				continue
+			}
+			return "", nil
+		}
+		field := reflect.New(fi.Type).Elem()
+		if err := parseAndSetDefault(field, answer, fi.Type); err != nil {
+			fmt.Fprintf(out, "invalid %v: %v\n", fi.Type, err)
+			continue
+		}
+		if err := checkTags(field, fi.tag, defaultConfig); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// checkOneOf validates a field tagged oneof:"a,b,c" against the listed
+// values, compared as text.
+func checkOneOf(field reflect.Value, arg string) error {
+	text := fmt.Sprint(field.Interface())
+	choices := tagList(arg)
+	for _, c := range choices {
+		if c == text {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", text, strings.Join(choices, ", "))
+}
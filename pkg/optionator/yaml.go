@@ -0,0 +1,551 @@
+package optionator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// isYAMLFile reports whether path names a YAML file by its extension.
+func isYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// decodeYAML decodes a stream of YAML documents separated by --- lines, each
+// of which must be a mapping or empty. It reads the subset of YAML that
+// configuration files use: block and flow mappings and sequences, plain,
+// quoted, literal and folded scalars, and comments. Anchors, aliases and
+// tags are rejected. Numbers are kept as json.Number, as by decodeJSON.
+func decodeYAML(data []byte) ([]map[string]any, error) {
+	var docs []map[string]any
+	var lines []yamlLine
+	open := false
+	end := func() error {
+		if !open {
+			lines = nil
+			return nil
+		}
+		v, err := (&yamlParser{lines: lines}).document()
+		if err != nil {
+			return err
+		}
+		switch v := v.(type) {
+		case nil:
+			docs = append(docs, map[string]any{})
+		case map[string]any:
+			docs = append(docs, v)
+		default:
+			return fmt.Errorf("yaml: document %d is not a mapping", len(docs)+1)
+		}
+		lines, open = nil, false
+		return nil
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	for i, line := range strings.Split(text, "\n") {
+		switch {
+		case line == "---" || strings.HasPrefix(line, "--- "):
+			if err := end(); err != nil {
+				return nil, err
+			}
+			if stripYAMLComment(strings.TrimSpace(line[3:])) != "" {
+				return nil, fmt.Errorf("yaml: line %d: content after --- is not supported", i+1)
+			}
+			open = true
+			continue
+		case line == "...":
+			if err := end(); err != nil {
+				return nil, err
+			}
+			continue
+		case !open && strings.HasPrefix(line, "%"):
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(trimmed, "\t") && strings.TrimSpace(trimmed) != "" {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", i+1)
+		}
+		if stripYAMLComment(trimmed) != "" {
+			open = true
+		}
+		lines = append(lines, yamlLine{num: i + 1, text: line})
+	}
+	if err := end(); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+type yamlLine struct {
+	num  int
+	text string
+}
+
+// yamlParser parses the block structure of one document, line by line.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	num := 0
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+	return fmt.Errorf("yaml: line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) document() (any, error) {
+	v, err := p.block(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, ok := p.peek(); ok {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+// peek skips blank and comment lines and returns the indentation and the
+// content, without its comment, of the next line.
+func (p *yamlParser) peek() (int, string, bool) {
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos].text
+		trimmed := strings.TrimLeft(raw, " ")
+		if text := stripYAMLComment(trimmed); text != "" {
+			return len(raw) - len(trimmed), text, true
+		}
+	}
+	return 0, "", false
+}
+
+// block parses the node starting on the next line, if it is indented at
+// least min spaces.
+func (p *yamlParser) block(min int) (any, error) {
+	indent, text, ok := p.peek()
+	if !ok || indent < min {
+		return nil, nil
+	}
+	switch {
+	case isSeqItem(text):
+		return p.sequence(indent)
+	case isYAMLKey(text):
+		return p.mapping(indent)
+	}
+	num := p.lines[p.pos].num
+	p.pos++
+	return p.item(text, indent, false, num)
+}
+
+func (p *yamlParser) mapping(ind int) (map[string]any, error) {
+	m := map[string]any{}
+	for {
+		indent, text, ok := p.peek()
+		if !ok || indent < ind {
+			return m, nil
+		}
+		if indent > ind {
+			return nil, p.errorf("unexpected indentation")
+		}
+		key, rest, ok := splitYAMLKey(text)
+		if !ok {
+			return nil, p.errorf("expected a key, got %q", text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %s", key)
+		}
+		num := p.lines[p.pos].num
+		p.pos++
+		v, err := p.item(rest, ind, true, num)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *yamlParser) sequence(ind int) ([]any, error) {
+	list := []any{}
+	for {
+		indent, text, ok := p.peek()
+		if !ok || indent < ind || indent == ind && !isSeqItem(text) {
+			return list, nil
+		}
+		if indent > ind {
+			return nil, p.errorf("unexpected indentation")
+		}
+		rest := strings.TrimLeft(text[1:], " ")
+		if rest != "" && (isSeqItem(rest) || isYAMLKey(rest)) {
+			// A mapping or sequence opening on the dash's line continues
+			// at the column it starts in; reparse the line from there.
+			indent += len(text) - len(rest)
+			p.lines[p.pos].text = strings.Repeat(" ", indent) + rest
+			v, err := p.block(indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		num := p.lines[p.pos].num
+		p.pos++
+		v, err := p.item(rest, ind, false, num)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// item parses the value after a key or dash on line num at indentation ind:
+// rest if it is not empty, else the block below. A sequence may sit at the
+// same indentation as the key it belongs to.
+func (p *yamlParser) item(rest string, ind int, inMap bool, num int) (any, error) {
+	if rest == "" {
+		indent, text, ok := p.peek()
+		switch {
+		case ok && indent > ind:
+			return p.block(indent)
+		case ok && inMap && indent == ind && isSeqItem(text):
+			return p.sequence(ind)
+		}
+		return nil, nil
+	}
+	var v any
+	var err error
+	switch rest[0] {
+	case '|', '>':
+		v, err = p.blockScalar(rest, ind)
+	case '[', '{':
+		v, err = p.flow(rest)
+	default:
+		v, err = yamlScalar(rest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %w", num, err)
+	}
+	return v, nil
+}
+
+// blockScalar reads the literal (|) or folded (>) scalar introduced by
+// header from the following lines indented past ind. The chomping
+// indicators - and + drop or keep its trailing newlines.
+func (p *yamlParser) blockScalar(header string, ind int) (string, error) {
+	chomp := header[1:]
+	if chomp != "" && chomp != "-" && chomp != "+" {
+		return "", fmt.Errorf("unsupported block scalar header %s", header)
+	}
+	var lines []string
+	indent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos].text
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(raw) - len(trimmed)
+		if n <= ind || indent >= 0 && n < indent {
+			break
+		}
+		if indent < 0 {
+			indent = n
+		}
+		lines = append(lines, raw[indent:])
+	}
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	text := strings.Join(lines, "\n")
+	if header[0] == '>' {
+		text = foldLines(lines)
+	}
+	switch {
+	case chomp == "+":
+		text += strings.Repeat("\n", trailing+1)
+	case chomp == "" && len(lines) > 0:
+		text += "\n"
+	}
+	return text, nil
+}
+
+// foldLines joins the lines of a folded scalar: single line breaks become
+// spaces and each blank line a newline.
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			switch {
+			case line == "":
+				b.WriteByte('\n')
+			case lines[i-1] != "":
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// flow parses a flow collection, which may continue over the following
+// lines until its brackets balance.
+func (p *yamlParser) flow(text string) (any, error) {
+	for !flowClosed(text) && p.pos < len(p.lines) {
+		text += " " + stripYAMLComment(strings.TrimSpace(p.lines[p.pos].text))
+		p.pos++
+	}
+	f := &yamlFlow{s: text}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.i < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q after flow collection", f.s[f.i:])
+	}
+	return v, nil
+}
+
+// flowClosed reports whether every bracket opened in text outside quotes
+// is closed.
+func flowClosed(text string) bool {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			if end := closingQuote(text[i:]); end > 0 {
+				i += end
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// yamlFlow parses a flow collection such as [a, "b"] or {k: v}.
+type yamlFlow struct {
+	s string
+	i int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpace()
+	if f.i == len(f.s) {
+		return nil, errors.New("unterminated flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		list := []any{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return list, nil
+			}
+			v, err := f.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		m := map[string]any{}
+		for {
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return m, nil
+			}
+			k, err := f.scalar(true)
+			if err != nil {
+				return nil, err
+			}
+			key := fmt.Sprint(k)
+			f.skipSpace()
+			if f.i == len(f.s) || f.s[f.i] != ':' {
+				return nil, fmt.Errorf("expected : after key %s", key)
+			}
+			f.i++
+			if m[key], err = f.value(); err != nil {
+				return nil, err
+			}
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(false)
+}
+
+// separator consumes the comma after an item, leaving the closing bracket
+// end for the caller.
+func (f *yamlFlow) separator(end byte) error {
+	f.skipSpace()
+	if f.i < len(f.s) {
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+			return nil
+		case end:
+			return nil
+		}
+	}
+	return fmt.Errorf("expected , or %c in flow collection", end)
+}
+
+// scalar reads a quoted scalar, or a plain one up to the next comma or
+// closing bracket, or colon in a key.
+func (f *yamlFlow) scalar(key bool) (any, error) {
+	if f.i == len(f.s) {
+		return nil, errors.New("unterminated flow collection")
+	}
+	start := f.i
+	if c := f.s[f.i]; c == '"' || c == '\'' {
+		end := closingQuote(f.s[f.i:])
+		if end < 0 {
+			return nil, errors.New("unterminated string")
+		}
+		f.i += end + 1
+		return yamlScalar(f.s[start:f.i])
+	}
+	for f.i < len(f.s) && strings.IndexByte(",]}", f.s[f.i]) < 0 && !(key && f.s[f.i] == ':') {
+		f.i++
+	}
+	text := strings.TrimSpace(f.s[start:f.i])
+	if text == "" {
+		return nil, nil
+	}
+	return yamlScalar(text)
+}
+
+// yamlScalar decodes an inline scalar: a quoted string, null, a boolean, a
+// number or a plain string.
+func yamlScalar(text string) (any, error) {
+	switch text[0] {
+	case '"':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("malformed string %s", text)
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("malformed string %s", text)
+		}
+		return s, nil
+	case '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("malformed string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %s", text)
+	}
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if c := text[0]; (c == '-' || c >= '0' && c <= '9') && json.Valid([]byte(text)) {
+		return json.Number(text), nil
+	}
+	return text, nil
+}
+
+// closingQuote returns the index of the quote closing the string that s
+// starts with, or -1.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == q && i+1 < len(s) && s[i+1] == q:
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a trailing comment, which starts with a # at the
+// start of the text or after a space, outside quotes.
+func stripYAMLComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			if i == 0 || strings.IndexByte(" [{,", s[i-1]) >= 0 {
+				if end := closingQuote(s[i:]); end > 0 {
+					i += end
+				}
+			}
+		case '#':
+			if i == 0 || s[i-1] == ' ' || s[i-1] == '\t' {
+				return strings.TrimRight(s[:i], " \t")
+			}
+		}
+	}
+	return strings.TrimRight(s, " \t")
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isYAMLKey(text string) bool {
+	_, _, ok := splitYAMLKey(text)
+	return ok
+}
+
+// splitYAMLKey splits "key: value" at the first colon outside quotes that
+// is followed by a space or ends the line.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	switch text[0] {
+	case '"', '\'':
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		after := strings.TrimLeft(text[end+1:], " ")
+		if !strings.HasPrefix(after, ":") || len(after) > 1 && after[1] != ' ' {
+			return "", "", false
+		}
+		k, err := yamlScalar(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return k.(string), strings.TrimSpace(after[1:]), true
+	case '[', '{':
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
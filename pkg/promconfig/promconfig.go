@@ -0,0 +1,143 @@
+// Package promconfig exposes configuration structs as Prometheus metrics in
+// the text exposition format, without depending on the Prometheus client.
+//
+// Every numeric or boolean field that is not secret becomes a sample of the
+// config_value gauge labeled by config name and field path; durations are
+// reported in seconds and booleans as 0 or 1. A config_info sample carries the fingerprint of
+// each configuration so drift across a fleet can be alerted on.
+package promconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Collector gathers registered configurations at scrape time.
+type Collector struct {
+	mu      sync.Mutex
+	configs map[string]any
+}
+
+// NewCollector returns an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{configs: map[string]any{}}
+}
+
+// Register adds a configuration under name. Target should be a pointer so
+// later changes show up in subsequent scrapes.
+func (c *Collector) Register(name string, target any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configs[name] = target
+}
+
+// Unregister removes the configuration registered under name.
+func (c *Collector) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.configs, name)
+}
+
+// Write writes the current metrics of all registered configurations to w.
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.configs))
+	for name := range c.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	targets := make([]any, len(names))
+	for i, name := range names {
+		targets[i] = c.configs[name]
+	}
+	c.mu.Unlock()
+
+	var values, info bytes.Buffer
+	for i, name := range names {
+		fields, err := optionator.Values(targets[i])
+		if err != nil {
+			return fmt.Errorf("config %s: %w", name, err)
+		}
+		infos, err := optionator.DescribeValue(targets[i])
+		if err != nil {
+			return fmt.Errorf("config %s: %w", name, err)
+		}
+		for _, fi := range infos {
+			if fi.Secret {
+				delete(fields, fi.Path)
+			}
+		}
+		paths := make([]string, 0, len(fields))
+		for path := range fields {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if f, ok := sample(fields[path]); ok {
+				fmt.Fprintf(&values, "config_value{config=\"%s\",field=\"%s\"} %g\n", escape(name), escape(path), f)
+			}
+		}
+		hash, err := optionator.Fingerprint(targets[i])
+		if err != nil {
+			return fmt.Errorf("config %s: %w", name, err)
+		}
+		fmt.Fprintf(&info, "config_info{config=\"%s\",hash=\"%s\"} 1\n", escape(name), hash)
+	}
+	if _, err := io.WriteString(w, "# HELP config_value Numeric and boolean configuration values.\n# TYPE config_value gauge\n"); err != nil {
+		return err
+	}
+	if _, err := values.WriteTo(w); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "# HELP config_info Fingerprint of each configuration.\n# TYPE config_info gauge\n"); err != nil {
+		return err
+	}
+	_, err := info.WriteTo(w)
+	return err
+}
+
+// ServeHTTP serves the metrics in the text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// sample converts a field value to a gauge value.
+func sample(value any) (float64, bool) {
+	if d, ok := value.(time.Duration); ok {
+		return d.Seconds(), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1, true
+		}
+		return 0, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,43 @@
+package promconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type server struct {
+	Address  string
+	MaxConns int
+	Debug    bool
+	Timeout  time.Duration
+	PIN      int  `secret:"true"`
+	Canary   bool `secret:"hash"`
+}
+
+func TestWrite(t *testing.T) {
+	c := NewCollector()
+	c.Register("api", &server{Address: "x", MaxConns: 200, Debug: true, Timeout: 1500 * time.Millisecond, PIN: 4321, Canary: true})
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`config_value{config="api",field="MaxConns"} 200`,
+		`config_value{config="api",field="Debug"} 1`,
+		`config_value{config="api",field="Timeout"} 1.5`,
+		`config_info{config="api",hash="`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `field="Address"`) {
+		t.Errorf("String fields must not be exported as gauges")
+	}
+	if strings.Contains(out, `field="PIN"`) || strings.Contains(out, `field="Canary"`) {
+		t.Errorf("Secret fields must not be exported:\n%s", out)
+	}
+}
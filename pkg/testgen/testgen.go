@@ -0,0 +1,378 @@
+// Package testgen generates random values of config structs for
+// property-based tests. The values are biased toward the edge cases the
+// struct's tags define: min and max bounds and their neighbours, oneof
+// choices, empty and very long strings, unusual Unicode, nil and empty
+// lists. Such boundary values find far more bugs than uniform ones.
+//
+//	g := testgen.New(seed)
+//	for i := 0; i < 1000; i++ {
+//		cfg, err := testgen.Generate[Config](g)
+//		...
+//	}
+package testgen
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Generator produces config values from a seeded source, so a failing
+// case can be reproduced from its seed.
+type Generator struct {
+	rand *rand.Rand
+	// EdgeBias is the probability that a field gets an edge value rather
+	// than a uniform random one. New sets it to 0.7.
+	EdgeBias float64
+	// Invalid also picks values just outside min and max bounds and lets
+	// required fields be empty, to test that validation rejects them.
+	Invalid bool
+	// Config names the tags; New sets the default and required tags.
+	Config optionator.Config
+}
+
+// New returns a Generator seeded with seed.
+func New(seed int64) *Generator {
+	return &Generator{
+		rand:     rand.New(rand.NewSource(seed)),
+		EdgeBias: 0.7,
+		Config:   optionator.Config{DefaultTag: "default", RequiredTag: "required"},
+	}
+}
+
+// Generate returns a T, a struct, with a generated value in every leaf
+// field. Fields of types the generator does not know, such as funcs,
+// interfaces and text types, keep their zero value.
+func Generate[T any](g *Generator) (*T, error) {
+	fields, err := optionator.DescribeWithConfig[T](g.Config)
+	if err != nil {
+		return nil, err
+	}
+	target := new(T)
+	v := reflect.ValueOf(target).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("testgen: %v is not a struct", v.Type())
+	}
+	for _, f := range fields {
+		field := fieldByPath(v, f.Path)
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+		if err := g.fill(field, f.Tag(), f.Required && !g.Invalid); err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Path, err)
+		}
+	}
+	return target, nil
+}
+
+// fieldByPath returns the field of struct v at a dotted path, allocating
+// nil pointers to nested structs on the way.
+func fieldByPath(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		v = v.FieldByName(name)
+	}
+	return v
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fill sets field to a generated value honouring tag; nonZero rules out
+// the zero value.
+func (g *Generator) fill(field reflect.Value, tag reflect.StructTag, nonZero bool) error {
+	t := field.Type()
+	if oneof := tag.Get("oneof"); oneof != "" {
+		choices := strings.Split(oneof, ",")
+		return setText(field, strings.TrimSpace(choices[g.rand.Intn(len(choices))]))
+	}
+	edge := g.rand.Float64() < g.EdgeBias
+	switch {
+	case t == timeType:
+		field.Set(reflect.ValueOf(g.time(edge, nonZero)))
+		return nil
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return nil
+	case t.Kind() == reflect.Ptr:
+		if edge && !nonZero && g.rand.Intn(2) == 0 {
+			return nil
+		}
+		p := reflect.New(t.Elem())
+		if err := g.fill(p.Elem(), tag, nonZero); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		field.SetBool(nonZero || g.rand.Intn(2) == 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n, err := g.number(t, tag, edge, nonZero)
+		if err != nil {
+			return err
+		}
+		setNumber(field, n)
+	case reflect.String:
+		field.SetString(g.text(edge, nonZero))
+	case reflect.Slice:
+		n := g.length(edge, nonZero)
+		if n < 0 {
+			return nil
+		}
+		s := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			if err := g.fill(s.Index(i), "", false); err != nil {
+				return err
+			}
+		}
+		field.Set(s)
+	case reflect.Map:
+		n := g.length(edge, nonZero)
+		if n < 0 {
+			return nil
+		}
+		m := reflect.MakeMapWithSize(t, n)
+		for i := 0; i < n; i++ {
+			k, e := reflect.New(t.Key()).Elem(), reflect.New(t.Elem()).Elem()
+			if err := g.fill(k, "", false); err != nil {
+				return err
+			}
+			if err := g.fill(e, "", false); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		field.Set(m)
+	}
+	return nil
+}
+
+// number picks a value for a numeric field within its min and max tags:
+// one of the bounds, their inner neighbours, 0, 1, -1 or the limits of the
+// type when edge is set, else a uniform one.
+func (g *Generator) number(t reflect.Type, tag reflect.StructTag, edge, nonZero bool) (float64, error) {
+	lo, hi := typeRange(t)
+	var candidates []float64
+	step := 1.0
+	if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+		step = 1e-9
+	}
+	if arg, ok := tag.Lookup("min"); ok {
+		b, err := parseBound(t, strings.TrimLeft(arg, "(["))
+		if err != nil {
+			return 0, err
+		}
+		if strings.HasPrefix(arg, "(") {
+			b += step
+		}
+		lo = math.Max(lo, b)
+		if g.Invalid {
+			candidates = append(candidates, b-step)
+		}
+	}
+	if arg, ok := tag.Lookup("max"); ok {
+		b, err := parseBound(t, strings.TrimRight(arg, ")]"))
+		if err != nil {
+			return 0, err
+		}
+		if strings.HasSuffix(arg, ")") {
+			b -= step
+		}
+		hi = math.Min(hi, b)
+		if g.Invalid {
+			candidates = append(candidates, b+step)
+		}
+	}
+	if lo > hi {
+		return 0, fmt.Errorf("no value between min %v and max %v", lo, hi)
+	}
+	if !edge {
+		// Uniform over the bounds, or over a modest range around zero
+		// when a bound is the limit of the type.
+		from, to := math.Max(lo, -1e6), math.Min(hi, 1e6)
+		if from > to {
+			from, to = lo, hi
+		}
+		n := from + g.rand.Float64()*(to-from)
+		if step == 1 {
+			n = math.Round(n)
+		}
+		if n == 0 && nonZero {
+			n = math.Min(hi, 1)
+		}
+		return n, nil
+	}
+	var picks []float64
+	for _, n := range append(candidates, lo, hi, lo+step, hi-step, 0, 1, -1) {
+		inside := n >= lo && n <= hi
+		if (inside || g.Invalid && n != 0 && n != 1 && n != -1) && !(n == 0 && nonZero) {
+			picks = append(picks, n)
+		}
+	}
+	if len(picks) == 0 {
+		return lo, nil
+	}
+	return picks[g.rand.Intn(len(picks))], nil
+}
+
+// typeRange returns the range of values of numeric type t, narrowed for
+// 64-bit integers to what a float64 holds exactly.
+func typeRange(t reflect.Type) (lo, hi float64) {
+	const exact = 1 << 53
+	switch t.Kind() {
+	case reflect.Int8:
+		return math.MinInt8, math.MaxInt8
+	case reflect.Int16:
+		return math.MinInt16, math.MaxInt16
+	case reflect.Int32:
+		return math.MinInt32, math.MaxInt32
+	case reflect.Int, reflect.Int64:
+		return -exact, exact
+	case reflect.Uint8:
+		return 0, math.MaxUint8
+	case reflect.Uint16:
+		return 0, math.MaxUint16
+	case reflect.Uint32:
+		return 0, math.MaxUint32
+	case reflect.Uint, reflect.Uint64:
+		return 0, exact
+	case reflect.Float32:
+		return -math.MaxFloat32, math.MaxFloat32
+	}
+	return -math.MaxFloat64, math.MaxFloat64
+}
+
+func parseBound(t reflect.Type, text string) (float64, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(text)
+		return float64(d), err
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func setNumber(field reflect.Value, n float64) {
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(math.Max(n, 0)))
+	default:
+		field.SetInt(int64(n))
+	}
+}
+
+// edgeStrings are strings that commonly break parsing, escaping and
+// length handling.
+var edgeStrings = []string{
+	" ",
+	"\t\n",
+	"\u00e9",
+	"e\u0301",
+	"\u65e5\u672c\u8a9e",
+	"\U0001F642",
+	"\u200b",
+	"\u202eabc",
+	`"'\`,
+	"${HOME}",
+	strings.Repeat("x", 4096),
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_."
+
+func (g *Generator) text(edge, nonZero bool) string {
+	if edge {
+		i := g.rand.Intn(len(edgeStrings) + 1)
+		if i < len(edgeStrings) {
+			return edgeStrings[i]
+		}
+		if !nonZero {
+			return ""
+		}
+	}
+	b := make([]byte, 1+g.rand.Intn(16))
+	for i := range b {
+		b[i] = letters[g.rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// length picks the length of a list or map, or -1 for nil.
+func (g *Generator) length(edge, nonZero bool) int {
+	if !edge {
+		return 1 + g.rand.Intn(4)
+	}
+	switch g.rand.Intn(4) {
+	case 0:
+		if !nonZero {
+			return -1
+		}
+	case 1:
+		if !nonZero {
+			return 0
+		}
+	case 2:
+		return 1
+	}
+	return 64
+}
+
+var edgeTimes = []time.Time{
+	time.Unix(0, 0).UTC(),
+	time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+	time.Date(2038, 1, 19, 3, 14, 8, 0, time.UTC),
+	time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+}
+
+func (g *Generator) time(edge, nonZero bool) time.Time {
+	if edge {
+		i := g.rand.Intn(len(edgeTimes) + 1)
+		if i < len(edgeTimes) {
+			return edgeTimes[i]
+		}
+		if !nonZero {
+			return time.Time{}
+		}
+	}
+	return time.Unix(g.rand.Int63n(4e9), 0).UTC()
+}
+
+// setText sets a field of any parsable kind from text, as oneof values are
+// written.
+func setText(field reflect.Value, text string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		setNumber(field, n)
+		return nil
+	}
+	return fmt.Errorf("oneof on %v", field.Type())
+}
@@ -0,0 +1,54 @@
+package testgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type limits struct {
+	Port    int           `min:"1" max:"65535" required:"true"`
+	Ratio   float64       `min:"(0" max:"1"`
+	Timeout time.Duration `min:"1s" max:"1m)"`
+	Mode    string        `oneof:"fast,safe"`
+	Name    string        `required:"true"`
+	Hosts   []string
+	Retries *uint8
+}
+
+func TestGenerateStaysValid(t *testing.T) {
+	g := New(1)
+	ports := map[int]bool{}
+	for i := 0; i < 500; i++ {
+		cfg, err := Generate[limits](g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := optionator.Validate(cfg); err != nil {
+			t.Fatalf("generated %+v: %v", cfg, err)
+		}
+		ports[cfg.Port] = true
+	}
+	for _, edge := range []int{1, 2, 65534, 65535} {
+		if !ports[edge] {
+			t.Errorf("port %d never generated", edge)
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	g := New(1)
+	g.Invalid = true
+	failed := false
+	for i := 0; i < 200 && !failed; i++ {
+		cfg, err := Generate[limits](g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		failed = optionator.Validate(cfg) != nil
+	}
+	if !failed {
+		t.Errorf("expected some invalid configs")
+	}
+}
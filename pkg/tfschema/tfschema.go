@@ -0,0 +1,218 @@
+// Package tfschema generates the terraform-plugin-framework schema of a
+// config struct as Go source, so a provider exposing the same settings as a
+// service declares them once, in the struct tags.
+//
+// The generated function returns a resource schema.Schema: required fields
+// become Required attributes, fields with defaults Optional and Computed
+// attributes with a static Default, secret fields Sensitive attributes and
+// desc tags their descriptions. Attribute names are snake_case, as
+// Terraform requires.
+package tfschema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/chetan-giradkar/Optionator/pkg/openapi"
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+// Options controls the generated file.
+type Options struct {
+	// Package is the package clause of the file; "provider" when empty.
+	Package string
+	// Func is the name of the generated function; "ConfigSchema" when
+	// empty.
+	Func string
+	// Config names the tags, e.g. optionator.Config{DefaultTag: "default",
+	// RequiredTag: "required"}. Its NamingStrategy is replaced by
+	// optionator.SnakeCase.
+	Config optionator.Config
+}
+
+const framework = "github.com/hashicorp/terraform-plugin-framework/"
+
+// Write writes the Go source of a function returning the schema of T.
+// Defaults of lists, maps and nested objects are left out, since the
+// framework needs them as attr.Value literals; lists and maps of structs
+// are not supported.
+func Write[T any](w io.Writer, opts Options) error {
+	config := opts.Config
+	config.NamingStrategy = optionator.SnakeCase
+	root, err := openapi.ForConfig[T](config)
+	if err != nil {
+		return err
+	}
+	fields, err := optionator.DescribeWithConfig[T](config)
+	if err != nil {
+		return err
+	}
+	g := &generator{imports: map[string]bool{"resource/schema": true}, sensitive: map[string]bool{}}
+	for _, f := range fields {
+		if f.Secret {
+			g.sensitive[config.NamingStrategy.Key(f.Path)] = true
+		}
+	}
+	if err := g.attributes(root, ""); err != nil {
+		return err
+	}
+	pkg, fn := opts.Package, opts.Func
+	if pkg == "" {
+		pkg = "provider"
+	}
+	if fn == "" {
+		fn = "ConfigSchema"
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by optionator tfschema. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	var imports []string
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", framework+imp)
+	}
+	fmt.Fprintf(&out, ")\n\n// %s returns the schema of the %s settings.\nfunc %s() schema.Schema {\n\treturn schema.Schema{\n", fn, pkg, fn)
+	out.Write(g.buf.Bytes())
+	out.WriteString("\t}\n}\n")
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated schema: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type generator struct {
+	buf       bytes.Buffer
+	imports   map[string]bool
+	sensitive map[string]bool
+}
+
+// attributes writes the Attributes of object s, found at dotted key path.
+func (g *generator) attributes(s *openapi.Schema, path string) error {
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	required := map[string]bool{}
+	for _, k := range s.Required {
+		required[k] = true
+	}
+	g.buf.WriteString("Attributes: map[string]schema.Attribute{\n")
+	for _, k := range keys {
+		key := k
+		if path != "" {
+			key = path + "." + k
+		}
+		if err := g.attribute(k, key, s.Properties[k], required[k]); err != nil {
+			return err
+		}
+	}
+	g.buf.WriteString("},\n")
+	return nil
+}
+
+func (g *generator) attribute(name, key string, s *openapi.Schema, required bool) error {
+	var kind, def, elem string
+	switch {
+	case s.Properties != nil:
+		kind = "SingleNestedAttribute"
+	case s.PreserveUnknownFields:
+		kind = "DynamicAttribute"
+	case s.Type == "array" || s.Type == "object":
+		var err error
+		if elem, err = g.elementType(s); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		kind = "ListAttribute"
+		if s.Type == "object" {
+			kind = "MapAttribute"
+		}
+	case s.Type == "boolean":
+		kind, def = "BoolAttribute", g.staticDefault("booldefault", "StaticBool", s.Default)
+	case s.Type == "integer":
+		kind, def = "Int64Attribute", g.staticDefault("int64default", "StaticInt64", s.Default)
+	case s.Type == "number":
+		kind, def = "Float64Attribute", g.staticDefault("float64default", "StaticFloat64", s.Default)
+	default:
+		kind, def = "StringAttribute", g.staticDefault("stringdefault", "StaticString", s.Default)
+	}
+	fmt.Fprintf(&g.buf, "%q: schema.%s{\n", name, kind)
+	if s.Description != "" {
+		fmt.Fprintf(&g.buf, "Description: %q,\n", s.Description)
+	}
+	switch {
+	case required:
+		g.buf.WriteString("Required: true,\n")
+	case def != "":
+		fmt.Fprintf(&g.buf, "Optional: true,\nComputed: true,\nDefault: %s,\n", def)
+	default:
+		g.buf.WriteString("Optional: true,\n")
+	}
+	if g.sensitive[key] {
+		g.buf.WriteString("Sensitive: true,\n")
+	}
+	if elem != "" {
+		fmt.Fprintf(&g.buf, "ElementType: %s,\n", elem)
+	}
+	if s.Properties != nil {
+		if err := g.attributes(s, key); err != nil {
+			return err
+		}
+	}
+	g.buf.WriteString("},\n")
+	return nil
+}
+
+// staticDefault returns the expression setting default value v, or "" if
+// there is none.
+func (g *generator) staticDefault(pkg, fn string, v any) string {
+	if v == nil {
+		return ""
+	}
+	g.imports["resource/schema/"+pkg] = true
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%s.%s(%s)", pkg, fn, strconv.Quote(s))
+	}
+	return fmt.Sprintf("%s.%s(%v)", pkg, fn, v)
+}
+
+// elementType returns the attr.Type expression of the elements of list or
+// map s.
+func (g *generator) elementType(s *openapi.Schema) (string, error) {
+	elem := s.Items
+	if s.Type == "object" {
+		elem = s.AdditionalProperties
+	}
+	var t string
+	switch {
+	case elem == nil || elem.Properties != nil || elem.PreserveUnknownFields:
+		return "", fmt.Errorf("no Terraform element type for the %s values", s.Type)
+	case elem.Type == "array" || elem.Type == "object":
+		inner, err := g.elementType(elem)
+		if err != nil {
+			return "", err
+		}
+		t = "types.ListType{ElemType: " + inner + "}"
+		if elem.Type == "object" {
+			t = "types.MapType{ElemType: " + inner + "}"
+		}
+	case elem.Type == "boolean":
+		t = "types.BoolType"
+	case elem.Type == "integer":
+		t = "types.Int64Type"
+	case elem.Type == "number":
+		t = "types.Float64Type"
+	default:
+		t = "types.StringType"
+	}
+	g.imports["types"] = true
+	return t, nil
+}
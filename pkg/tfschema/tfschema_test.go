@@ -0,0 +1,55 @@
+package tfschema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+)
+
+type tls struct {
+	CertFile string `required:"true"`
+}
+
+type service struct {
+	MaxConns int           `default:"100" desc:"Connection limit"`
+	Timeout  time.Duration `default:"30s"`
+	Verbose  bool
+	APIKey   string `secret:"true"`
+	Tags     map[string][]string
+	TLS      tls
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write[service](&buf, Options{Config: optionator.Config{DefaultTag: "default", RequiredTag: "required"}})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"`,
+		"func ConfigSchema() schema.Schema {",
+		`"max_conns": schema.Int64Attribute{
+				Description: "Connection limit",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(100),`,
+		`Default:  stringdefault.StaticString("30s"),`,
+		`"api_key": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,`,
+		`ElementType: types.ListType{ElemType: types.StringType},`,
+		`"tls": schema.SingleNestedAttribute{
+				Optional: true,
+				Attributes: map[string]schema.Attribute{
+					"cert_file": schema.StringAttribute{
+						Required: true,`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+}
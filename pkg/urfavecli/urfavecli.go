@@ -0,0 +1,64 @@
+// Package urfavecli derives urfave/cli v2 flags from the metadata of a
+// configuration struct. Flags returns a flag for every field BindFlags would
+// bind, and Option or New apply the flags set on the command line before
+// defaults and required validation, so a command built with urfave/cli gets
+// the same options as one built with the standard flag package.
+//
+// The package is a module of its own, so the optionator module stays free
+// of dependencies.
+package urfavecli
+
+import (
+	"strconv"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+	"github.com/urfave/cli/v2"
+)
+
+// Flags returns a flag for every field of T that optionator.DescribeFlags
+// lists under config, in field order. The flags have no defaults of their
+// own; the default tag shows in the help text, and applies in New.
+func Flags[T any](config optionator.Config) ([]cli.Flag, error) {
+	specs, err := optionator.DescribeFlags[T](config)
+	if err != nil {
+		return nil, err
+	}
+	flags := make([]cli.Flag, len(specs))
+	for i, spec := range specs {
+		if spec.Bool {
+			flags[i] = &cli.BoolFlag{Name: spec.Name, Usage: spec.Usage, DefaultText: spec.Default}
+			continue
+		}
+		flags[i] = &cli.StringFlag{Name: spec.Name, Usage: spec.Usage, DefaultText: spec.Default}
+	}
+	return flags, nil
+}
+
+// Option returns an Option applying the flags of T set explicitly on c.
+func Option[T any](c *cli.Context, config optionator.Config) optionator.Option[T] {
+	return func(target T) error {
+		specs, err := optionator.DescribeFlags[T](config)
+		if err != nil {
+			return err
+		}
+		set := map[string]string{}
+		for _, spec := range specs {
+			if !c.IsSet(spec.Name) {
+				continue
+			}
+			if spec.Bool {
+				set[spec.Name] = strconv.FormatBool(c.Bool(spec.Name))
+			} else {
+				set[spec.Name] = c.String(spec.Name)
+			}
+		}
+		return optionator.WithFlags[T](config, set)(target)
+	}
+}
+
+// New constructs target with config, applying opts and then the flags set
+// on c, for use in an Action.
+func New[T any](c *cli.Context, target T, config optionator.Config, opts ...optionator.Option[T]) (T, error) {
+	opts = append(opts[:len(opts):len(opts)], Option[T](c, config))
+	return optionator.NewWithConfig(target, config, opts...)
+}
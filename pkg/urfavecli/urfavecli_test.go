@@ -0,0 +1,61 @@
+package urfavecli
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chetan-giradkar/Optionator/pkg/optionator"
+	"github.com/urfave/cli/v2"
+)
+
+type server struct {
+	Host    string        `default:"localhost" desc:"interface to listen on"`
+	Port    int           `default:"8080"`
+	Verbose bool          `default:"true"`
+	Timeout time.Duration `default:"5s"`
+	Token   string        `required:"true" from:"env,flag"`
+	Secret  string        `from:"env"`
+}
+
+func run(t *testing.T, args ...string) (*server, error) {
+	t.Helper()
+	config := optionator.Config{DefaultTag: "default", RequiredTag: "required"}
+	flags, err := Flags[*server](config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg *server
+	app := &cli.App{
+		Name:      "server",
+		Flags:     flags,
+		Writer:    io.Discard,
+		ErrWriter: io.Discard,
+		Action: func(c *cli.Context) error {
+			var err error
+			cfg, err = New(c, &server{}, config)
+			return err
+		},
+	}
+	return cfg, app.Run(append([]string{"server"}, args...))
+}
+
+func TestFlags(t *testing.T) {
+	cfg, err := run(t, "--port", "9090", "--verbose=false", "--token", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "localhost" || cfg.Port != 9090 || cfg.Verbose || cfg.Timeout != 5*time.Second || cfg.Token != "t" {
+		t.Errorf("got %+v", cfg)
+	}
+	if _, err := run(t, "--port", "9090"); err == nil || !strings.Contains(err.Error(), "Token") {
+		t.Errorf("Expected the required Token to be enforced, got %v", err)
+	}
+	if _, err := run(t, "--token", "t", "--port", "x"); err == nil || !strings.Contains(err.Error(), "flag port") {
+		t.Errorf("Expected a parse error for port, got %v", err)
+	}
+	if _, err := run(t, "--token", "t", "--secret", "s"); err == nil || !strings.Contains(err.Error(), "may only be set from env") {
+		t.Errorf("Expected the from tag to refuse the flag, got %v", err)
+	}
+}